@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// adminRequestTimeout bounds the on-chain reads done before an action is queued
+const adminRequestTimeout = 30 * time.Second
+
+// AdminServer is the operator API. Every request needs the bearer token;
+// requests that change anything also need an X-Operator header, which is
+// recorded in the ID's timeline and the audit journal.
+//
+//	GET  /v1/workflows?kind=&state=&hold=       list IDs and their state
+//	GET  /v1/workflows/{kind}/{id}              one ID with its timeline
+//	POST /v1/workflows/{kind}/{id}/retry        {"stage": "provisional|payment|fdc|dispute"}
+//	POST /v1/workflows/{kind}/{id}/skip         {"reason": "..."}
+//	POST /v1/workflows/{kind}/{id}/quarantine   {"reason": "..."}
+//	POST /v1/workflows/{kind}/{id}/release      {"reason": "..."}
+//	GET  /v1/pause                              which workflows are paused
+//	POST /v1/pause/{kind}                       stop starting new stages
+//	POST /v1/resume/{kind}                      resume and run deferred stages
+//	GET  /v1/breakers                           tripped circuit breakers
+//	POST /v1/breakers/{name}/reset              reset and run deferred payouts
+//	GET  /v1/approvals?status=                  redemptions over a risk limit
+//	POST /v1/approvals/{id}/approve             {"reason": "..."} pay it
+//	POST /v1/approvals/{id}/reject              {"reason": "..."} leave it unpaid
+type AdminServer struct {
+	agent  *Agent
+	token  []byte
+	server *http.Server
+}
+
+// adminAction is the body of a mutating request
+type adminAction struct {
+	Stage  string `json:"stage"` // Retry: empty runs whatever stage is next
+	Reason string `json:"reason"`
+	DryRun bool   `json:"dry_run"` // Retry: check the stage without queueing it
+}
+
+// retryResult is the response to a retry request
+type retryResult struct {
+	Kind   string `json:"kind"`
+	ID     uint64 `json:"id"`
+	Stage  string `json:"stage"` // Empty when there was nothing to do
+	Queued bool   `json:"queued"`
+}
+
+// NewAdminServer creates a server listening on config.Admin.ListenAddr
+func NewAdminServer(agent *Agent, config *Config) *AdminServer {
+	s := &AdminServer{
+		agent: agent,
+		token: []byte(config.Admin.Token),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/workflows", s.handleList)
+	mux.HandleFunc("GET /v1/workflows/{kind}/{id}", s.handleGet)
+	mux.HandleFunc("POST /v1/workflows/{kind}/{id}/retry", s.operator(s.handleRetry))
+	mux.HandleFunc("POST /v1/workflows/{kind}/{id}/skip", s.operator(s.holdHandler(HoldSkipped)))
+	mux.HandleFunc("POST /v1/workflows/{kind}/{id}/quarantine", s.operator(s.holdHandler(HoldQuarantined)))
+	mux.HandleFunc("POST /v1/workflows/{kind}/{id}/release", s.operator(s.holdHandler("")))
+	mux.HandleFunc("GET /v1/pause", s.handlePaused)
+	mux.HandleFunc("POST /v1/pause/{kind}", s.operator(s.handlePause))
+	mux.HandleFunc("POST /v1/resume/{kind}", s.operator(s.handleResume))
+	mux.HandleFunc("GET /v1/breakers", s.handleBreakers)
+	mux.HandleFunc("POST /v1/breakers/{name}/reset", s.operator(s.handleResetBreaker))
+	mux.HandleFunc("GET /v1/approvals", s.handleApprovals)
+	mux.HandleFunc("POST /v1/approvals/{id}/approve", s.operator(s.approvalHandler(true)))
+	mux.HandleFunc("POST /v1/approvals/{id}/reject", s.operator(s.approvalHandler(false)))
+	mux.HandleFunc("POST /v1/firelight/{id}/approve", s.operator(s.handleFirelightApprove))
+
+	s.server = &http.Server{
+		Addr:              config.Admin.ListenAddr,
+		Handler:           s.authenticate(mux),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// Run serves until ctx is cancelled
+func (s *AdminServer) Run(ctx context.Context) error {
+	if host, _, err := net.SplitHostPort(s.server.Addr); err == nil {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			log.Warn().Str("addr", s.server.Addr).Msg("Admin API is not bound to loopback")
+		}
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		log.Info().Str("addr", s.server.Addr).Msg("Admin API listening")
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errChan <- fmt.Errorf("admin server failed: %w", err)
+		}
+		close(errChan)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return s.server.Shutdown(shutdownCtx)
+	}
+}
+
+func (s *AdminServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), s.token) != 1 {
+			writeAdminError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// operatorHandler is a mutating handler with the acting operator's identity
+type operatorHandler func(w http.ResponseWriter, r *http.Request, operator string)
+
+// operator requires the X-Operator header and logs the action
+func (s *AdminServer) operator(next operatorHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		operator := strings.TrimSpace(r.Header.Get("X-Operator"))
+		if operator == "" {
+			writeAdminError(w, http.StatusBadRequest, errors.New("X-Operator header is required"))
+			return
+		}
+		log.Info().
+			Str("operator", operator).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Msg("Admin action")
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, fmt.Errorf("failed to read body: %w", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r, operator)
+
+		// The outcome is journaled too: a refused override is still an attempt
+		var action adminAction
+		json.Unmarshal(body, &action)
+		kind := r.PathValue("kind")
+		if strings.HasPrefix(r.URL.Path, "/v1/approvals/") || strings.HasPrefix(r.URL.Path, "/v1/firelight/") {
+			kind = WorkflowRedemption
+		}
+		var id *uint64
+		if v, err := strconv.ParseUint(r.PathValue("id"), 10, 64); err == nil {
+			id = &v
+		}
+		s.agent.audit.RecordOverride(operator, kind, id, map[string]interface{}{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"stage":  action.Stage,
+			"reason": action.Reason,
+			"status": recorder.status,
+		})
+	}
+}
+
+// statusRecorder keeps the status code a handler wrote
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *AdminServer) handleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	writeAdminJSON(w, http.StatusOK, s.agent.workflows.List(q.Get("kind"), q.Get("state"), q.Get("hold")))
+}
+
+func (s *AdminServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	kind, id, err := workflowPath(r)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+	record, ok := s.agent.workflows.Get(kind, id)
+	if !ok {
+		writeAdminError(w, http.StatusNotFound, fmt.Errorf("%s %d has not been seen by this agent", kind, id))
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, record)
+}
+
+func (s *AdminServer) handleRetry(w http.ResponseWriter, r *http.Request, operator string) {
+	kind, id, err := workflowPath(r)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+	action, err := readAdminAction(r)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), adminRequestTimeout)
+	defer cancel()
+
+	result := retryResult{Kind: kind, ID: id, Stage: action.Stage}
+	if result.Stage == "" {
+		if result.Stage, err = s.agent.NextStage(ctx, kind, id); err != nil {
+			writeAdminError(w, http.StatusConflict, err)
+			return
+		}
+		if result.Stage == "" {
+			writeAdminJSON(w, http.StatusOK, result)
+			return
+		}
+	}
+
+	op, err := s.agent.PrepareRetry(ctx, kind, id, result.Stage)
+	if err != nil {
+		writeAdminError(w, http.StatusConflict, err)
+		return
+	}
+	if action.DryRun {
+		writeAdminJSON(w, http.StatusOK, result)
+		return
+	}
+	detail := result.Stage
+	if action.Reason != "" {
+		detail += ": " + action.Reason
+	}
+	s.agent.workflows.Note(kind, id, "retry_requested", detail, operator)
+	if err := s.agent.enqueue(ctx, op); err != nil {
+		writeAdminError(w, http.StatusServiceUnavailable, fmt.Errorf("agent is busy: %w", err))
+		return
+	}
+	result.Queued = true
+	writeAdminJSON(w, http.StatusAccepted, result)
+}
+
+// holdHandler places hold on an ID, or releases it when hold is ""
+func (s *AdminServer) holdHandler(hold string) operatorHandler {
+	return func(w http.ResponseWriter, r *http.Request, operator string) {
+		kind, id, err := workflowPath(r)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		action, err := readAdminAction(r)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		if hold != "" && action.Reason == "" {
+			writeAdminError(w, http.StatusBadRequest, errors.New("reason is required"))
+			return
+		}
+
+		s.agent.workflows.SetHold(kind, id, hold, action.Reason, operator)
+		record, _ := s.agent.workflows.Get(kind, id)
+		record.Timeline = nil
+		writeAdminJSON(w, http.StatusOK, record)
+	}
+}
+
+func (s *AdminServer) handlePaused(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, map[string]bool{
+		WorkflowRedemption: s.agent.workflows.Paused(WorkflowRedemption),
+		WorkflowMinting:    s.agent.workflows.Paused(WorkflowMinting),
+	})
+}
+
+func (s *AdminServer) handlePause(w http.ResponseWriter, r *http.Request, operator string) {
+	kind := r.PathValue("kind")
+	if err := checkWorkflowKind(kind); err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.agent.workflows.SetPaused(kind, true)
+	log.Warn().Str("workflow", kind).Str("operator", operator).Msg("Workflow paused")
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{"kind": kind, "paused": true})
+}
+
+func (s *AdminServer) handleResume(w http.ResponseWriter, r *http.Request, operator string) {
+	kind := r.PathValue("kind")
+	if err := checkWorkflowKind(kind); err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), adminRequestTimeout)
+	defer cancel()
+
+	ids, err := s.agent.Resume(ctx, kind)
+	if err != nil {
+		writeAdminError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	log.Info().Str("workflow", kind).Str("operator", operator).Int("deferred", len(ids)).Msg("Workflow resumed")
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{"kind": kind, "paused": false, "resumed_ids": ids})
+}
+
+func (s *AdminServer) handleBreakers(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, s.agent.breakers.Tripped())
+}
+
+func (s *AdminServer) handleResetBreaker(w http.ResponseWriter, r *http.Request, operator string) {
+	ctx, cancel := context.WithTimeout(r.Context(), adminRequestTimeout)
+	defer cancel()
+
+	name := r.PathValue("name")
+	ids, err := s.agent.ResetBreaker(ctx, name, operator)
+	if err != nil {
+		writeAdminError(w, http.StatusConflict, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{
+		"breaker":     name,
+		"tripped":     s.agent.breakers.Tripped(),
+		"resumed_ids": ids,
+	})
+}
+
+func (s *AdminServer) handleApprovals(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, s.agent.limits.Approvals(r.URL.Query().Get("status")))
+}
+
+// approvalHandler approves or rejects a redemption in the approval queue
+func (s *AdminServer) approvalHandler(approve bool) operatorHandler {
+	return func(w http.ResponseWriter, r *http.Request, operator string) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid id %q", r.PathValue("id")))
+			return
+		}
+		action, err := readAdminAction(r)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		if !approve && action.Reason == "" {
+			writeAdminError(w, http.StatusBadRequest, errors.New("reason is required"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), adminRequestTimeout)
+		defer cancel()
+
+		approval, err := s.agent.DecideApproval(ctx, id, approve, operator, action.Reason)
+		if approval.Status == "" {
+			writeAdminError(w, http.StatusConflict, err)
+			return
+		}
+		response := map[string]interface{}{"approval": approval, "queued": err == nil}
+		if err != nil {
+			response["error"] = err.Error()
+		}
+		writeAdminJSON(w, http.StatusOK, response)
+	}
+}
+
+// handleFirelightApprove approves a Firelight trigger above the approval
+// threshold
+func (s *AdminServer) handleFirelightApprove(w http.ResponseWriter, r *http.Request, operator string) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid id %q", r.PathValue("id")))
+		return
+	}
+	action, err := readAdminAction(r)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	incident, err := s.agent.firelight.Approve(id, operator, action.Reason)
+	if err != nil {
+		writeAdminError(w, http.StatusConflict, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{"incident": incident})
+}
+
+func checkWorkflowKind(kind string) error {
+	if kind != WorkflowRedemption && kind != WorkflowMinting {
+		return fmt.Errorf("unknown workflow %q, expected %s or %s", kind, WorkflowRedemption, WorkflowMinting)
+	}
+	return nil
+}
+
+func workflowPath(r *http.Request) (string, uint64, error) {
+	kind := r.PathValue("kind")
+	if err := checkWorkflowKind(kind); err != nil {
+		return "", 0, err
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid id %q", r.PathValue("id"))
+	}
+	return kind, id, nil
+}
+
+func readAdminAction(r *http.Request) (adminAction, error) {
+	var action adminAction
+	if r.ContentLength == 0 {
+		return action, nil
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<16)).Decode(&action); err != nil {
+		return action, fmt.Errorf("invalid request body: %w", err)
+	}
+	return action, nil
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	writeAdminJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -5,13 +5,15 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/flip-protocol/agent/bindings"
+	"github.com/flip-protocol/scoring"
 	"github.com/rs/zerolog/log"
 )
 
@@ -21,35 +23,147 @@ type Agent struct {
 	eventMonitor         *EventMonitor
 	paymentProc          *PaymentProcessor
 	fdcSubmitter         *FDCSubmitter
+	contracts            *ContractResolver
+	flip                 *FlipContracts
+	settings             *LiveSettings
+	keeper               *TimeoutKeeper
+	firelight            *FirelightWatcher
+	router               *Router
+	multicall            *Multicaller
 	flareClient          *ethclient.Client
-	processedRedemptions map[uint64]bool // Track already processed redemptions
-	processedMintings    map[uint64]bool // Track already processed mintings
+	processedRedemptions map[uint64]bool   // Track already processed redemptions
+	processedMintings    map[uint64]bool   // Track already processed mintings
+	mintingTxHashes      map[string]uint64 // XRPL deposit hash -> first minting ID using it
+	workflows            *WorkflowStore
+	adminOps             chan func(context.Context) // Operator actions, run between events
+	roleVerified         atomic.Bool       // Agent is FLIPCore owner or a registered operator
+	shadow               *ShadowRecorder   // Set in dry-run mode
+	breakers             *CircuitBreakers  // Halt XRPL payouts on abnormal conditions
+	limits               *RiskLimiter      // Payout limits and the approval queue
+	screener             Screener          // Compliance screening; nil when off
+	notifier             *Notifier         // Pushes lifecycle and incident events to sinks
+	audit                *AuditJournal     // Hash-chained record of every decision and side effect
+	reconciler           *Reconciler       // Compares XRPL payouts with FLIPCore records
+	shutdownGrace        time.Duration     // How long in-flight work may run after shutdown starts
+	stopping             atomic.Bool       // Shutdown started; no new work is taken
 }
 
 // NewAgent creates a new agent instance
 func NewAgent(config *Config) (*Agent, error) {
+	// Initialize Flare client for contract calls
+	flareClient, err := dialFlare(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Flare RPC: %w", err)
+	}
+
+	// Validate FLIP contract addresses and fill any left unset in config
+	flip, err := NewFlipContracts(flareClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FLIP contract registry: %w", err)
+	}
+	if err := flip.Resolve(context.Background(), config.Flare); err != nil {
+		return nil, err
+	}
+	flip.Fill(&config.Flare)
+
+	// Settings that can be changed on SIGHUP
+	settings := NewLiveSettings(config)
+
 	// Initialize event monitor
-	eventMonitor, err := NewEventMonitor(config)
+	eventMonitor, err := NewEventMonitor(config, settings)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create event monitor: %w", err)
 	}
 
 	// Initialize payment processor
-	paymentProc, err := NewPaymentProcessor(config)
+	paymentProc, err := NewPaymentProcessor(config, settings)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create payment processor: %w", err)
 	}
 
+	// Resolve Flare system contracts (FdcHub, Relay, ...) via ContractRegistry
+	contracts, err := NewContractResolver(flareClient, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contract resolver: %w", err)
+	}
+	if err := contracts.Resolve(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to resolve Flare system contracts: %w", err)
+	}
+
+	audit, err := openAudit(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lifecycle and incident notifications
+	notifier, err := NewNotifier(config, paymentProc.xrplClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notifier: %w", err)
+	}
+
+	// Circuit breakers, fed FDC outcomes by the submitter
+	breakers, err := NewCircuitBreakers(flareClient, flip, eventMonitor, paymentProc.xrplClient, notifier, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit breakers: %w", err)
+	}
+
 	// Initialize FDC submitter
-	fdcSubmitter, err := NewFDCSubmitter(config)
+	fdcSubmitter, err := NewFDCSubmitter(config, contracts, breakers, notifier)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create FDC submitter: %w", err)
 	}
 
-	// Initialize Flare client for contract calls
-	flareClient, err := ethclient.Dial(config.Flare.RPCURL)
+	// Batched reads for startup recovery and the timeout keeper
+	multicall, err := NewMulticaller(flareClient, config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Flare RPC: %w", err)
+		return nil, fmt.Errorf("failed to create multicaller: %w", err)
+	}
+
+	// Initialize timeout keeper
+	keeper, err := NewTimeoutKeeper(flareClient, fdcSubmitter, multicall, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create timeout keeper: %w", err)
+	}
+
+	// Initialize Firelight watcher
+	firelight, err := NewFirelightWatcher(flareClient, fdcSubmitter, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Firelight watcher: %w", err)
+	}
+
+	// Periodic check of XRPL payouts against FLIPCore records
+	reconciler, err := NewReconciler(flareClient, flip, multicall, paymentProc.xrplClient, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reconciler: %w", err)
+	}
+
+	// Per-ID state, operator holds and pauses
+	workflows, err := NewWorkflowStore(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow state: %w", err)
+	}
+
+	// Initialize score-driven routing
+	router, err := NewRouter(flareClient, contracts, flip, multicall, workflows, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create router: %w", err)
+	}
+
+	// Payout limits and the manual approval queue
+	limits, err := NewRiskLimiter(config, settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load risk limits: %w", err)
+	}
+
+	screener, err := NewScreener(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up screening: %w", err)
+	}
+
+	// Already opened by the clients when dry-run mode is on
+	shadow, err := openShadow(config)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Agent{
@@ -57,9 +171,27 @@ func NewAgent(config *Config) (*Agent, error) {
 		eventMonitor:         eventMonitor,
 		paymentProc:          paymentProc,
 		fdcSubmitter:         fdcSubmitter,
+		contracts:            contracts,
+		flip:                 flip,
+		settings:             settings,
+		keeper:               keeper,
+		firelight:            firelight,
+		router:               router,
+		multicall:            multicall,
 		flareClient:          flareClient,
 		processedRedemptions: make(map[uint64]bool),
 		processedMintings:    make(map[uint64]bool),
+		mintingTxHashes:      make(map[string]uint64),
+		workflows:            workflows,
+		adminOps:             make(chan func(context.Context), 16),
+		shadow:               shadow,
+		breakers:             breakers,
+		limits:               limits,
+		screener:             screener,
+		notifier:             notifier,
+		audit:                audit,
+		reconciler:           reconciler,
+		shutdownGrace:        time.Duration(config.Agent.ShutdownGracePeriod) * time.Second,
 	}, nil
 }
 
@@ -77,27 +209,16 @@ func (a *Agent) verifyAccessControl(ctx context.Context) error {
 
 	agentAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
 
-	// ABI for checking owner and operator status
-	const checkABI = `[
-		{"inputs":[],"name":"owner","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
-		{"inputs":[{"name":"_operator","type":"address"}],"name":"isOperator","outputs":[{"name":"","type":"bool"}],"stateMutability":"view","type":"function"}
-	]`
-
-	parsed, err := abi.JSON(strings.NewReader(checkABI))
+	flipCore, err := bindings.NewFLIPCoreCaller(common.HexToAddress(a.config.Flare.FLIPCoreAddress), a.flareClient)
 	if err != nil {
-		return fmt.Errorf("failed to parse check ABI: %w", err)
+		return fmt.Errorf("failed to bind FLIPCore: %w", err)
 	}
 
-	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
-	contract := bind.NewBoundContract(flipCoreAddr, parsed, a.flareClient, a.flareClient, a.flareClient)
-
 	// Check owner
-	var ownerResult []interface{}
-	err = contract.Call(&bind.CallOpts{Context: ctx}, &ownerResult, "owner")
+	owner, err := flipCore.Owner(&bind.CallOpts{Context: ctx})
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to check FLIPCore owner")
-	} else if len(ownerResult) > 0 {
-		owner := ownerResult[0].(common.Address)
+	} else {
 		isOwner := owner == agentAddress
 		log.Info().
 			Str("agent_address", agentAddress.Hex()).
@@ -105,34 +226,28 @@ func (a *Agent) verifyAccessControl(ctx context.Context) error {
 			Bool("is_owner", isOwner).
 			Msg("Access control check: owner")
 
-		if !isOwner {
+		if isOwner {
+			a.roleVerified.Store(true)
+		} else {
 			log.Warn().Msg("Agent is NOT the FLIPCore owner")
 		}
 	}
 
 	// Check if operator via OperatorRegistry
-	const operatorCheckABI = `[
-		{"inputs":[{"name":"_operator","type":"address"}],"name":"isOperator","outputs":[{"name":"","type":"bool"}],"stateMutability":"view","type":"function"}
-	]`
-
-	operatorParsed, err := abi.JSON(strings.NewReader(operatorCheckABI))
+	operatorRegistry, err := bindings.NewOperatorRegistryCaller(a.flip.Address(ContractOperatorRegistry), a.flareClient)
 	if err == nil {
-		// Try to read operator registry address from config or use default
-		operatorRegistryAddr := common.HexToAddress("0x1e6DDfcA83c483c79C82230Ea923C57c1ef1A626") // From config.yaml
-		operatorContract := bind.NewBoundContract(operatorRegistryAddr, operatorParsed, a.flareClient, a.flareClient, a.flareClient)
-
-		var isOperatorResult []interface{}
-		err = operatorContract.Call(&bind.CallOpts{Context: ctx}, &isOperatorResult, "isOperator", agentAddress)
+		isOperator, err := operatorRegistry.IsOperator(&bind.CallOpts{Context: ctx}, agentAddress)
 		if err != nil {
 			log.Warn().Err(err).Msg("Failed to check operator status")
-		} else if len(isOperatorResult) > 0 {
-			isOperator := isOperatorResult[0].(bool)
+		} else {
 			log.Info().
 				Str("agent_address", agentAddress.Hex()).
 				Bool("is_operator", isOperator).
 				Msg("Access control check: operator")
 
-			if !isOperator {
+			if isOperator {
+				a.roleVerified.Store(true)
+			} else {
 				log.Warn().Msg("Agent is NOT a registered operator - finalizeProvisional and finalizeMintingProvisional will fail unless agent is owner")
 			}
 		}
@@ -141,28 +256,61 @@ func (a *Agent) verifyAccessControl(ctx context.Context) error {
 	return nil
 }
 
-// Run starts the agent service
+// Run starts the agent service. Cancelling ctx stops new work; the event in
+// flight may finish within the shutdown grace period, after which it stops at
+// its next checkpoint. Run returns once nothing is in flight.
 func (a *Agent) Run(ctx context.Context) error {
 	log.Info().Msg("Agent service started")
 
+	// Handlers run on work, which outlives ctx by the grace period
+	work, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	go a.watchShutdown(ctx, work, cancelWork)
+
 	// Verify access control at startup
 	if err := a.verifyAccessControl(ctx); err != nil {
 		log.Warn().Err(err).Msg("Access control verification failed")
 	}
 
-	// Recover any failed FDC submissions (XRP sent but not finalized)
-	if err := a.recoverFailedFDCSubmissions(ctx); err != nil {
-		log.Warn().Err(err).Msg("Failed to recover FDC submissions, continuing anyway")
+	// Refuse to run against contracts that no longer match our ABIs
+	if err := a.verifySelectors(ctx); err != nil {
+		return fmt.Errorf("selector drift check failed: %w", err)
 	}
 
-	// Recover any pending escrows from previous runs
-	if err := a.recoverPendingEscrows(ctx); err != nil {
-		log.Warn().Err(err).Msg("Failed to recover pending escrows, continuing anyway")
+	// Keep Flare system contract addresses current across protocol upgrades
+	go a.contracts.Watch(ctx)
+
+	// Time out escrows that FDC did not settle within their window
+	if a.config.Keeper.Enabled {
+		go a.keeper.Run(ctx)
 	}
 
-	// Recover any pending minting requests
-	if err := a.recoverPendingMintings(ctx); err != nil {
-		log.Warn().Err(err).Msg("Failed to recover pending mintings, continuing anyway")
+	// Trigger the Firelight backstop for failed and timed-out redemptions
+	if a.config.Firelight.Enabled {
+		go a.firelight.Run(ctx)
+	}
+
+	// Sample FTSO prices for volatility scoring
+	go a.router.Run(ctx)
+
+	// Compare XRPL payouts with the hashes recorded on FLIPCore
+	if a.config.Reconcile.Enabled {
+		go a.reconciler.Run(ctx)
+	}
+
+	// Deliver notifications until in-flight work has drained
+	go a.notifier.Run(work)
+
+	// Halt payouts on paused contracts, low balance, FDC trouble or chain
+	// lag. Checked once up front so recovery does not pay into a problem.
+	a.breakers.Check(ctx)
+	go a.breakers.Run(ctx)
+
+	// Finish stages interrupted by the last shutdown, then FDC submissions,
+	// unpaid escrows and pending mintings left over from previous runs
+	a.resumeInterrupted(work)
+	if err := a.recoverPreviousRuns(work); err != nil {
+		log.Warn().Err(err).Msg("Failed to recover previous runs, continuing anyway")
 	}
 
 	// Start monitoring EscrowCreated events
@@ -179,28 +327,42 @@ func (a *Agent) Run(ctx context.Context) error {
 
 	// Process events from all channels
 	for {
+		// Checked first so a ready event is never picked over shutdown
+		if ctx.Err() != nil {
+			a.drain(redemptionChan, escrowChan, mintingChan)
+			return ctx.Err()
+		}
+
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			continue
+		case op := <-a.adminOps:
+			op(work)
 		case event := <-redemptionChan:
+			eventsSeen.WithLabelValues("RedemptionRequested").Inc()
+			a.audit.RecordFor(AuditEventObserved, WorkflowRedemption, event.RedemptionID.Uint64(), auditEvent("RedemptionRequested", event))
 			// Process new redemption requests - call finalizeProvisional
-			if err := a.handleRedemptionRequested(ctx, event); err != nil {
+			if err := a.handleRedemptionRequested(work, event); err != nil {
 				log.Error().
 					Err(err).
 					Uint64("redemption_id", event.RedemptionID.Uint64()).
 					Msg("Failed to handle RedemptionRequested event")
 			}
 		case event := <-escrowChan:
+			eventsSeen.WithLabelValues("EscrowCreated").Inc()
+			a.audit.RecordFor(AuditEventObserved, WorkflowRedemption, event.RedemptionID.Uint64(), auditEvent("EscrowCreated", event))
 			// Process escrow created - send XRP payment
-			if err := a.handleEscrowCreated(ctx, event); err != nil {
+			if err := a.handleEscrowCreated(work, event); err != nil {
 				log.Error().
 					Err(err).
 					Uint64("redemption_id", event.RedemptionID.Uint64()).
 					Msg("Failed to handle EscrowCreated event")
 			}
 		case event := <-mintingChan:
+			eventsSeen.WithLabelValues("MintingRequested").Inc()
+			a.audit.RecordFor(AuditEventObserved, WorkflowMinting, event.MintingID.Uint64(), auditEvent("MintingRequested", event))
 			// Process minting request - call finalizeMintingProvisional
-			if err := a.handleMintingRequested(ctx, event); err != nil {
+			if err := a.handleMintingRequested(work, event); err != nil {
 				log.Error().
 					Err(err).
 					Uint64("minting_id", event.MintingID.Uint64()).
@@ -210,6 +372,89 @@ func (a *Agent) Run(ctx context.Context) error {
 	}
 }
 
+// watchShutdown marks the agent as stopping when ctx is cancelled and cancels
+// work once the grace period runs out
+func (a *Agent) watchShutdown(ctx, work context.Context, cancelWork context.CancelFunc) {
+	select {
+	case <-ctx.Done():
+	case <-work.Done():
+		return
+	}
+	a.stopping.Store(true)
+	log.Info().Dur("grace_period", a.shutdownGrace).Msg("Shutdown started, draining in-flight work")
+
+	select {
+	case <-time.After(a.shutdownGrace):
+		log.Warn().Msg("Shutdown grace period expired, stopping in-flight work at its next checkpoint")
+		cancelWork()
+	case <-work.Done():
+	}
+}
+
+// drain records events that were received but not started so the next start
+// resumes them. Queued operator actions are dropped.
+func (a *Agent) drain(redemptionChan <-chan RedemptionRequestedEvent, escrowChan <-chan EscrowCreatedEvent, mintingChan <-chan MintingRequestedEvent) {
+	for {
+		select {
+		case event := <-redemptionChan:
+			if !a.processedRedemptions[event.RedemptionID.Uint64()] {
+				a.interrupt(WorkflowRedemption, event.RedemptionID.Uint64(), RetryProvisional, "received during shutdown")
+			}
+		case event := <-escrowChan:
+			a.interrupt(WorkflowRedemption, event.RedemptionID.Uint64(), RetryPayment, "received during shutdown")
+		case event := <-mintingChan:
+			if !a.processedMintings[event.MintingID.Uint64()] {
+				a.interrupt(WorkflowMinting, event.MintingID.Uint64(), RetryProvisional, "received during shutdown")
+			}
+		case <-a.adminOps:
+			log.Warn().Msg("Dropping queued admin action during shutdown")
+		default:
+			return
+		}
+	}
+}
+
+// Reload applies a re-read config. Only settings that are safe to change
+// while running are applied; other changes are reported and wait for a
+// restart.
+func (a *Agent) Reload(ctx context.Context, config *Config) error {
+	if err := a.flip.Reload(ctx, config.Flare); err != nil {
+		return fmt.Errorf("failed to reload FLIP contracts: %w", err)
+	}
+	a.flip.Fill(&config.Flare)
+
+	var applied, pending []string
+	for _, field := range changedFields(a.config, config) {
+		if hotReloadFields[field] {
+			applied = append(applied, field)
+		} else {
+			pending = append(pending, field)
+		}
+	}
+
+	a.settings.Apply(config)
+
+	if len(applied) > 0 {
+		log.Info().Strs("fields", applied).Msg("Applied reloaded settings")
+	}
+	if len(pending) > 0 {
+		log.Warn().Strs("fields", pending).Msg("Changed settings require a restart to take effect")
+	}
+	return nil
+}
+
+// RunKeeper runs only the timeout keeper, without processing redemptions or
+// mintings
+func (a *Agent) RunKeeper(ctx context.Context) error {
+	log.Info().Msg("Agent started in keeper mode")
+	if err := a.verifySelectors(ctx); err != nil {
+		return fmt.Errorf("selector drift check failed: %w", err)
+	}
+	go a.contracts.Watch(ctx)
+	a.keeper.Run(ctx)
+	return ctx.Err()
+}
+
 // handleRedemptionRequested processes a new redemption request by calling finalizeProvisional
 func (a *Agent) handleRedemptionRequested(ctx context.Context, event RedemptionRequestedEvent) error {
 	redemptionID := event.RedemptionID.Uint64()
@@ -227,14 +472,60 @@ func (a *Agent) handleRedemptionRequested(ctx context.Context, event RedemptionR
 		Str("amount", event.Amount.String()).
 		Msg("Processing new RedemptionRequested event")
 
+	a.workflows.Record(WorkflowRedemption, redemptionID, StateRequested,
+		fmt.Sprintf("%s of %s to %s", event.Amount, event.Asset.Hex(), event.XRPLAddress))
+	if !a.admit(WorkflowRedemption, redemptionID, RetryProvisional) {
+		return nil
+	}
+	if ctx.Err() != nil {
+		a.interrupt(WorkflowRedemption, redemptionID, RetryProvisional, "not started")
+		return nil
+	}
+
+	// Score the request with live inputs; FLIPCore would reject the
+	// provisional call anyway if the score is too low
+	decision, err := a.router.Evaluate(ctx, event.Asset, event.Amount)
+	if err != nil && ctx.Err() != nil {
+		a.interrupt(WorkflowRedemption, redemptionID, RetryProvisional, err.Error())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to score redemption: %w", err)
+	}
+	decision.log("redemption", redemptionID)
+	a.audit.RecordFor(AuditScoreInputs, WorkflowRedemption, redemptionID, decision)
+
+	if !decision.Result.CanProvisionalSettle {
+		err := a.queueForFDC(ctx, event.RedemptionID)
+		if err != nil && ctx.Err() != nil {
+			a.interrupt(WorkflowRedemption, redemptionID, RetryProvisional, err.Error())
+			return nil
+		}
+		if err != nil {
+			a.workflows.Note(WorkflowRedemption, redemptionID, "queue_fdc_failed", err.Error(), "")
+			return fmt.Errorf("failed to queue redemption for FDC: %w", err)
+		}
+		a.workflows.Record(WorkflowRedemption, redemptionID, StateQueuedFDC, decision.summary())
+		a.processedRedemptions[redemptionID] = true
+		return nil
+	}
+
 	// Call finalizeProvisional to create escrow
 	// This requires the agent to have owner/operator privileges on FLIPCore
 	// Using finalizeProvisional instead of ownerProcessRedemption because it uses
 	// onlyOperator modifier which allows both operators AND owner
-	err := a.callFinalizeProvisional(ctx, event.RedemptionID)
+	done := observeStage(StageProvisional)
+	err = a.callFinalizeProvisional(ctx, event.RedemptionID, decision.Params)
+	done(err)
+	if err != nil && ctx.Err() != nil {
+		a.interrupt(WorkflowRedemption, redemptionID, RetryProvisional, err.Error())
+		return nil
+	}
 	if err != nil {
+		a.workflows.Note(WorkflowRedemption, redemptionID, "provisional_failed", err.Error(), "")
 		return fmt.Errorf("failed to call finalizeProvisional: %w", err)
 	}
+	a.workflows.Record(WorkflowRedemption, redemptionID, StateEscrowCreated, decision.summary())
 
 	a.processedRedemptions[redemptionID] = true
 	log.Info().Uint64("redemption_id", redemptionID).Msg("Redemption processed, escrow created")
@@ -242,32 +533,29 @@ func (a *Agent) handleRedemptionRequested(ctx context.Context, event RedemptionR
 	return nil
 }
 
+// queueForFDC sends a low-confidence redemption down the standard FDC path
+func (a *Agent) queueForFDC(ctx context.Context, redemptionID *big.Int) error {
+	tx, err := a.fdcSubmitter.transactFLIPCore(ctx, "queueForFDC", 100000, redemptionID)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("tx_hash", tx.Hash().Hex()).
+		Uint64("redemption_id", redemptionID.Uint64()).
+		Msg("Redemption queued for FDC")
+
+	return nil
+}
+
 // callFinalizeProvisional calls FLIPCore.finalizeProvisional
 // This function uses onlyOperator modifier which allows both operators AND owner
-func (a *Agent) callFinalizeProvisional(ctx context.Context, redemptionID *big.Int) error {
-	// ABI for finalizeProvisional (uses onlyOperator, not onlyOwner)
-	const flipCoreABIJSON = `[{
-		"inputs": [
-			{"name": "_redemptionId", "type": "uint256"},
-			{"name": "_priceVolatility", "type": "uint256"},
-			{"name": "_agentSuccessRate", "type": "uint256"},
-			{"name": "_agentStake", "type": "uint256"}
-		],
-		"name": "finalizeProvisional",
-		"outputs": [],
-		"stateMutability": "nonpayable",
-		"type": "function"
-	}]`
-
-	parsed, err := abi.JSON(strings.NewReader(flipCoreABIJSON))
-	if err != nil {
-		return fmt.Errorf("failed to parse ABI: %w", err)
-	}
-
-	// Default parameters for scoring (high confidence)
-	priceVolatility := big.NewInt(10000)    // 1% volatility
-	agentSuccessRate := big.NewInt(990000)  // 99% success rate
-	agentStake := new(big.Int).Mul(big.NewInt(200000), big.NewInt(1e18)) // 200k tokens
+func (a *Agent) callFinalizeProvisional(ctx context.Context, redemptionID *big.Int, params scoring.ScoringParams) error {
+	// finalizeProvisional uses onlyOperator, not onlyOwner
+	flipCore, err := bindings.NewFLIPCoreTransactor(common.HexToAddress(a.config.Flare.FLIPCoreAddress), a.flareClient)
+	if err != nil {
+		return fmt.Errorf("failed to bind FLIPCore: %w", err)
+	}
 
 	// Get private key from environment (loaded from .env file)
 	privateKeyHex := a.config.Flare.PrivateKey
@@ -307,13 +595,12 @@ func (a *Agent) callFinalizeProvisional(ctx context.Context, redemptionID *big.I
 	auth.GasPrice = gasPrice
 	auth.GasLimit = uint64(500000) // Set reasonable gas limit
 
-	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
-
 	// Send transaction
-	tx, err := bind.NewBoundContract(flipCoreAddr, parsed, a.flareClient, a.flareClient, a.flareClient).Transact(auth, "finalizeProvisional", redemptionID, priceVolatility, agentSuccessRate, agentStake)
+	tx, err := flipCore.FinalizeProvisional(auth, redemptionID, params.PriceVolatility, params.AgentSuccessRate, params.AgentStake)
 	if err != nil {
 		return fmt.Errorf("failed to send transaction: %w", err)
 	}
+	a.audit.RecordFlareTx(tx)
 
 	log.Info().
 		Str("tx_hash", tx.Hash().Hex()).
@@ -324,6 +611,7 @@ func (a *Agent) callFinalizeProvisional(ctx context.Context, redemptionID *big.I
 	if err != nil {
 		return fmt.Errorf("failed to wait for transaction: %w", err)
 	}
+	recordGas("finalizeProvisional", tx, receipt)
 
 	if receipt.Status != 1 {
 		// Try to get more info about failure
@@ -343,194 +631,132 @@ func (a *Agent) callFinalizeProvisional(ctx context.Context, redemptionID *big.I
 	return nil
 }
 
-// recoverFailedFDCSubmissions checks for redemptions where XRP was sent but FDC finalization failed
-func (a *Agent) recoverFailedFDCSubmissions(ctx context.Context) error {
-	log.Info().Msg("Checking for failed FDC submissions to recover...")
-
-	const flipCoreABIJSON = `[
-		{"inputs":[],"name":"nextRedemptionId","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
-		{"inputs":[{"name":"_redemptionId","type":"uint256"}],"name":"redemptions","outputs":[{"name":"user","type":"address"},{"name":"asset","type":"address"},{"name":"amount","type":"uint256"},{"name":"requestedAt","type":"uint256"},{"name":"priceLocked","type":"uint256"},{"name":"hedgeId","type":"uint256"},{"name":"status","type":"uint8"},{"name":"fdcRequestId","type":"uint256"},{"name":"provisionalSettled","type":"bool"},{"name":"xrplAddress","type":"string"}],"stateMutability":"view","type":"function"},
-		{"inputs":[{"name":"","type":"uint256"}],"name":"redemptionXrplTxHash","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"}
-	]`
+// disputeNonPayment proves via FDC that the redemption was never paid on XRPL
+// and reports the failure to FLIPCore
+func (a *Agent) disputeNonPayment(ctx context.Context, redemptionID *big.Int, xrplAddress string, amount, requestedAt *big.Int) error {
+	log.Info().
+		Uint64("redemption_id", redemptionID.Uint64()).
+		Str("xrpl_address", xrplAddress).
+		Str("amount", amount.String()).
+		Msg("Redemption will not be paid, requesting nonexistence proof")
 
-	parsed, err := abi.JSON(strings.NewReader(flipCoreABIJSON))
+	minimal, deadline, err := a.paymentProc.xrplClient.NonPaymentWindow(ctx, time.Unix(requestedAt.Int64(), 0))
 	if err != nil {
-		return fmt.Errorf("failed to parse ABI: %w", err)
+		return fmt.Errorf("failed to build ledger window: %w", err)
 	}
 
-	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
-	contract := bind.NewBoundContract(flipCoreAddr, parsed, a.flareClient, a.flareClient, a.flareClient)
-
-	var result []interface{}
-	err = contract.Call(&bind.CallOpts{Context: ctx}, &result, "nextRedemptionId")
+	proof, err := a.fdcSubmitter.GetNonPaymentProof(ctx, NonPaymentQuery{
+		Destination:       xrplAddress,
+		AmountDrops:       amount,
+		PaymentReference:  generatePaymentReference(redemptionID),
+		MinimalLedger:     minimal,
+		DeadlineLedger:    deadline.Index,
+		DeadlineTimestamp: uint64(deadline.CloseTime.Unix()),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get nextRedemptionId: %w", err)
+		return fmt.Errorf("failed to get nonexistence proof: %w", err)
 	}
-	nextID := result[0].(*big.Int).Uint64()
-
-	for i := uint64(0); i < nextID; i++ {
-		redemptionID := big.NewInt(int64(i))
-		var redemptionResult []interface{}
-		err = contract.Call(&bind.CallOpts{Context: ctx}, &redemptionResult, "redemptions", redemptionID)
-		if err != nil {
-			continue
-		}
-
-		status := redemptionResult[6].(uint8)
-		// Status 2 = EscrowCreated - check if XRP was sent but FDC not finalized
-		if status == 2 {
-			var txHashResult []interface{}
-			err = contract.Call(&bind.CallOpts{Context: ctx}, &txHashResult, "redemptionXrplTxHash", redemptionID)
-			if err != nil || len(txHashResult) == 0 {
-				continue
-			}
 
-			xrplTxHash := txHashResult[0].(string)
-			if xrplTxHash == "" {
-				continue // No XRP payment recorded yet
-			}
-
-			log.Info().
-				Uint64("redemption_id", i).
-				Str("xrpl_tx_hash", xrplTxHash).
-				Msg("Found redemption needing FDC finalization, retrying...")
-
-			// Get FDC proof and submit
-			proof, err := a.fdcSubmitter.GetFDCProof(ctx, xrplTxHash)
-			if err != nil {
-				log.Warn().Err(err).Uint64("redemption_id", i).Msg("Failed to get FDC proof for recovery")
-				continue
-			}
-
-			if err := a.fdcSubmitter.SubmitProof(ctx, redemptionID, proof); err != nil {
-				log.Warn().Err(err).Uint64("redemption_id", i).Msg("Failed to submit FDC proof in recovery")
-			} else {
-				log.Info().Uint64("redemption_id", i).Msg("Successfully recovered FDC submission")
-			}
-		}
+	if err := a.fdcSubmitter.SubmitNonPaymentProof(ctx, redemptionID, proof); err != nil {
+		return fmt.Errorf("failed to submit nonexistence proof: %w", err)
 	}
 
+	log.Info().
+		Uint64("redemption_id", redemptionID.Uint64()).
+		Uint64("fdc_round_id", proof.RoundID).
+		Msg("Non-payment proven, redemption moved to failure path")
 	return nil
 }
 
-// recoverPendingEscrows checks for existing escrows that need XRP payments
-func (a *Agent) recoverPendingEscrows(ctx context.Context) error {
-	log.Info().Msg("Checking for pending escrows to recover...")
-
-	// Get the next redemption ID to know how many to check
-	const flipCoreABIJSON = `[
-		{"inputs":[],"name":"nextRedemptionId","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
-		{"inputs":[{"name":"_redemptionId","type":"uint256"}],"name":"redemptions","outputs":[{"name":"user","type":"address"},{"name":"asset","type":"address"},{"name":"amount","type":"uint256"},{"name":"requestedAt","type":"uint256"},{"name":"priceLocked","type":"uint256"},{"name":"hedgeId","type":"uint256"},{"name":"status","type":"uint8"},{"name":"fdcRequestId","type":"uint256"},{"name":"provisionalSettled","type":"bool"},{"name":"xrplAddress","type":"string"}],"stateMutability":"view","type":"function"},
-		{"inputs":[{"name":"","type":"uint256"}],"name":"redemptionXrplTxHash","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"}
-	]`
+// handleEscrowCreated processes an EscrowCreated event
+func (a *Agent) handleEscrowCreated(ctx context.Context, event EscrowCreatedEvent) error {
+	log.Info().
+		Uint64("redemption_id", event.RedemptionID.Uint64()).
+		Str("user", event.User.Hex()).
+		Str("xrpl_address", event.XRPLAddress).
+		Msg("Processing EscrowCreated event")
 
-	parsed, err := abi.JSON(strings.NewReader(flipCoreABIJSON))
-	if err != nil {
-		return fmt.Errorf("failed to parse ABI: %w", err)
+	redemptionID := event.RedemptionID.Uint64()
+	if !a.admit(WorkflowRedemption, redemptionID, RetryPayment) {
+		return nil
+	}
+	if ctx.Err() != nil {
+		a.interrupt(WorkflowRedemption, redemptionID, RetryPayment, "payment not started")
+		return nil
 	}
 
-	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
-	contract := bind.NewBoundContract(flipCoreAddr, parsed, a.flareClient, a.flareClient, a.flareClient)
+	// From submitting the payment until its hash is recorded, shutdown must
+	// wait: a restart that found no recorded hash would pay the user again
+	critical := context.WithoutCancel(ctx)
 
-	// Get next redemption ID
-	var result []interface{}
-	err = contract.Call(&bind.CallOpts{Context: ctx}, &result, "nextRedemptionId")
-	if err != nil {
-		return fmt.Errorf("failed to get nextRedemptionId: %w", err)
-	}
-	nextID := result[0].(*big.Int).Uint64()
-	log.Info().Uint64("next_redemption_id", nextID).Msg("Found redemptions to check")
+	// A payment sent by an earlier attempt whose hash never reached FLIPCore
+	// is recorded, not sent again
+	txHash, paid := a.sentPayment(redemptionID)
+	if paid {
+		log.Warn().
+			Uint64("redemption_id", redemptionID).
+			Str("xrpl_tx_hash", txHash).
+			Msg("Redemption was already paid, recording the stored payment instead of paying again")
+	} else {
+		if ok, err := a.screen(ctx, WorkflowRedemption, redemptionID, event.XRPLAddress, event.User); !ok {
+			return err
+		}
+		if !a.approvedForPayout(event) {
+			return nil
+		}
 
-	// Check each redemption
-	for i := uint64(0); i < nextID; i++ {
-		redemptionID := big.NewInt(int64(i))
-		var redemptionResult []interface{}
-		err = contract.Call(&bind.CallOpts{Context: ctx}, &redemptionResult, "redemptions", redemptionID)
+		// Step 1: Send XRP payment to user
+		var err error
+		txHash, err = a.paymentProc.SendPayment(
+			critical,
+			event.XRPLAddress,
+			event.Amount,
+			event.PaymentReference,
+		)
 		if err != nil {
-			log.Warn().Err(err).Uint64("redemption_id", i).Msg("Failed to get redemption")
-			continue
+			a.limits.Release(redemptionID)
+			a.workflows.Note(WorkflowRedemption, redemptionID, "payment_failed", err.Error(), "")
+			a.notifier.Notify(EventPaymentFailed, redemptionSubject(redemptionID), err.Error(), map[string]string{
+				"destination":  event.XRPLAddress,
+				"amount_drops": event.Amount.String(),
+			})
+			return fmt.Errorf("failed to send XRP payment: %w", err)
 		}
+		a.workflows.Record(WorkflowRedemption, redemptionID, StatePaymentSent, txHash)
+		a.limits.RecordPayout(redemptionID, event.XRPLAddress, event.Amount)
 
-		// Parse redemption data
-		user := redemptionResult[0].(common.Address)
-		amount := redemptionResult[2].(*big.Int)
-		status := redemptionResult[6].(uint8)
-		xrplAddress := redemptionResult[9].(string)
-
-		// Status 2 = EscrowCreated (waiting for XRP payment)
-		if status == 2 {
-			// Check if payment already recorded on-chain
-			var txHashResult []interface{}
-			err = contract.Call(&bind.CallOpts{Context: ctx}, &txHashResult, "redemptionXrplTxHash", redemptionID)
-			if err == nil && len(txHashResult) > 0 {
-				existingTxHash := txHashResult[0].(string)
-				if existingTxHash != "" {
-					log.Info().
-						Uint64("redemption_id", i).
-						Str("existing_tx_hash", existingTxHash).
-						Msg("Payment already recorded on-chain, skipping")
-					continue
-				}
-			}
-
-			log.Info().
-				Uint64("redemption_id", i).
-				Str("user", user.Hex()).
-				Str("xrpl_address", xrplAddress).
-				Str("amount", amount.String()).
-				Msg("Found pending escrow, processing...")
-
-			// Create event and process it
-			event := EscrowCreatedEvent{
-				RedemptionID:     redemptionID,
-				User:             user,
-				Amount:           amount,
-				XRPLAddress:      strings.Trim(xrplAddress, "\x00"),
-				PaymentReference: generatePaymentReference(redemptionID),
-			}
-
-			if err := a.handleEscrowCreated(ctx, event); err != nil {
-				log.Error().Err(err).Uint64("redemption_id", i).Msg("Failed to process pending escrow")
-			}
-		}
+		log.Info().
+			Str("xrpl_tx_hash", txHash).
+			Msg("XRP payment sent, recording on-chain")
 	}
 
-	return nil
-}
-
-// handleEscrowCreated processes an EscrowCreated event
-func (a *Agent) handleEscrowCreated(ctx context.Context, event EscrowCreatedEvent) error {
-	log.Info().
-		Uint64("redemption_id", event.RedemptionID.Uint64()).
-		Str("user", event.User.Hex()).
-		Str("xrpl_address", event.XRPLAddress).
-		Msg("Processing EscrowCreated event")
-
-	// Step 1: Send XRP payment to user
-	txHash, err := a.paymentProc.SendPayment(
-		ctx,
-		event.XRPLAddress,
-		event.Amount,
-		event.PaymentReference,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to send XRP payment: %w", err)
+	// Step 2: Record payment on-chain (prevents double-payment on restart).
+	// Until it lands the redemption stays payment_sent, and every later
+	// attempt retries the record with the stored hash.
+	if err := a.recordSentPayment(critical, event.RedemptionID, txHash); err != nil {
+		return err
 	}
 
-	log.Info().
-		Str("xrpl_tx_hash", txHash).
-		Msg("XRP payment sent, recording on-chain")
-
-	// Step 2: Record payment on-chain (prevents double-payment on restart)
-	if err := a.recordXrplPayment(ctx, event.RedemptionID, txHash); err != nil {
-		log.Warn().Err(err).Msg("Failed to record payment on-chain, continuing anyway")
+	// A dry-run payment was never submitted, so there is nothing for FDC to prove
+	if a.shadow.Sent(txHash) {
+		a.workflows.Note(WorkflowRedemption, redemptionID, "dry_run", "stopping before FDC, payment was not submitted", "")
+		return nil
 	}
 
 	// Step 3: Wait for XRPL transaction finalization
-	time.Sleep(10 * time.Second) // XRPL finalization typically takes 4-5 seconds
+	select {
+	case <-ctx.Done():
+		a.interrupt(WorkflowRedemption, redemptionID, RetryFDC, "payment recorded, FDC not started")
+		return nil
+	case <-time.After(10 * time.Second): // XRPL finalization typically takes 4-5 seconds
+	}
 
 	// Step 4: Get FDC proof (cryptographic proof of XRP payment)
 	proof, err := a.fdcSubmitter.GetFDCProof(ctx, txHash)
+	if err != nil && ctx.Err() != nil {
+		a.interrupt(WorkflowRedemption, redemptionID, RetryFDC, err.Error())
+		return nil
+	}
 	if err != nil {
 		// Log warning but don't fail - XRP was already sent
 		// FDC proof can be retried later for final settlement
@@ -539,6 +765,7 @@ func (a *Agent) handleEscrowCreated(ctx context.Context, event EscrowCreatedEven
 			Uint64("redemption_id", event.RedemptionID.Uint64()).
 			Str("xrpl_tx_hash", txHash).
 			Msg("FDC proof fetch failed - XRP payment was sent, can retry FDC later")
+		a.workflows.Note(WorkflowRedemption, redemptionID, "fdc_proof_failed", err.Error(), "")
 		return nil
 	}
 
@@ -555,7 +782,14 @@ func (a *Agent) handleEscrowCreated(ctx context.Context, event EscrowCreatedEven
 				Int("retry", retry).
 				Uint64("redemption_id", event.RedemptionID.Uint64()).
 				Msg("Retrying FDC proof submission")
-			time.Sleep(5 * time.Second)
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+		if ctx.Err() != nil {
+			a.interrupt(WorkflowRedemption, redemptionID, RetryFDC, "FDC proof obtained, not submitted")
+			return nil
 		}
 
 		submitErr = a.fdcSubmitter.SubmitProof(ctx, event.RedemptionID, proof)
@@ -563,6 +797,8 @@ func (a *Agent) handleEscrowCreated(ctx context.Context, event EscrowCreatedEven
 			log.Info().
 				Uint64("redemption_id", event.RedemptionID.Uint64()).
 				Msg("FDC proof submitted successfully - redemption complete")
+			a.workflows.Record(WorkflowRedemption, redemptionID, StateFinalized, fmt.Sprintf("FDC round %d", proof.RoundID))
+			a.notifySettled(redemptionID, txHash, proof.RoundID)
 			return nil
 		}
 
@@ -577,25 +813,66 @@ func (a *Agent) handleEscrowCreated(ctx context.Context, event EscrowCreatedEven
 		Err(submitErr).
 		Uint64("redemption_id", event.RedemptionID.Uint64()).
 		Msg("FDC proof submission failed after all retries")
+	a.workflows.Note(WorkflowRedemption, redemptionID, "fdc_submit_failed", submitErr.Error(), "")
 	return nil
 }
 
+// Attempts at recording a sent payment's hash before escalating
+const (
+	recordPaymentAttempts = 3
+	recordPaymentDelay    = 5 * time.Second
+)
+
+// sentPayment returns the hash of a payment this agent sent for a redemption
+// but has not recorded on FLIPCore. It reads the timeline rather than the
+// state, which a later deferral or interruption overwrites.
+func (a *Agent) sentPayment(redemptionID uint64) (txHash string, paid bool) {
+	r, _ := a.workflows.Get(WorkflowRedemption, redemptionID)
+	for i := len(r.Timeline) - 1; i >= 0; i-- {
+		switch r.Timeline[i].Event {
+		case StatePaymentRecorded, StateFinalized:
+			return "", false
+		case StatePaymentSent:
+			return r.Timeline[i].Detail, true
+		}
+	}
+	return "", false
+}
+
+// recordSentPayment records a sent payment's hash on FLIPCore, retrying a
+// few times. When it still fails an operator is notified: the redemption
+// stays payment_sent and is never paid again.
+func (a *Agent) recordSentPayment(ctx context.Context, redemptionID *big.Int, txHash string) error {
+	id := redemptionID.Uint64()
+	var err error
+	for attempt := 1; attempt <= recordPaymentAttempts; attempt++ {
+		if err = a.recordXrplPayment(ctx, redemptionID, txHash); err == nil {
+			a.workflows.Record(WorkflowRedemption, id, StatePaymentRecorded, txHash)
+			return nil
+		}
+		log.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Uint64("redemption_id", id).
+			Str("xrpl_tx_hash", txHash).
+			Msg("Failed to record payment on-chain")
+		if attempt < recordPaymentAttempts {
+			time.Sleep(recordPaymentDelay)
+		}
+	}
+
+	a.workflows.Note(WorkflowRedemption, id, "record_payment_failed", err.Error(), "")
+	a.notifier.Notify(EventRecordFailed, redemptionSubject(id),
+		"XRPL payment was sent but could not be recorded on FLIPCore: "+err.Error(),
+		map[string]string{"xrpl_tx_hash": txHash})
+	return fmt.Errorf("payment %s sent but not recorded on-chain: %w", txHash, err)
+}
+
 // recordXrplPayment records the XRPL tx hash on-chain to prevent double-payment
 func (a *Agent) recordXrplPayment(ctx context.Context, redemptionID *big.Int, xrplTxHash string) error {
-	const recordPaymentABI = `[{
-		"inputs": [
-			{"name": "_redemptionId", "type": "uint256"},
-			{"name": "_xrplTxHash", "type": "string"}
-		],
-		"name": "recordXrplPayment",
-		"outputs": [],
-		"stateMutability": "nonpayable",
-		"type": "function"
-	}]`
-
-	parsed, err := abi.JSON(strings.NewReader(recordPaymentABI))
+	flipCore, err := bindings.NewFLIPCoreTransactor(common.HexToAddress(a.config.Flare.FLIPCoreAddress), a.flareClient)
 	if err != nil {
-		return fmt.Errorf("failed to parse ABI: %w", err)
+		return fmt.Errorf("failed to bind FLIPCore: %w", err)
 	}
 
 	privateKeyHex := a.config.Flare.PrivateKey
@@ -627,11 +904,11 @@ func (a *Agent) recordXrplPayment(ctx context.Context, redemptionID *big.Int, xr
 	auth.GasPrice = gasPrice
 	auth.GasLimit = uint64(200000)
 
-	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
-	tx, err := bind.NewBoundContract(flipCoreAddr, parsed, a.flareClient, a.flareClient, a.flareClient).Transact(auth, "recordXrplPayment", redemptionID, xrplTxHash)
+	tx, err := flipCore.RecordXrplPayment(auth, redemptionID, xrplTxHash)
 	if err != nil {
 		return fmt.Errorf("failed to send transaction: %w", err)
 	}
+	a.audit.RecordFlareTx(tx)
 
 	log.Info().
 		Str("tx_hash", tx.Hash().Hex()).
@@ -641,6 +918,7 @@ func (a *Agent) recordXrplPayment(ctx context.Context, redemptionID *big.Int, xr
 	if err != nil {
 		return fmt.Errorf("failed to wait for transaction: %w", err)
 	}
+	recordGas("recordXrplPayment", tx, receipt)
 
 	if receipt.Status != 1 {
 		return fmt.Errorf("transaction failed with status %d", receipt.Status)
@@ -670,38 +948,86 @@ func (a *Agent) handleMintingRequested(ctx context.Context, event MintingRequest
 		Str("fxrp_amount", event.FxrpAmount.String()).
 		Msg("Processing MintingRequested event")
 
-	// Call finalizeMintingProvisional to match LP and transfer FXRP to user
-	err := a.callFinalizeMintingProvisional(ctx, event.MintingID)
+	a.workflows.Record(WorkflowMinting, mintingID, StateRequested,
+		fmt.Sprintf("%s drops via %s", event.XrpAmount, event.XrplTxHash))
+	if !a.admit(WorkflowMinting, mintingID, RetryProvisional) {
+		return nil
+	}
+	if ctx.Err() != nil {
+		a.interrupt(WorkflowMinting, mintingID, RetryProvisional, "not started")
+		return nil
+	}
+	if ok, err := a.screen(ctx, WorkflowMinting, mintingID, "", event.User); !ok {
+		return err
+	}
+
+	req := MintingAttestationRequest{
+		MintingID:               event.MintingID,
+		Asset:                   event.Asset,
+		CollateralReservationID: event.CollateralReservationID,
+		XrplTxHash:              event.XrplTxHash,
+		XrpAmount:               event.XrpAmount,
+		FxrpAmount:              event.FxrpAmount,
+	}
+
+	// Verify the deposit on XRPL, then match LP and transfer FXRP to user
+	settled, err := a.settleMintingProvisional(ctx, req)
+	if err != nil && ctx.Err() != nil {
+		a.interrupt(WorkflowMinting, mintingID, RetryProvisional, err.Error())
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to call finalizeMintingProvisional: %w", err)
+		return err
 	}
 
 	a.processedMintings[mintingID] = true
+	if !settled {
+		return nil
+	}
 	log.Info().Uint64("minting_id", mintingID).Msg("Minting provisional settlement complete")
 
+	// Prove the deposit through FDC so the LP is repaid from the minted FXRP
+	a.finalizeMintingWithFDC(ctx, req)
+
 	return nil
 }
 
-// callFinalizeMintingProvisional calls FLIPCore.finalizeMintingProvisional
-func (a *Agent) callFinalizeMintingProvisional(ctx context.Context, mintingID *big.Int) error {
-	const flipCoreABIJSON = `[{
-		"inputs": [
-			{"name": "_mintingId", "type": "uint256"},
-			{"name": "_priceVolatility", "type": "uint256"}
-		],
-		"name": "finalizeMintingProvisional",
-		"outputs": [],
-		"stateMutability": "nonpayable",
-		"type": "function"
-	}]`
-
-	parsed, err := abi.JSON(strings.NewReader(flipCoreABIJSON))
+// finalizeMintingWithFDC drives handleMintingFDCAttestation for a minting.
+// Failures are logged and retried by recoverPendingMintings on the next start.
+func (a *Agent) finalizeMintingWithFDC(ctx context.Context, req MintingAttestationRequest) {
+	log.Info().
+		Uint64("minting_id", req.MintingID.Uint64()).
+		Str("xrpl_tx", req.XrplTxHash).
+		Str("xrp_amount", req.XrpAmount.String()).
+		Msg("Requesting FDC attestation for minting deposit")
+
+	err := a.fdcSubmitter.FinalizeMinting(ctx, req)
+	if err != nil && ctx.Err() != nil {
+		a.interrupt(WorkflowMinting, req.MintingID.Uint64(), RetryFDC, err.Error())
+		return
+	}
 	if err != nil {
-		return fmt.Errorf("failed to parse ABI: %w", err)
+		log.Warn().
+			Err(err).
+			Uint64("minting_id", req.MintingID.Uint64()).
+			Str("xrpl_tx", req.XrplTxHash).
+			Msg("Minting FDC finalization failed - can retry later")
+		a.workflows.Note(WorkflowMinting, req.MintingID.Uint64(), "fdc_failed", err.Error(), "")
+		return
 	}
+	a.workflows.Record(WorkflowMinting, req.MintingID.Uint64(), StateFinalized, "")
 
-	// Default low volatility for high confidence
-	priceVolatility := big.NewInt(10000) // 1% volatility
+	log.Info().
+		Uint64("minting_id", req.MintingID.Uint64()).
+		Msg("Minting finalized via FDC")
+}
+
+// callFinalizeMintingProvisional calls FLIPCore.finalizeMintingProvisional
+func (a *Agent) callFinalizeMintingProvisional(ctx context.Context, mintingID *big.Int, priceVolatility *big.Int) error {
+	flipCore, err := bindings.NewFLIPCoreTransactor(common.HexToAddress(a.config.Flare.FLIPCoreAddress), a.flareClient)
+	if err != nil {
+		return fmt.Errorf("failed to bind FLIPCore: %w", err)
+	}
 
 	privateKeyHex := a.config.Flare.PrivateKey
 	if privateKeyHex == "" {
@@ -738,12 +1064,11 @@ func (a *Agent) callFinalizeMintingProvisional(ctx context.Context, mintingID *b
 	auth.GasPrice = gasPrice
 	auth.GasLimit = uint64(500000)
 
-	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
-
-	tx, err := bind.NewBoundContract(flipCoreAddr, parsed, a.flareClient, a.flareClient, a.flareClient).Transact(auth, "finalizeMintingProvisional", mintingID, priceVolatility)
+	tx, err := flipCore.FinalizeMintingProvisional(auth, mintingID, priceVolatility)
 	if err != nil {
 		return fmt.Errorf("failed to send transaction: %w", err)
 	}
+	a.audit.RecordFlareTx(tx)
 
 	log.Info().
 		Str("tx_hash", tx.Hash().Hex()).
@@ -754,6 +1079,7 @@ func (a *Agent) callFinalizeMintingProvisional(ctx context.Context, mintingID *b
 	if err != nil {
 		return fmt.Errorf("failed to wait for transaction: %w", err)
 	}
+	recordGas("finalizeMintingProvisional", tx, receipt)
 
 	if receipt.Status != 1 {
 		// Log more details for debugging
@@ -776,30 +1102,21 @@ func (a *Agent) callFinalizeMintingProvisional(ctx context.Context, mintingID *b
 
 // checkLPLiquidity checks if there's LP liquidity available for the given token
 func (a *Agent) checkLPLiquidity(ctx context.Context, tokenAddress common.Address, amount *big.Int) (bool, error) {
-	const lpRegistryABI = `[
-		{"inputs":[{"name":"_token","type":"address"}],"name":"getActiveERC20LPCount","outputs":[{"name":"count","type":"uint256"}],"stateMutability":"view","type":"function"},
-		{"inputs":[{"name":"_token","type":"address"},{"name":"_index","type":"uint256"}],"name":"activeERC20LPs","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
-		{"inputs":[{"name":"_lp","type":"address"},{"name":"_token","type":"address"}],"name":"erc20Positions","outputs":[{"name":"lp","type":"address"},{"name":"asset","type":"address"},{"name":"depositedAmount","type":"uint256"},{"name":"availableAmount","type":"uint256"},{"name":"minHaircut","type":"uint256"},{"name":"maxDelay","type":"uint256"},{"name":"totalEarned","type":"uint256"},{"name":"active","type":"bool"}],"stateMutability":"view","type":"function"}
-	]`
-
-	parsed, err := abi.JSON(strings.NewReader(lpRegistryABI))
+	// LP Registry address from config.yaml
+	registry, err := bindings.NewLiquidityProviderRegistryCaller(a.flip.Address(ContractLPRegistry), a.flareClient)
 	if err != nil {
 		return false, err
 	}
-
-	// LP Registry address from config.yaml
-	lpRegistryAddr := common.HexToAddress("0xbc8423cd34653b1D64a8B54C4D597d90C4CEe100")
-	contract := bind.NewBoundContract(lpRegistryAddr, parsed, a.flareClient, a.flareClient, a.flareClient)
+	opts := &bind.CallOpts{Context: ctx}
 
 	// Get count of active LPs for this token
-	var countResult []interface{}
-	err = contract.Call(&bind.CallOpts{Context: ctx}, &countResult, "getActiveERC20LPCount", tokenAddress)
+	count, err := registry.GetActiveERC20LPCount(opts, tokenAddress)
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to query active ERC20 LP count")
 		return false, err
 	}
 
-	lpCount := countResult[0].(*big.Int).Uint64()
+	lpCount := count.Uint64()
 	if lpCount == 0 {
 		log.Warn().
 			Str("token", tokenAddress.Hex()).
@@ -811,26 +1128,21 @@ func (a *Agent) checkLPLiquidity(ctx context.Context, tokenAddress common.Addres
 	totalAvailable := big.NewInt(0)
 	for i := uint64(0); i < lpCount; i++ {
 		// Get LP address at index
-		var lpResult []interface{}
-		err = contract.Call(&bind.CallOpts{Context: ctx}, &lpResult, "activeERC20LPs", tokenAddress, big.NewInt(int64(i)))
+		lp, err := registry.ActiveERC20LPs(opts, tokenAddress, new(big.Int).SetUint64(i))
 		if err != nil {
 			continue
 		}
-		lp := lpResult[0].(common.Address)
 
 		// Get LP position
-		var posResult []interface{}
-		err = contract.Call(&bind.CallOpts{Context: ctx}, &posResult, "erc20Positions", lp, tokenAddress)
+		position, err := registry.Erc20Positions(opts, lp, tokenAddress)
 		if err != nil {
 			continue
 		}
-		availableAmount := posResult[3].(*big.Int)
-		active := posResult[7].(bool)
-		if active && availableAmount.Cmp(big.NewInt(0)) > 0 {
-			totalAvailable.Add(totalAvailable, availableAmount)
+		if position.Active && position.AvailableAmount.Sign() > 0 {
+			totalAvailable.Add(totalAvailable, position.AvailableAmount)
 			log.Debug().
 				Str("lp", lp.Hex()).
-				Str("available", availableAmount.String()).
+				Str("available", position.AvailableAmount.String()).
 				Msg("Found LP with liquidity")
 		}
 	}
@@ -846,98 +1158,3 @@ func (a *Agent) checkLPLiquidity(ctx context.Context, tokenAddress common.Addres
 	return hasLiquidity, nil
 }
 
-// recoverPendingMintings checks for pending minting requests that need processing
-func (a *Agent) recoverPendingMintings(ctx context.Context) error {
-	log.Info().Msg("Checking for pending minting requests to recover...")
-
-	const flipCoreABIJSON = `[
-		{"inputs":[],"name":"nextMintingId","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
-		{"inputs":[{"name":"_mintingId","type":"uint256"}],"name":"mintingRequests","outputs":[{"name":"user","type":"address"},{"name":"asset","type":"address"},{"name":"collateralReservationId","type":"uint256"},{"name":"xrplTxHash","type":"string"},{"name":"xrpAmount","type":"uint256"},{"name":"fxrpAmount","type":"uint256"},{"name":"requestedAt","type":"uint256"},{"name":"priceLocked","type":"uint256"},{"name":"hedgeId","type":"uint256"},{"name":"status","type":"uint8"},{"name":"fdcRequestId","type":"uint256"},{"name":"matchedLP","type":"address"},{"name":"haircutRate","type":"uint256"},{"name":"userAuthorizedFlip","type":"bool"}],"stateMutability":"view","type":"function"}
-	]`
-
-	parsed, err := abi.JSON(strings.NewReader(flipCoreABIJSON))
-	if err != nil {
-		return fmt.Errorf("failed to parse ABI: %w", err)
-	}
-
-	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
-	contract := bind.NewBoundContract(flipCoreAddr, parsed, a.flareClient, a.flareClient, a.flareClient)
-
-	var result []interface{}
-	err = contract.Call(&bind.CallOpts{Context: ctx}, &result, "nextMintingId")
-	if err != nil {
-		return fmt.Errorf("failed to get nextMintingId: %w", err)
-	}
-	nextID := result[0].(*big.Int).Uint64()
-	log.Info().Uint64("next_minting_id", nextID).Msg("Found minting requests to check")
-
-	for i := uint64(0); i < nextID; i++ {
-		mintingID := big.NewInt(int64(i))
-		var mintingResult []interface{}
-		err = contract.Call(&bind.CallOpts{Context: ctx}, &mintingResult, "mintingRequests", mintingID)
-		if err != nil {
-			log.Warn().Err(err).Uint64("minting_id", i).Msg("Failed to get minting request")
-			continue
-		}
-
-		// Parse minting request fields
-		user := mintingResult[0].(common.Address)
-		asset := mintingResult[1].(common.Address)
-		fxrpAmount := mintingResult[5].(*big.Int)
-		status := mintingResult[9].(uint8)
-
-		// Log current status
-		statusNames := []string{"Pending", "ProvisionalSettled", "QueuedForFDC", "Finalized", "Failed", "Timeout"}
-		statusName := "Unknown"
-		if int(status) < len(statusNames) {
-			statusName = statusNames[status]
-		}
-
-		log.Debug().
-			Uint64("minting_id", i).
-			Uint8("status", status).
-			Str("status_name", statusName).
-			Str("asset", asset.Hex()).
-			Msg("Checking minting request")
-
-		// Status 0 = Pending (needs processing)
-		if status == 0 {
-			log.Info().
-				Uint64("minting_id", i).
-				Str("user", user.Hex()).
-				Str("fxrp_amount", fxrpAmount.String()).
-				Str("asset", asset.Hex()).
-				Msg("Found pending minting, checking LP liquidity...")
-
-			// Check LP liquidity first
-			hasLiquidity, err := a.checkLPLiquidity(ctx, asset, fxrpAmount)
-			if err != nil {
-				log.Warn().Err(err).Msg("Failed to check LP liquidity")
-			}
-
-			if !hasLiquidity {
-				log.Warn().
-					Uint64("minting_id", i).
-					Str("asset", asset.Hex()).
-					Msg("Skipping minting - no LP liquidity available. Register LP via LiquidityProviderRegistry.registerERC20Position()")
-				continue
-			}
-
-			log.Info().Uint64("minting_id", i).Msg("LP liquidity available, processing minting...")
-
-			if err := a.callFinalizeMintingProvisional(ctx, mintingID); err != nil {
-				log.Error().Err(err).Uint64("minting_id", i).Msg("Failed to process pending minting")
-			} else {
-				a.processedMintings[i] = true
-			}
-		} else {
-			log.Debug().
-				Uint64("minting_id", i).
-				Str("status", statusName).
-				Msg("Minting not in Pending status, skipping")
-		}
-	}
-
-	return nil
-}
-
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestSentPaymentSurvivesDeferral(t *testing.T) {
+	a := newScreeningTestAgent(t, nil, false)
+
+	if _, paid := a.sentPayment(5); paid {
+		t.Fatal("unknown redemption reported as paid")
+	}
+
+	a.workflows.Record(WorkflowRedemption, 5, StatePaymentSent, "ABCDEF")
+	// A pause or shutdown after paying overwrites the state, not the payment
+	a.workflows.Defer(WorkflowRedemption, 5, RetryPayment, "workflow paused")
+	a.workflows.Interrupt(WorkflowRedemption, 5, RetryPayment, "shutting down")
+	if txHash, paid := a.sentPayment(5); !paid || txHash != "ABCDEF" {
+		t.Fatalf("sentPayment = %q, %v; want ABCDEF, true", txHash, paid)
+	}
+
+	a.workflows.Record(WorkflowRedemption, 5, StatePaymentRecorded, "ABCDEF")
+	if _, paid := a.sentPayment(5); paid {
+		t.Fatal("recorded payment reported as unrecorded")
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultAuditJournal is used when audit.journal is unset
+const defaultAuditJournal = "audit.jsonl"
+
+// auditGenesisHash is the prev_hash of the first entry
+var auditGenesisHash = strings.Repeat("0", 64)
+
+// Audit entry types
+const (
+	AuditEventObserved = "event_observed"     // FLIPCore event picked up by the agent
+	AuditScoreInputs   = "score_inputs"       // Scoring inputs and the routing outcome
+	AuditXRPLBuilt     = "xrpl_tx_built"      // Autofilled payment, as signed
+	AuditXRPLSigned    = "xrpl_tx_signed"     // Signed blob and hash, before submission
+	AuditXRPLSubmitted = "xrpl_tx_submitted"  // Signed blob submitted and validated
+	AuditEVMTxSent     = "evm_tx_sent"        // Flare transaction broadcast
+	AuditFDCProof      = "fdc_proof_received" // FDC proof fetched from the DA layer
+	AuditOverride      = "operator_override"  // Action taken by an operator
+)
+
+// AuditEntry is one line of the audit journal. Hash is the SHA-256 of the
+// entry's JSON encoding with Hash empty, so it covers PrevHash and chains
+// every entry to the one before it.
+type AuditEntry struct {
+	Seq      uint64          `json:"seq"`
+	Time     time.Time       `json:"time"`
+	Type     string          `json:"type"`
+	Workflow string          `json:"workflow,omitempty"`
+	ID       *uint64         `json:"id,omitempty"`
+	Operator string          `json:"operator,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+}
+
+// computeHash returns the hash an entry should carry
+func (e AuditEntry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditJournal appends hash-chained entries to a JSON lines file. Each
+// append locks the file and chains onto its current last line, so the agent
+// and CLI commands run next to it share one chain. Write failures are logged
+// rather than returned so the journal never blocks settlement.
+type AuditJournal struct {
+	path string
+	mu   sync.Mutex
+}
+
+var (
+	auditMu       sync.Mutex
+	auditJournals = make(map[string]*AuditJournal)
+)
+
+// openAudit returns the journal for config.Audit.Journal. Every component in
+// the process shares one journal per path.
+func openAudit(config *Config) (*AuditJournal, error) {
+	path := config.Audit.Journal
+	if path == "" {
+		path = defaultAuditJournal
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if j, ok := auditJournals[path]; ok {
+		return j, nil
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create audit journal dir: %w", err)
+		}
+	}
+	j := &AuditJournal{path: path}
+	auditJournals[path] = j
+	return j, nil
+}
+
+// Record appends an entry that is not about a single ID
+func (j *AuditJournal) Record(entryType string, data interface{}) {
+	j.append(AuditEntry{Type: entryType}, data)
+}
+
+// RecordFor appends an entry about one redemption or minting
+func (j *AuditJournal) RecordFor(entryType, workflow string, id uint64, data interface{}) {
+	j.append(AuditEntry{Type: entryType, Workflow: workflow, ID: &id}, data)
+}
+
+// RecordOverride appends an operator action. id is nil for actions that are
+// not about a single ID.
+func (j *AuditJournal) RecordOverride(operator, workflow string, id *uint64, data interface{}) {
+	j.append(AuditEntry{Type: AuditOverride, Workflow: workflow, ID: id, Operator: operator}, data)
+}
+
+// RecordFlareTx appends a broadcast Flare transaction, naming the FLIPCore
+// method and ID it acts on when the selector is known
+func (j *AuditJournal) RecordFlareTx(tx *types.Transaction) {
+	method, workflow, id := decodeFlareCall(tx.Data())
+	data := map[string]string{
+		"hash":   tx.Hash().Hex(),
+		"method": method,
+		"nonce":  fmt.Sprint(tx.Nonce()),
+		"value":  tx.Value().String(),
+	}
+	if tx.To() != nil {
+		data["to"] = tx.To().Hex()
+	}
+	j.append(AuditEntry{Type: AuditEVMTxSent, Workflow: workflow, ID: id}, data)
+}
+
+func (j *AuditJournal) append(entry AuditEntry, data interface{}) {
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			log.Error().Err(err).Str("type", entry.Type).Msg("Failed to encode audit entry")
+			return
+		}
+		entry.Data = raw
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.write(entry); err != nil {
+		log.Error().Err(err).Str("path", j.path).Str("type", entry.Type).Msg("Failed to write audit journal")
+	}
+}
+
+// write chains entry onto the last line of the file and appends it.
+// Callers hold mu.
+func (j *AuditJournal) write(entry AuditEntry) error {
+	f, err := os.OpenFile(j.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Other processes (CLI commands) append to the same chain
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock audit journal: %w", err)
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	entry.PrevHash = auditGenesisHash
+	entry.Seq = 1
+	last, err := lastLine(f)
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		var prev AuditEntry
+		if err := json.Unmarshal(last, &prev); err != nil {
+			return fmt.Errorf("last audit entry is unreadable, run audit-verify: %w", err)
+		}
+		entry.PrevHash = prev.Hash
+		entry.Seq = prev.Seq + 1
+	}
+
+	entry.Time = time.Now().UTC()
+	if entry.Hash, err = entry.computeHash(); err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// lastLine returns the last non-empty line of f, or nil for an empty file
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	const chunk = 4096
+	end := info.Size()
+	var tail []byte
+	for end > 0 {
+		start := max(end-chunk, 0)
+		buf := make([]byte, end-start)
+		if _, err := f.ReadAt(buf, start); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		tail = append(buf, tail...)
+		end = start
+
+		trimmed := bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+	if trimmed := bytes.TrimRight(tail, "\n"); len(trimmed) > 0 {
+		return trimmed, nil
+	}
+	return nil, nil
+}
+
+// decodeFlareCall names the FLIPCore method in calldata and the workflow ID
+// its first argument identifies
+func decodeFlareCall(data []byte) (method, workflow string, id *uint64) {
+	if len(data) < 4 {
+		return "transfer", "", nil
+	}
+	signature, ok := shadowSelectors[string(data[:4])]
+	if !ok {
+		return "0x" + hex.EncodeToString(data[:4]), "", nil
+	}
+	method = signature[:strings.Index(signature, "(")]
+	if kind := shadowMethods[signature]; kind != "" && len(data) >= 36 {
+		workflow = kind
+		id = new(uint64)
+		*id = new(big.Int).SetBytes(data[4:36]).Uint64()
+	}
+	return method, workflow, id
+}
+
+// redemptionFromReference returns the redemption a payment reference
+// (memo) identifies. Redemption payments carry the ID as 32 hex bytes.
+func redemptionFromReference(reference string) *uint64 {
+	if len(reference) != 64 {
+		return nil
+	}
+	id, ok := new(big.Int).SetString(reference, 16)
+	if !ok || !id.IsUint64() {
+		return nil
+	}
+	v := id.Uint64()
+	return &v
+}
+
+// AuditProblem is a break in the chain found by VerifyAuditJournal
+type AuditProblem struct {
+	Line    int    `json:"line"`
+	Seq     uint64 `json:"seq,omitempty"`
+	Problem string `json:"problem"`
+}
+
+// AuditVerification is the result of checking a journal
+type AuditVerification struct {
+	Entries  int            `json:"entries"`
+	HeadSeq  uint64         `json:"head_seq"`
+	HeadHash string         `json:"head_hash"`
+	Problems []AuditProblem `json:"problems,omitempty"`
+}
+
+// Valid reports whether the chain is intact
+func (v *AuditVerification) Valid() bool {
+	return len(v.Problems) == 0
+}
+
+// ReadAuditJournal loads every entry and checks the chain: each line must
+// parse, carry the hash of its own contents, follow the previous entry's
+// sequence number and commit to the previous entry's hash. Entries are
+// returned even when problems are found.
+func ReadAuditJournal(path string) ([]AuditEntry, *AuditVerification, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open audit journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	result := &AuditVerification{}
+	prevHash, prevSeq := auditGenesisHash, uint64(0)
+	problem := func(line int, seq uint64, format string, args ...interface{}) {
+		result.Problems = append(result.Problems, AuditProblem{Line: line, Seq: seq, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	reader := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			var entry AuditEntry
+			if jsonErr := json.Unmarshal(data, &entry); jsonErr != nil {
+				problem(line, 0, "unreadable entry: %v", jsonErr)
+			} else {
+				if entry.Seq != prevSeq+1 {
+					problem(line, entry.Seq, "sequence jumps from %d to %d", prevSeq, entry.Seq)
+				}
+				if entry.PrevHash != prevHash {
+					problem(line, entry.Seq, "prev_hash does not match the previous entry's hash")
+				}
+				if hash, _ := entry.computeHash(); hash != entry.Hash {
+					problem(line, entry.Seq, "hash does not match the entry's contents")
+				}
+				entries = append(entries, entry)
+				prevHash, prevSeq = entry.Hash, entry.Seq
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read audit journal: %w", err)
+		}
+	}
+
+	result.Entries = len(entries)
+	result.HeadSeq = prevSeq
+	result.HeadHash = prevHash
+	return entries, result, nil
+}
+
+// auditEvent wraps a FLIPCore event for an event_observed entry
+func auditEvent(name string, event interface{}) map[string]interface{} {
+	return map[string]interface{}{"event": name, "fields": event}
+}
@@ -0,0 +1,127 @@
+// Command bindgen generates the bindings package from Foundry artifacts.
+// It binds every contract whose source lives under -src and writes
+// contracts_gen.go, which registers each contract's metadata with the package.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// artifact is the subset of a Foundry out/<File>.sol/<Contract>.json we use
+type artifact struct {
+	ABI      json.RawMessage `json:"abi"`
+	Bytecode struct {
+		Object string `json:"object"`
+	} `json:"bytecode"`
+	MethodIdentifiers map[string]string `json:"methodIdentifiers"`
+	Metadata          struct {
+		Settings struct {
+			CompilationTarget map[string]string `json:"compilationTarget"`
+		} `json:"settings"`
+	} `json:"metadata"`
+	AST struct {
+		AbsolutePath string `json:"absolutePath"`
+	} `json:"ast"`
+}
+
+// sourcePath returns the Solidity file the artifact was compiled from
+func (a *artifact) sourcePath() string {
+	for path := range a.Metadata.Settings.CompilationTarget {
+		return path
+	}
+	return a.AST.AbsolutePath
+}
+
+func main() {
+	artifactsDir := flag.String("artifacts", "../../out", "Foundry out/ directory")
+	srcPrefix := flag.String("src", "contracts/", "Only bind contracts whose source path starts with this prefix")
+	outDir := flag.String("out", ".", "Output directory")
+	pkg := flag.String("pkg", "bindings", "Go package name")
+	flag.Parse()
+
+	if err := run(*artifactsDir, *srcPrefix, *outDir, *pkg); err != nil {
+		fmt.Fprintln(os.Stderr, "bindgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(artifactsDir, srcPrefix, outDir, pkg string) error {
+	paths, err := filepath.Glob(filepath.Join(artifactsDir, "*.sol", "*.json"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no artifacts in %s, run forge build first", artifactsDir)
+	}
+	sort.Strings(paths)
+
+	var (
+		types     []string
+		abis      []string
+		bytecodes []string
+		fsigs     []map[string]string
+	)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var a artifact
+		if err := json.Unmarshal(data, &a); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if !strings.HasPrefix(a.sourcePath(), srcPrefix) || len(a.ABI) == 0 {
+			continue
+		}
+
+		// The same contract name compiled from two files (e.g. a test copy)
+		// would produce clashing Go types
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		for _, existing := range types {
+			if existing == name {
+				return fmt.Errorf("duplicate contract name %s (%s)", name, path)
+			}
+		}
+
+		sigs := make(map[string]string, len(a.MethodIdentifiers))
+		for sig, selector := range a.MethodIdentifiers {
+			sigs[selector] = sig
+		}
+
+		types = append(types, name)
+		abis = append(abis, string(a.ABI))
+		bytecodes = append(bytecodes, a.Bytecode.Object)
+		fsigs = append(fsigs, sigs)
+	}
+	if len(types) == 0 {
+		return fmt.Errorf("no artifacts compiled from %s", srcPrefix)
+	}
+
+	code, err := bind.Bind(types, abis, bytecodes, fsigs, pkg, bind.LangGo, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to generate bindings: %w", err)
+	}
+
+	var registry strings.Builder
+	registry.WriteString("\nfunc init() {\n")
+	for _, name := range types {
+		fmt.Fprintf(&registry, "\tregister(%q, %sMetaData)\n", name, name)
+	}
+	registry.WriteString("}\n")
+
+	out := filepath.Join(outDir, "contracts_gen.go")
+	if err := os.WriteFile(out, []byte(code+registry.String()), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("bindgen: wrote %d contracts to %s\n", len(types), out)
+	return nil
+}
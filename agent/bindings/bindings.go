@@ -0,0 +1,46 @@
+// Package bindings holds typed Go bindings for the contracts under contracts/,
+// generated from the Foundry out/ artifacts, plus a check that deployed
+// bytecode still exposes the selectors the bindings were generated from.
+//
+// Regenerate after any contract change:
+//
+//	forge build
+//	cd agent && go generate ./bindings
+package bindings
+
+//go:generate go run ./bindgen -artifacts ../../out -src contracts/ -out .
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// registry maps contract names to their generated metadata. Generated files
+// add entries from init.
+var registry = make(map[string]*bind.MetaData)
+
+func register(name string, metadata *bind.MetaData) {
+	registry[name] = metadata
+}
+
+// Contracts returns the names of all generated contracts, sorted
+func Contracts() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ABI returns the parsed ABI of a generated contract
+func ABI(name string) (*abi.ABI, error) {
+	metadata, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("no bindings generated for %s", name)
+	}
+	return metadata.GetAbi()
+}
@@ -0,0 +1,91 @@
+package bindings
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// SelectorDriftError lists methods whose selectors are absent from deployed
+// bytecode
+type SelectorDriftError struct {
+	Contract string
+	Address  common.Address
+	Missing  []string // Method signatures
+}
+
+func (e *SelectorDriftError) Error() string {
+	return fmt.Sprintf("%s at %s is missing %d selector(s): %s",
+		e.Contract, e.Address.Hex(), len(e.Missing), strings.Join(e.Missing, ", "))
+}
+
+// CodeSelectors returns every value of up to four bytes pushed by code. The
+// solc dispatcher compares calldata against each selector with a PUSH, using
+// PUSH3 or shorter when the selector has leading zero bytes.
+func CodeSelectors(code []byte) map[[4]byte]bool {
+	selectors := make(map[[4]byte]bool)
+	for pc := 0; pc < len(code); pc++ {
+		op := vm.OpCode(code[pc])
+		if op < vm.PUSH1 || op > vm.PUSH32 {
+			continue
+		}
+		size := int(op-vm.PUSH1) + 1
+		if size <= 4 && pc+size < len(code) {
+			var selector [4]byte
+			copy(selector[4-size:], code[pc+1:pc+1+size])
+			selectors[selector] = true
+		}
+		pc += size
+	}
+	return selectors
+}
+
+// MissingSelectors returns the signatures of methods in parsed whose selectors
+// do not appear in code. If methods is non-empty only those are checked.
+func MissingSelectors(code []byte, parsed *abi.ABI, methods ...string) []string {
+	if len(methods) == 0 {
+		for name := range parsed.Methods {
+			methods = append(methods, name)
+		}
+	}
+
+	present := CodeSelectors(code)
+	var missing []string
+	for _, name := range methods {
+		method, ok := parsed.Methods[name]
+		if !ok {
+			missing = append(missing, name+" (not in ABI)")
+			continue
+		}
+		var selector [4]byte
+		copy(selector[:], method.ID)
+		if !present[selector] {
+			missing = append(missing, method.Sig)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// CheckDeployed fetches the code at address and returns a *SelectorDriftError
+// if any of the methods are not dispatched by it
+func CheckDeployed(ctx context.Context, client bind.ContractCaller, contract string, address common.Address, parsed *abi.ABI, methods ...string) error {
+	code, err := client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s code: %w", contract, err)
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("no code deployed for %s at %s", contract, address.Hex())
+	}
+
+	if missing := MissingSelectors(code, parsed, methods...); len(missing) > 0 {
+		return &SelectorDriftError{Contract: contract, Address: address, Missing: missing}
+	}
+	return nil
+}
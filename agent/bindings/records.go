@@ -0,0 +1,27 @@
+package bindings
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// FLIPCoreRedemption is the record FLIPCore.redemptions returns. The
+// generated getter returns it as an anonymous struct; naming it lets reads
+// batched through Multicall3 decode into the same type.
+type FLIPCoreRedemption = struct {
+	User               common.Address
+	Asset              common.Address
+	Amount             *big.Int
+	RequestedAt        *big.Int
+	PriceLocked        *big.Int
+	HedgeId            *big.Int
+	Status             uint8
+	FdcRequestId       *big.Int
+	ProvisionalSettled bool
+	XrplAddress        string
+}
+
+// Regenerating with a changed redemptions getter breaks the build here
+var _ func(*bind.CallOpts, *big.Int) (FLIPCoreRedemption, error) = (*FLIPCoreCaller)(nil).Redemptions
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog/log"
+)
+
+// Circuit breaker defaults used when the config leaves them unset
+const (
+	defaultBreakerInterval   = 30 * time.Second
+	defaultBreakerStateFile  = "breaker_state.json"
+	defaultBreakerFDCWindow  = time.Hour
+	defaultBreakerMinSamples = 5
+)
+
+// Circuit breakers. Any tripped breaker halts the XRPL payout stage.
+const (
+	BreakerContractPaused = "contract_paused"    // FLIPCore, EscrowVault or InsurancePool paused
+	BreakerXRPLBalance    = "xrpl_balance"       // Wallet below breakers.min_xrp_balance
+	BreakerFDCFailures    = "fdc_failures"       // FDC proof failure rate over the window
+	BreakerProofMismatch  = "fdc_proof_mismatch" // DA providers returned different proofs
+	BreakerChainLag       = "chain_lag"          // Event cursors behind head
+)
+
+var breakerNames = []string{
+	BreakerContractPaused,
+	BreakerXRPLBalance,
+	BreakerFDCFailures,
+	BreakerProofMismatch,
+	BreakerChainLag,
+}
+
+// Contracts announce a pause with Paused(address) (Pausable) or PoolPaused()
+// (InsurancePool)
+var breakerPauseTopics = []common.Hash{
+	crypto.Keccak256Hash([]byte("Paused(address)")),
+	crypto.Keccak256Hash([]byte("PoolPaused()")),
+}
+
+var breakerPausedWatched = []string{
+	ContractFLIPCore,
+	ContractEscrowVault,
+	ContractInsurancePool,
+}
+
+const breakerPausedABI = `[
+	{"inputs":[],"name":"paused","outputs":[{"name":"","type":"bool"}],"stateMutability":"view","type":"function"}
+]`
+
+// BreakerTrip records why a breaker tripped. It stays until an operator
+// resets it, across restarts.
+type BreakerTrip struct {
+	Breaker   string    `json:"breaker"`
+	Reason    string    `json:"reason"`
+	TrippedAt time.Time `json:"tripped_at"`
+}
+
+// fdcOutcome is one FDC proof attempt seen by the FDC breakers
+type fdcOutcome struct {
+	at       time.Time
+	failed   bool
+	mismatch bool
+}
+
+// CircuitBreakers halt XRPL payouts when something is wrong: a FLIP contract
+// was paused, the XRPL wallet ran low, FDC keeps failing or DA providers
+// disagree, or the agent's view of the chain fell behind. Tripping is
+// automatic; only an operator resets a breaker, through the admin API.
+type CircuitBreakers struct {
+	client    *ethclient.Client
+	flip      *FlipContracts
+	monitor   *EventMonitor
+	xrpl      *XRPLClient
+	notifier  *Notifier
+	pausedABI abi.ABI
+	path      string
+	interval  time.Duration
+	lastBlock uint64 // Last block scanned for pause events
+
+	minXRPBalance      uint64
+	fdcWindow          time.Duration
+	fdcMinSamples      int
+	maxFDCFailureRate  int
+	proofMismatchLimit int
+	maxBlockLag        uint64
+
+	mu    sync.Mutex
+	trips map[string]BreakerTrip
+	fdc   []fdcOutcome
+}
+
+// NewCircuitBreakers creates the breakers and loads the ones still tripped
+// from breakers.state_file
+func NewCircuitBreakers(client *ethclient.Client, flip *FlipContracts, monitor *EventMonitor, xrpl *XRPLClient, notifier *Notifier, config *Config) (*CircuitBreakers, error) {
+	pausedABI, err := abi.JSON(strings.NewReader(breakerPausedABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse paused ABI: %w", err)
+	}
+
+	interval := time.Duration(config.Breakers.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultBreakerInterval
+	}
+	path := config.Breakers.StateFile
+	if path == "" {
+		path = defaultBreakerStateFile
+	}
+	window := time.Duration(config.Breakers.FDCWindow) * time.Second
+	if window <= 0 {
+		window = defaultBreakerFDCWindow
+	}
+	minSamples := config.Breakers.FDCMinSamples
+	if minSamples <= 0 {
+		minSamples = defaultBreakerMinSamples
+	}
+
+	b := &CircuitBreakers{
+		client:             client,
+		flip:               flip,
+		monitor:            monitor,
+		xrpl:               xrpl,
+		notifier:           notifier,
+		pausedABI:          pausedABI,
+		path:               path,
+		interval:           interval,
+		minXRPBalance:      config.Breakers.MinXRPBalance,
+		fdcWindow:          window,
+		fdcMinSamples:      minSamples,
+		maxFDCFailureRate:  config.Breakers.MaxFDCFailureRate,
+		proofMismatchLimit: config.Breakers.ProofMismatchLimit,
+		maxBlockLag:        uint64(config.Breakers.MaxBlockLag),
+		trips:              make(map[string]BreakerTrip),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read breaker state: %w", err)
+	}
+	if err == nil {
+		var trips []BreakerTrip
+		if err := json.Unmarshal(data, &trips); err != nil {
+			return nil, fmt.Errorf("failed to decode breaker state: %w", err)
+		}
+		for _, trip := range trips {
+			b.trips[trip.Breaker] = trip
+		}
+	}
+
+	for _, name := range breakerNames {
+		breakerTripped.WithLabelValues(name).Set(0)
+	}
+	for name, trip := range b.trips {
+		breakerTripped.WithLabelValues(name).Set(1)
+		log.Error().
+			Str("breaker", name).
+			Str("reason", trip.Reason).
+			Time("tripped_at", trip.TrippedAt).
+			Msg("Circuit breaker still tripped from a previous run, XRPL payouts halted")
+	}
+	return b, nil
+}
+
+// Run checks every breaker each interval until ctx is cancelled. Call Check
+// once first; Run does not check before its first tick.
+func (b *CircuitBreakers) Run(ctx context.Context) {
+	log.Info().Dur("interval", b.interval).Msg("Circuit breakers started")
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.Check(ctx)
+		}
+	}
+}
+
+// Check evaluates every breaker that is not already tripped. Pause events are
+// scanned from the head block seen by the first call.
+func (b *CircuitBreakers) Check(ctx context.Context) {
+	if err := b.scanPauseEvents(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to scan for pause events")
+	}
+
+	for _, name := range []string{BreakerContractPaused, BreakerXRPLBalance, BreakerChainLag} {
+		if b.isTripped(name) {
+			continue
+		}
+		reason, err := b.condition(ctx, name)
+		if err != nil {
+			log.Warn().Err(err).Str("breaker", name).Msg("Circuit breaker check failed")
+			continue
+		}
+		if reason != "" {
+			b.trip(name, reason)
+		}
+	}
+}
+
+// condition returns why a contract, balance or lag breaker should be
+// tripped, or "". The FDC breakers are evaluated as outcomes arrive.
+func (b *CircuitBreakers) condition(ctx context.Context, name string) (string, error) {
+	switch name {
+	case BreakerContractPaused:
+		for _, contract := range breakerPausedWatched {
+			addr := b.flip.Address(contract)
+			if addr == (common.Address{}) {
+				continue
+			}
+			var result []interface{}
+			bound := bind.NewBoundContract(addr, b.pausedABI, b.client, b.client, b.client)
+			if err := bound.Call(&bind.CallOpts{Context: ctx}, &result, "paused"); err != nil {
+				// Contracts without paused() are only watched for events
+				log.Debug().Err(err).Str("contract", contract).Msg("Failed to read paused()")
+				continue
+			}
+			if result[0].(bool) {
+				return fmt.Sprintf("%s at %s is paused", contract, addr.Hex()), nil
+			}
+		}
+	case BreakerXRPLBalance:
+		if b.minXRPBalance == 0 {
+			return "", nil
+		}
+		balance, err := b.xrpl.GetBalance(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get XRPL balance: %w", err)
+		}
+		drops, ok := new(big.Int).SetString(balance, 10)
+		if !ok {
+			return "", fmt.Errorf("invalid XRPL balance %q", balance)
+		}
+		if drops.Cmp(new(big.Int).SetUint64(b.minXRPBalance)) < 0 {
+			return fmt.Sprintf("XRPL balance %s drops is below the %d drop floor", balance, b.minXRPBalance), nil
+		}
+	case BreakerChainLag:
+		if b.maxBlockLag == 0 {
+			return "", nil
+		}
+		if cursor, lag := b.monitor.MaxBlockLag(); lag > b.maxBlockLag {
+			return fmt.Sprintf("%s cursor is %d blocks behind head, limit %d", cursor, lag, b.maxBlockLag), nil
+		}
+	}
+	return "", nil
+}
+
+// scanPauseEvents trips the contract_paused breaker on any pause event since
+// the last scan
+func (b *CircuitBreakers) scanPauseEvents(ctx context.Context) error {
+	const maxBlockRange uint64 = 29
+
+	head, err := b.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+	if b.lastBlock == 0 || head <= b.lastBlock {
+		b.lastBlock = max(b.lastBlock, head)
+		return nil
+	}
+
+	names := make(map[common.Address]string)
+	var addresses []common.Address
+	for _, contract := range breakerPausedWatched {
+		if addr := b.flip.Address(contract); addr != (common.Address{}) {
+			names[addr] = contract
+			addresses = append(addresses, addr)
+		}
+	}
+
+	for start := b.lastBlock + 1; start <= head; start += maxBlockRange + 1 {
+		end := min(start+maxBlockRange, head)
+		logs, err := b.client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: addresses,
+			Topics:    [][]common.Hash{breakerPauseTopics},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to filter logs %d-%d: %w", start, end, err)
+		}
+		for _, vLog := range logs {
+			b.trip(BreakerContractPaused, fmt.Sprintf("%s emitted a pause event in tx %s (block %d)",
+				names[vLog.Address], vLog.TxHash.Hex(), vLog.BlockNumber))
+		}
+		b.lastBlock = end
+	}
+	return nil
+}
+
+// ObserveFDC records the outcome of an FDC proof attempt. Attempts cut short
+// by shutdown are ignored.
+func (b *CircuitBreakers) ObserveFDC(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.fdc = append(b.fdc, fdcOutcome{at: now, failed: err != nil, mismatch: errors.Is(err, ErrProofMismatch)})
+	cutoff := now.Add(-b.fdcWindow)
+	for len(b.fdc) > 0 && b.fdc[0].at.Before(cutoff) {
+		b.fdc = b.fdc[1:]
+	}
+	reasons := make(map[string]string)
+	for _, name := range []string{BreakerFDCFailures, BreakerProofMismatch} {
+		if reason := b.fdcCondition(name); reason != "" {
+			reasons[name] = reason
+		}
+	}
+	b.mu.Unlock()
+
+	for name, reason := range reasons {
+		if !b.isTripped(name) {
+			b.trip(name, reason)
+		}
+	}
+}
+
+// fdcCondition evaluates an FDC breaker over the window. Callers hold b.mu.
+func (b *CircuitBreakers) fdcCondition(name string) string {
+	var failed, mismatches int
+	for _, o := range b.fdc {
+		if o.failed {
+			failed++
+		}
+		if o.mismatch {
+			mismatches++
+		}
+	}
+
+	switch name {
+	case BreakerFDCFailures:
+		if b.maxFDCFailureRate == 0 || len(b.fdc) < b.fdcMinSamples {
+			return ""
+		}
+		if rate := failed * 100 / len(b.fdc); rate > b.maxFDCFailureRate {
+			return fmt.Sprintf("%d of %d FDC proofs failed in the last %s (%d%%, limit %d%%)", failed, len(b.fdc), b.fdcWindow, rate, b.maxFDCFailureRate)
+		}
+	case BreakerProofMismatch:
+		if b.proofMismatchLimit > 0 && mismatches >= b.proofMismatchLimit {
+			return fmt.Sprintf("%d DA proof mismatches in the last %s, limit %d", mismatches, b.fdcWindow, b.proofMismatchLimit)
+		}
+	}
+	return ""
+}
+
+func (b *CircuitBreakers) trip(name, reason string) {
+	b.mu.Lock()
+	if _, ok := b.trips[name]; ok {
+		b.mu.Unlock()
+		return
+	}
+	b.trips[name] = BreakerTrip{Breaker: name, Reason: reason, TrippedAt: time.Now().UTC()}
+	b.save()
+	b.mu.Unlock()
+
+	breakerTripped.WithLabelValues(name).Set(1)
+	breakerTrips.WithLabelValues(name).Inc()
+	log.Error().
+		Str("breaker", name).
+		Str("reason", reason).
+		Msg("Circuit breaker tripped, XRPL payouts halted until an operator resets it")
+	b.notifier.Notify(EventBreakerTripped, name, reason+"; XRPL payouts halted until an operator resets it", nil)
+}
+
+// Reset clears a tripped breaker. It refuses while the condition behind the
+// breaker still holds; resetting an FDC breaker forgets the outcomes in its
+// window.
+func (b *CircuitBreakers) Reset(ctx context.Context, name, operator string) error {
+	if !b.isTripped(name) {
+		if !slices.Contains(breakerNames, name) {
+			return fmt.Errorf("unknown circuit breaker %q", name)
+		}
+		return fmt.Errorf("circuit breaker %s is not tripped", name)
+	}
+
+	switch name {
+	case BreakerFDCFailures, BreakerProofMismatch:
+		b.mu.Lock()
+		b.fdc = nil
+		b.mu.Unlock()
+	default:
+		reason, err := b.condition(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to re-check %s: %w", name, err)
+		}
+		if reason != "" {
+			return fmt.Errorf("%s still holds: %s", name, reason)
+		}
+	}
+
+	b.mu.Lock()
+	trip := b.trips[name]
+	delete(b.trips, name)
+	b.save()
+	b.mu.Unlock()
+
+	breakerTripped.WithLabelValues(name).Set(0)
+	log.Warn().
+		Str("breaker", name).
+		Str("reason", trip.Reason).
+		Str("operator", operator).
+		Msg("Circuit breaker reset")
+	return nil
+}
+
+// Tripped returns the tripped breakers, ordered by name
+func (b *CircuitBreakers) Tripped() []BreakerTrip {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	trips := make([]BreakerTrip, 0, len(b.trips))
+	for _, trip := range b.trips {
+		trips = append(trips, trip)
+	}
+	sort.Slice(trips, func(i, j int) bool { return trips[i].Breaker < trips[j].Breaker })
+	return trips
+}
+
+// Halted returns a description of the tripped breakers, or "" when payouts
+// may run
+func (b *CircuitBreakers) Halted() string {
+	var names []string
+	for _, trip := range b.Tripped() {
+		names = append(names, trip.Breaker)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (b *CircuitBreakers) isTripped(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.trips[name]
+	return ok
+}
+
+// save writes the tripped breakers atomically. Callers hold b.mu.
+func (b *CircuitBreakers) save() {
+	trips := make([]BreakerTrip, 0, len(b.trips))
+	for _, trip := range b.trips {
+		trips = append(trips, trip)
+	}
+	data, err := json.MarshalIndent(trips, "", "  ")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode breaker state")
+		return
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Error().Err(err).Msg("Failed to write breaker state")
+		return
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		log.Error().Err(err).Msg("Failed to write breaker state")
+	}
+}
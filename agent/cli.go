@@ -0,0 +1,1263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/flip-protocol/agent/bindings"
+	"github.com/rs/zerolog"
+)
+
+// cliTimeout bounds the reads done by the read-only commands
+const cliTimeout = 2 * time.Minute
+
+// command is an agent subcommand
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"run", "[-keeper] [-dry-run]", "Run the agent daemon (default)", runAgent},
+	{"status", "[-json]", "Summarize balances, roles and pending work", runStatus},
+	{"inspect", "[-json] <redemptionId>", "Show chain, XRPL and FDC state for a redemption", runInspect},
+	{"retry-fdc", "[-operator name] <redemptionId>", "Re-run the FDC proof path for a paid redemption", runRetryFDC},
+	{"replay", "-from-block N [-to-block M] [-dry-run] [-operator name]", "Reprocess FLIPCore events in a block range", runReplay},
+	{"doctor", "", "Run preflight diagnostics", runDoctor},
+	{"shadow-report", "[-journal path] [-json] [-all]", "Compare a dry run's journal with what production did", runShadowReport},
+	{"approvals", "[-json] [-all]", "List payouts waiting in the approval queue", runApprovals},
+	{"approve", "[-operator name] [-reason text] <redemptionId>", "Approve a payout over a risk limit", runApprove},
+	{"reject", "[-operator name] -reason text <redemptionId>", "Reject a payout over a risk limit", runReject},
+	{"firelight-approve", "-operator name [-reason text] <redemptionId>", "Approve a Firelight trigger above the approval threshold", runFirelightApprove},
+	{"audit-verify", "[-journal path] [-json]", "Check the audit journal's hash chain for gaps or tampering", runAuditVerify},
+	{"audit-export", "[-journal path] [-workflow kind -id N] [-from time] [-to time] [-o file]", "Export audit journal entries for auditors or a dispute", runAuditExport},
+	{"reconcile", "[-ledgers N] [-blocks N] [-json] [-o file]", "Compare XRPL payouts with the payments recorded on FLIPCore", runReconcile},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: agent [-config path] <command> [args]\n\nCommands:\n")
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	w.Flush()
+	fmt.Fprintf(os.Stderr, "\nGlobal flags:\n")
+	flag.PrintDefaults()
+}
+
+// openAgent loads the config and builds the agent for a one-shot command.
+// Info logs are suppressed unless verbose so command output stays readable.
+func openAgent(verbose bool) (*Agent, error) {
+	config, err := commandConfig(verbose)
+	if err != nil {
+		return nil, err
+	}
+	return NewAgent(config)
+}
+
+// openExclusiveAgent builds the agent for a command that runs workflow
+// stages in-process. It holds the state directory lock, so it fails while a
+// daemon owns the same state files. Call the returned function when done.
+func openExclusiveAgent(verbose bool) (*Agent, func(), error) {
+	config, err := commandConfig(verbose)
+	if err != nil {
+		return nil, nil, err
+	}
+	unlock, err := lockStateDir(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w; is the daemon running with its admin API disabled?", err)
+	}
+	a, err := NewAgent(config)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return a, unlock, nil
+}
+
+// commandConfig loads the config for a one-shot command and applies its log
+// level
+func commandConfig(verbose bool) (*Config, error) {
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if !verbose {
+		config.Agent.LogLevel = "warn"
+	}
+	// NewAgent logs while resolving contracts, before it applies the level
+	if level, err := zerolog.ParseLevel(config.Agent.LogLevel); err == nil {
+		zerolog.SetGlobalLevel(level)
+	}
+	return config, nil
+}
+
+// retryOnDaemon asks the running daemon to run a stage, so it goes through
+// the daemon's holds, limits and state. stage "" runs whatever is next.
+func retryOnDaemon(kind string, id uint64, stage, reason, operator string, dryRun bool) (retryResult, error) {
+	var result retryResult
+	path := fmt.Sprintf("/v1/workflows/%s/%d/retry", kind, id)
+	err := callAdmin(http.MethodPost, path, operator, adminAction{Stage: stage, Reason: reason, DryRun: dryRun}, &result)
+	return result, err
+}
+
+// commandContext is cancelled on SIGINT or SIGTERM
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+// operatorFlag registers -operator, defaulting to the login user
+func operatorFlag(fs *flag.FlagSet) *string {
+	return fs.String("operator", os.Getenv("USER"), "Operator name recorded in the workflow timeline")
+}
+
+// parseID parses the single <id> argument of a command
+func parseID(fs *flag.FlagSet) (uint64, error) {
+	if fs.NArg() != 1 {
+		return 0, fmt.Errorf("expected exactly one redemption ID")
+	}
+	id, err := strconv.ParseUint(fs.Arg(0), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid redemption ID %q", fs.Arg(0))
+	}
+	return id, nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// formatDrops renders drops as XRP
+func formatDrops(drops *big.Int) string {
+	if drops == nil {
+		return "-"
+	}
+	xrp := new(big.Float).Quo(new(big.Float).SetInt(drops), big.NewFloat(1e6))
+	return xrp.Text('f', 6) + " XRP"
+}
+
+// formatWei renders wei as FLR
+func formatWei(wei *big.Int) string {
+	if wei == nil {
+		return "-"
+	}
+	flr := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(params.Ether))
+	return flr.Text('f', 4) + " FLR"
+}
+
+// agentAddress is the Flare address of the configured key
+func (a *Agent) agentAddress() (common.Address, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(a.config.Flare.PrivateKey, "0x"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return crypto.PubkeyToAddress(privateKey.PublicKey), nil
+}
+
+// AgentStatus is the output of the status command
+type AgentStatus struct {
+	FlareAddress    string          `json:"flare_address"`
+	FlareBalance    string          `json:"flare_balance_wei"`
+	XRPLAddress     string          `json:"xrpl_address"`
+	XRPLBalance     string          `json:"xrpl_balance_drops"`
+	MinXRPLBalance  uint64          `json:"min_xrpl_balance_drops"`
+	RoleVerified    bool            `json:"role_verified"`
+	Paused          map[string]bool `json:"paused"`
+	Breakers        []BreakerTrip   `json:"tripped_breakers"`
+	AwaitingPayout  int             `json:"awaiting_approval"`
+	Held            int             `json:"held"`
+	Deferred        int             `json:"deferred"`
+	UnpaidEscrows   []uint64        `json:"unpaid_escrows"`
+	AwaitingFDC     []uint64        `json:"paid_awaiting_fdc"`
+	OpenMintings    []uint64        `json:"open_mintings"`
+	RedemptionsFrom uint64          `json:"redemptions_scanned_from"`
+	MintingsFrom    uint64          `json:"mintings_scanned_from"`
+}
+
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print JSON")
+	verbose := fs.Bool("v", false, "Show agent logs")
+	fs.Parse(args)
+
+	a, err := openAgent(*verbose)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+
+	status, err := a.status(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(status)
+	}
+
+	flareBalance, _ := new(big.Int).SetString(status.FlareBalance, 10)
+	xrplBalance, _ := new(big.Int).SetString(status.XRPLBalance, 10)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Flare address\t%s\n", status.FlareAddress)
+	fmt.Fprintf(w, "Flare balance\t%s\n", formatWei(flareBalance))
+	fmt.Fprintf(w, "XRPL address\t%s\n", status.XRPLAddress)
+	fmt.Fprintf(w, "XRPL balance\t%s (floor %s)\n", formatDrops(xrplBalance), formatDrops(new(big.Int).SetUint64(status.MinXRPLBalance)))
+	fmt.Fprintf(w, "Owner or operator\t%t\n", status.RoleVerified)
+	fmt.Fprintf(w, "Paused\tredemption=%t minting=%t\n", status.Paused[WorkflowRedemption], status.Paused[WorkflowMinting])
+	if len(status.Breakers) == 0 {
+		fmt.Fprintf(w, "Circuit breakers\tnone tripped\n")
+	}
+	for _, trip := range status.Breakers {
+		fmt.Fprintf(w, "Circuit breaker\t%s since %s: %s\n", trip.Breaker, trip.TrippedAt.Format(time.RFC3339), trip.Reason)
+	}
+	fmt.Fprintf(w, "Held / deferred IDs\t%d / %d\n", status.Held, status.Deferred)
+	fmt.Fprintf(w, "Awaiting approval\t%d\n", status.AwaitingPayout)
+	fmt.Fprintf(w, "Unpaid escrows\t%s\n", formatIDs(status.UnpaidEscrows))
+	fmt.Fprintf(w, "Paid, awaiting FDC\t%s\n", formatIDs(status.AwaitingFDC))
+	fmt.Fprintf(w, "Open mintings\t%s\n", formatIDs(status.OpenMintings))
+	fmt.Fprintf(w, "Scanned from\tredemption %d, minting %d\n", status.RedemptionsFrom, status.MintingsFrom)
+	return w.Flush()
+}
+
+func formatIDs(ids []uint64) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(id, 10)
+	}
+	return fmt.Sprintf("%d (%s)", len(ids), strings.Join(parts, ", "))
+}
+
+// status gathers balances, roles and the work recovery would pick up
+func (a *Agent) status(ctx context.Context) (*AgentStatus, error) {
+	held := 0
+	for _, hold := range []string{HoldSkipped, HoldQuarantined, HoldScreened} {
+		held += len(a.workflows.List("", "", hold))
+	}
+	status := &AgentStatus{
+		XRPLAddress:    a.paymentProc.xrplClient.wallet.Address,
+		MinXRPLBalance: a.settings.MinXRPBalance(),
+		Paused: map[string]bool{
+			WorkflowRedemption: a.workflows.Paused(WorkflowRedemption),
+			WorkflowMinting:    a.workflows.Paused(WorkflowMinting),
+		},
+		Breakers:       a.breakers.Tripped(),
+		AwaitingPayout: len(a.limits.Approvals(ApprovalPending)),
+		Held:           held,
+		Deferred:       len(a.workflows.List("", StateDeferred, "")),
+	}
+
+	address, err := a.agentAddress()
+	if err != nil {
+		return nil, err
+	}
+	status.FlareAddress = address.Hex()
+	balance, err := a.flareClient.BalanceAt(ctx, address, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Flare balance: %w", err)
+	}
+	status.FlareBalance = balance.String()
+
+	if status.XRPLBalance, err = a.paymentProc.xrplClient.GetBalance(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get XRPL balance: %w", err)
+	}
+
+	if err := a.verifyAccessControl(ctx); err != nil {
+		return nil, err
+	}
+	status.RoleVerified = a.roleVerified.Load()
+
+	// Scan the same ranges startup recovery would
+	state, err := a.loadRecoveryState()
+	if err != nil {
+		return nil, err
+	}
+	flipCoreABI, err := bindings.ABI(ContractFLIPCore)
+	if err != nil {
+		return nil, err
+	}
+	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
+	counts, err := a.multicall.Call(ctx, []BatchCall{
+		{Target: flipCoreAddr, ABI: flipCoreABI, Method: "nextRedemptionId"},
+		{Target: flipCoreAddr, ABI: flipCoreABI, Method: "nextMintingId"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	nextRedemption, nextMinting, err := decodeNextIDs(counts)
+	if err != nil {
+		return nil, err
+	}
+	if state.RedemptionLowWater > nextRedemption || state.MintingLowWater > nextMinting {
+		state = &RecoveryState{}
+	}
+	status.RedemptionsFrom = state.RedemptionLowWater
+	status.MintingsFrom = state.MintingLowWater
+
+	redemptions, _, err := a.scanRedemptions(ctx, flipCoreABI, flipCoreAddr, state.RedemptionLowWater, nextRedemption)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range redemptions {
+		if r.xrplTxHash == "" {
+			status.UnpaidEscrows = append(status.UnpaidEscrows, r.id.Uint64())
+		} else {
+			status.AwaitingFDC = append(status.AwaitingFDC, r.id.Uint64())
+		}
+	}
+
+	mintings, _, err := a.scanMintings(ctx, flipCoreABI, flipCoreAddr, state.MintingLowWater, nextMinting)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mintings {
+		status.OpenMintings = append(status.OpenMintings, m.req.MintingID.Uint64())
+	}
+
+	return status, nil
+}
+
+// RedemptionInspection is the output of the inspect command
+type RedemptionInspection struct {
+	ID          uint64 `json:"id"`
+	Status      string `json:"status"`
+	User        string `json:"user"`
+	Asset       string `json:"asset"`
+	Amount      string `json:"amount"`
+	XRPLAddress string `json:"xrpl_address"`
+	RequestedAt int64  `json:"requested_at"`
+	NextStage   string `json:"next_stage,omitempty"`
+
+	EscrowStatus     string `json:"escrow_status"`
+	EscrowLP         string `json:"escrow_lp,omitempty"`
+	EscrowCanTimeout bool   `json:"escrow_can_timeout"`
+
+	XRPLTxHash      string `json:"xrpl_tx_hash,omitempty"`
+	XRPLValidated   bool   `json:"xrpl_validated,omitempty"`
+	XRPLResult      string `json:"xrpl_result,omitempty"`
+	XRPLDestination string `json:"xrpl_destination,omitempty"`
+	XRPLDelivered   string `json:"xrpl_delivered_drops,omitempty"`
+	XRPLError       string `json:"xrpl_error,omitempty"`
+
+	FDCRoundID        uint64 `json:"fdc_round_id,omitempty"`
+	FDCRoundFinalized bool   `json:"fdc_round_finalized,omitempty"`
+	FDCError          string `json:"fdc_error,omitempty"`
+
+	Workflow *WorkflowRecord `json:"workflow,omitempty"`
+}
+
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print JSON")
+	verbose := fs.Bool("v", false, "Show agent logs")
+	fs.Parse(args)
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	a, err := openAgent(*verbose)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+
+	inspection, err := a.inspectRedemption(ctx, id)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(inspection)
+	}
+
+	amount, _ := new(big.Int).SetString(inspection.Amount, 10)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Redemption\t%d\n", inspection.ID)
+	fmt.Fprintf(w, "Status\t%s\n", inspection.Status)
+	fmt.Fprintf(w, "User\t%s\n", inspection.User)
+	fmt.Fprintf(w, "Amount\t%s (%s)\n", inspection.Amount, formatDrops(amount))
+	fmt.Fprintf(w, "XRPL address\t%s\n", inspection.XRPLAddress)
+	fmt.Fprintf(w, "Requested\t%s\n", time.Unix(inspection.RequestedAt, 0).UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "Escrow\t%s, LP %s, can time out %t\n", inspection.EscrowStatus, inspection.EscrowLP, inspection.EscrowCanTimeout)
+	switch {
+	case inspection.XRPLTxHash == "":
+		fmt.Fprintf(w, "XRPL payment\tnot recorded\n")
+	case inspection.XRPLError != "":
+		fmt.Fprintf(w, "XRPL payment\t%s (%s)\n", inspection.XRPLTxHash, inspection.XRPLError)
+	default:
+		fmt.Fprintf(w, "XRPL payment\t%s %s validated=%t to %s delivered %s drops\n",
+			inspection.XRPLTxHash, inspection.XRPLResult, inspection.XRPLValidated, inspection.XRPLDestination, inspection.XRPLDelivered)
+	}
+	switch {
+	case inspection.FDCRoundID == 0:
+		fmt.Fprintf(w, "FDC round\tnone\n")
+	case inspection.FDCError != "":
+		fmt.Fprintf(w, "FDC round\t%d (%s)\n", inspection.FDCRoundID, inspection.FDCError)
+	default:
+		fmt.Fprintf(w, "FDC round\t%d finalized=%t\n", inspection.FDCRoundID, inspection.FDCRoundFinalized)
+	}
+	next := inspection.NextStage
+	if next == "" {
+		next = "none"
+	}
+	fmt.Fprintf(w, "Next agent stage\t%s\n", next)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if inspection.Workflow != nil {
+		fmt.Printf("\nAgent timeline (state %s", inspection.Workflow.State)
+		if inspection.Workflow.Hold != "" {
+			fmt.Printf(", %s: %s", inspection.Workflow.Hold, inspection.Workflow.HoldReason)
+		}
+		fmt.Println("):")
+		for _, entry := range inspection.Workflow.Timeline {
+			line := fmt.Sprintf("  %s  %-20s %s", entry.Time.Format(time.RFC3339), entry.Event, entry.Detail)
+			if entry.Operator != "" {
+				line += " [" + entry.Operator + "]"
+			}
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+// inspectRedemption reads a redemption's state on Flare, XRPL and FDC
+func (a *Agent) inspectRedemption(ctx context.Context, id uint64) (*RedemptionInspection, error) {
+	r, timedOut, err := a.readRedemption(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &RedemptionInspection{
+		ID:               id,
+		Status:           statusName(redemptionStatusNames, r.status),
+		User:             r.user.Hex(),
+		Asset:            r.asset.Hex(),
+		Amount:           r.amount.String(),
+		XRPLAddress:      r.xrplAddress,
+		RequestedAt:      r.requestedAt.Int64(),
+		EscrowCanTimeout: timedOut,
+		XRPLTxHash:       r.xrplTxHash,
+	}
+	if inspection.NextStage, err = a.NextStage(ctx, WorkflowRedemption, id); err != nil {
+		return nil, err
+	}
+
+	escrow, err := a.firelight.vault.Escrows(&bind.CallOpts{Context: ctx}, r.id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get escrow: %w", err)
+	}
+	inspection.EscrowStatus = statusName(escrowStatusNames, escrow.Status)
+	if escrow.Lp != (common.Address{}) {
+		inspection.EscrowLP = escrow.Lp.Hex()
+	}
+	inspection.FDCRoundID = escrow.FdcRoundId.Uint64()
+
+	if r.xrplTxHash != "" {
+		tx, err := a.paymentProc.xrplClient.GetTransaction(ctx, r.xrplTxHash)
+		if err != nil {
+			inspection.XRPLError = err.Error()
+		} else {
+			inspection.XRPLValidated = tx.Validated
+			inspection.XRPLResult = tx.Result
+			inspection.XRPLDestination = tx.Destination
+			if tx.DeliveredAmount != nil {
+				inspection.XRPLDelivered = tx.DeliveredAmount.String()
+			}
+		}
+	}
+
+	if inspection.FDCRoundID != 0 {
+		finalized, err := a.fdcSubmitter.isRoundFinalized(ctx, inspection.FDCRoundID)
+		if err != nil {
+			inspection.FDCError = err.Error()
+		}
+		inspection.FDCRoundFinalized = finalized
+	}
+
+	if record, ok := a.workflows.Get(WorkflowRedemption, id); ok {
+		inspection.Workflow = &record
+	}
+	return inspection, nil
+}
+
+func runRetryFDC(args []string) error {
+	fs := flag.NewFlagSet("retry-fdc", flag.ExitOnError)
+	operator := operatorFlag(fs)
+	verbose := fs.Bool("v", true, "Show agent logs")
+	fs.Parse(args)
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+	if *operator == "" {
+		return errors.New("-operator is required")
+	}
+
+	// The daemon owns the workflow state, so it runs the stage when it is up
+	_, err = retryOnDaemon(WorkflowRedemption, id, RetryFDC, "retry-fdc (cli)", *operator, false)
+	if err == nil {
+		fmt.Printf("Redemption %d: %s stage queued on the running agent\n", id, RetryFDC)
+		return nil
+	}
+	if !errors.Is(err, errAdminUnreachable) {
+		return err
+	}
+	fmt.Printf("%v, running in-process\n", err)
+
+	a, unlock, err := openExclusiveAgent(*verbose)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	op, err := a.PrepareRetry(ctx, WorkflowRedemption, id, RetryFDC)
+	if err != nil {
+		return err
+	}
+	a.workflows.Note(WorkflowRedemption, id, "retry_requested", RetryFDC+" (cli)", *operator)
+	a.audit.RecordOverride(*operator, WorkflowRedemption, &id, map[string]string{"command": "retry-fdc", "stage": RetryFDC})
+	op(ctx)
+
+	record, _ := a.workflows.Get(WorkflowRedemption, id)
+	fmt.Printf("Redemption %d: %s\n", id, record.State)
+	if record.State != StateFinalized {
+		return fmt.Errorf("redemption %d was not finalized, see the log above", id)
+	}
+	return nil
+}
+
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fromBlock := fs.Uint64("from-block", 0, "First block to replay (required)")
+	toBlock := fs.Uint64("to-block", 0, "Last block to replay, inclusive (default: head)")
+	dryRun := fs.Bool("dry-run", false, "Only print what would be done")
+	operator := operatorFlag(fs)
+	verbose := fs.Bool("v", true, "Show agent logs")
+	fs.Parse(args)
+	if *fromBlock == 0 {
+		return errors.New("-from-block is required")
+	}
+
+	config, err := commandConfig(*verbose)
+	if err != nil {
+		return err
+	}
+	monitor, err := NewEventMonitor(config, NewLiveSettings(config))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	if *toBlock == 0 {
+		if *toBlock, err = monitor.client.BlockNumber(ctx); err != nil {
+			return fmt.Errorf("failed to get block number: %w", err)
+		}
+	}
+	if *toBlock < *fromBlock {
+		return fmt.Errorf("-to-block %d is before -from-block %d", *toBlock, *fromBlock)
+	}
+
+	events, err := monitor.EventsInRange(ctx, *fromBlock, *toBlock)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Found %d events in blocks %d-%d\n", len(events), *fromBlock, *toBlock)
+
+	// Each ID is driven once, from its current on-chain state, so replaying
+	// an already settled range does nothing
+	seen := make(map[string]bool)
+	var unique []HistoricalEvent
+	for _, e := range events {
+		key := workflowKey(e.Workflow, e.ID)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, e)
+		}
+	}
+
+	// The daemon owns the workflow state, so it runs the stages when it is up
+	var paused map[string]bool
+	err = callAdmin(http.MethodGet, "/v1/pause", "", nil, &paused)
+	if err == nil {
+		return replayOnDaemon(ctx, unique, *operator, *dryRun)
+	}
+	if !errors.Is(err, errAdminUnreachable) {
+		return err
+	}
+	fmt.Printf("%v, replaying in-process\n", err)
+
+	a, unlock, err := openExclusiveAgent(*verbose)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, e := range unique {
+		stage, err := a.NextStage(ctx, e.Workflow, e.ID)
+		if err != nil {
+			fmt.Printf("%s %d (block %d): %v\n", e.Workflow, e.ID, e.Block, err)
+			continue
+		}
+		if stage == "" {
+			fmt.Printf("%s %d (block %d): nothing to do\n", e.Workflow, e.ID, e.Block)
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("%s %d (block %d): would run %s\n", e.Workflow, e.ID, e.Block, stage)
+			continue
+		}
+
+		op, err := a.PrepareRetry(ctx, e.Workflow, e.ID, stage)
+		if err != nil {
+			fmt.Printf("%s %d (block %d): %v\n", e.Workflow, e.ID, e.Block, err)
+			continue
+		}
+		a.workflows.Note(e.Workflow, e.ID, "replayed", fmt.Sprintf("%s from block %d", stage, e.Block), *operator)
+		a.audit.RecordOverride(*operator, e.Workflow, &e.ID, map[string]interface{}{"command": "replay", "stage": stage, "block": e.Block})
+		op(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		record, _ := a.workflows.Get(e.Workflow, e.ID)
+		fmt.Printf("%s %d (block %d): ran %s, now %s\n", e.Workflow, e.ID, e.Block, stage, record.State)
+	}
+	return nil
+}
+
+// replayOnDaemon queues each ID's next stage on the running daemon
+func replayOnDaemon(ctx context.Context, events []HistoricalEvent, operator string, dryRun bool) error {
+	if operator == "" {
+		return errors.New("-operator is required")
+	}
+	for _, e := range events {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		result, err := retryOnDaemon(e.Workflow, e.ID, "", fmt.Sprintf("replay from block %d", e.Block), operator, dryRun)
+		switch {
+		case err != nil:
+			fmt.Printf("%s %d (block %d): %v\n", e.Workflow, e.ID, e.Block, err)
+		case result.Stage == "":
+			fmt.Printf("%s %d (block %d): nothing to do\n", e.Workflow, e.ID, e.Block)
+		case dryRun:
+			fmt.Printf("%s %d (block %d): would run %s\n", e.Workflow, e.ID, e.Block, result.Stage)
+		default:
+			fmt.Printf("%s %d (block %d): queued %s on the running agent\n", e.Workflow, e.ID, e.Block, result.Stage)
+		}
+	}
+	return nil
+}
+
+// doctorCheck is one preflight diagnostic
+type doctorCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	verbose := fs.Bool("v", false, "Show agent logs")
+	fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+
+	failed := 0
+	report := func(name string, err error) {
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL  %-22s %v\n", name, err)
+		} else {
+			fmt.Printf("ok    %s\n", name)
+		}
+	}
+
+	config, err := LoadConfig(*configPath)
+	report("config", err)
+	if err != nil {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+
+	client, err := ethclient.DialContext(ctx, config.Flare.RPCURL)
+	if err == nil {
+		var chainID *big.Int
+		if chainID, err = client.ChainID(ctx); err == nil && chainID.Int64() != config.Flare.ChainID {
+			err = fmt.Errorf("RPC reports chain %s, config has %d", chainID, config.Flare.ChainID)
+		}
+	}
+	report("flare_chain_id", err)
+
+	// Contract resolution happens while building the agent
+	a, err := openAgent(*verbose)
+	report("contracts", err)
+	if err != nil {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+
+	checks := []doctorCheck{
+		{"selectors", a.verifySelectors},
+		{"access_control", a.verifyAccessControl},
+	}
+	for _, check := range checks {
+		report(check.name, check.run(ctx))
+	}
+	for _, probe := range a.Readiness(ctx) {
+		var err error
+		if !probe.OK {
+			err = errors.New(probe.Error)
+		}
+		report(probe.Name, err)
+	}
+
+	checks = []doctorCheck{
+		{"xrpl_balance", func(ctx context.Context) error {
+			balance, err := a.paymentProc.xrplClient.GetBalance(ctx)
+			if err != nil {
+				return err
+			}
+			drops, _ := new(big.Int).SetString(balance, 10)
+			if drops == nil || drops.Cmp(new(big.Int).SetUint64(a.settings.MinXRPBalance())) <= 0 {
+				return fmt.Errorf("%s is at or below the %d drop floor", formatDrops(drops), a.settings.MinXRPBalance())
+			}
+			return nil
+		}},
+		{"flare_gas_balance", func(ctx context.Context) error {
+			address, err := a.agentAddress()
+			if err != nil {
+				return err
+			}
+			balance, err := a.flareClient.BalanceAt(ctx, address, nil)
+			if err != nil {
+				return err
+			}
+			if balance.Sign() == 0 {
+				return fmt.Errorf("%s has no FLR for gas", address.Hex())
+			}
+			return nil
+		}},
+		{"fdc_da_layer", func(ctx context.Context) error {
+			_, err := a.fdcSubmitter.da.GetDALayer(ctx, "/api/v0/fsp/status")
+			return err
+		}},
+		{"state_files", func(ctx context.Context) error {
+			for _, path := range []string{a.recoveryStatePath(), a.workflows.path, a.keeper.outcomeLog} {
+				if err := checkWritableDir(path); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"listen_addresses", func(ctx context.Context) error {
+			for _, addr := range []string{config.Metrics.ListenAddr, config.Admin.ListenAddr} {
+				if addr == "" {
+					continue
+				}
+				l, err := net.Listen("tcp", addr)
+				if err != nil {
+					return fmt.Errorf("%s is not available (is the agent already running?): %w", addr, err)
+				}
+				l.Close()
+			}
+			return nil
+		}},
+	}
+	for _, check := range checks {
+		report(check.name, check.run(ctx))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	fmt.Println("All checks passed")
+	return nil
+}
+
+// checkWritableDir verifies a file can be created next to path
+func checkWritableDir(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".doctor-*")
+	if err != nil {
+		return fmt.Errorf("cannot write next to %s: %w", path, err)
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+func runShadowReport(args []string) error {
+	fs := flag.NewFlagSet("shadow-report", flag.ExitOnError)
+	journal := fs.String("journal", "", "Shadow journal (default: shadow.journal from the config)")
+	asJSON := fs.Bool("json", false, "Print JSON")
+	all := fs.Bool("all", false, "Include IDs where shadow and production agree")
+	verbose := fs.Bool("v", false, "Show agent logs")
+	fs.Parse(args)
+
+	// The report reads production state, so it must not run in dry-run mode
+	os.Setenv(envName("shadow.enabled"), "false")
+	a, err := openAgent(*verbose)
+	if err != nil {
+		return err
+	}
+	if *journal == "" {
+		*journal = a.config.Shadow.Journal
+	}
+	if *journal == "" {
+		dir := a.config.Shadow.StateDir
+		if dir == "" {
+			dir = defaultShadowStateDir
+		}
+		*journal = filepath.Join(dir, defaultShadowJournal)
+	}
+
+	entries, err := ReadShadowJournal(*journal)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+
+	diffs, err := a.ShadowReport(ctx, entries)
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		return errShadowJournalEmpty
+	}
+
+	differing := 0
+	var shown []ShadowDiff
+	for _, d := range diffs {
+		if len(d.Differences) > 0 {
+			differing++
+		}
+		if *all || len(d.Differences) > 0 {
+			shown = append(shown, d)
+		}
+	}
+	if *asJSON {
+		return printJSON(shown)
+	}
+
+	for _, d := range shown {
+		fmt.Printf("%s %d\n  shadow:     %s\n  production: %s\n", d.Workflow, d.ID, strings.Join(d.Shadow, ", "), d.Production)
+		for _, difference := range d.Differences {
+			fmt.Printf("  DIFF  %s\n", difference)
+		}
+		for _, note := range d.Notes {
+			fmt.Printf("  note  %s\n", note)
+		}
+	}
+	fmt.Printf("%d of %d IDs differ (%d journal entries from %s)\n", differing, len(diffs), len(entries), *journal)
+	return nil
+}
+
+// callAdmin sends a request to the running agent's admin API. Queue
+// decisions go through the daemon because it owns the approval queue.
+// errAdminUnreachable means no daemon is serving the admin API
+var errAdminUnreachable = errors.New("no running agent is reachable")
+
+func callAdmin(method, path, operator string, body, out interface{}) error {
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if config.Admin.ListenAddr == "" {
+		return fmt.Errorf("%w: admin.listen_addr is not set", errAdminUnreachable)
+	}
+	host, port, err := net.SplitHostPort(config.Admin.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("invalid admin.listen_addr: %w", err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, "http://"+net.JoinHostPort(host, port)+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.Admin.Token)
+	req.Header.Set("Content-Type", "application/json")
+	if operator != "" {
+		req.Header.Set("X-Operator", operator)
+	}
+
+	resp, err := (&http.Client{Timeout: cliTimeout}).Do(req)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("%w: %v", errAdminUnreachable, err)
+	}
+	if err != nil {
+		return fmt.Errorf("admin API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("admin API returned %s: %s", resp.Status, apiErr.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func runApprovals(args []string) error {
+	fs := flag.NewFlagSet("approvals", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print JSON")
+	all := fs.Bool("all", false, "Include approved and rejected redemptions")
+	fs.Parse(args)
+
+	path := "/v1/approvals?status=" + ApprovalPending
+	if *all {
+		path = "/v1/approvals"
+	}
+	var approvals []Approval
+	if err := callAdmin(http.MethodGet, path, "", nil, &approvals); err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(approvals)
+	}
+	if len(approvals) == 0 {
+		fmt.Println("No redemptions waiting for approval")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\tSTATUS\tAMOUNT\tDESTINATION\tQUEUED\tREASON\n")
+	for _, a := range approvals {
+		drops, _ := new(big.Int).SetString(a.Drops, 10)
+		status := a.Status
+		if a.Operator != "" {
+			status += " by " + a.Operator
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", a.RedemptionID, status, formatDrops(drops), a.Destination,
+			a.QueuedAt.Format(time.RFC3339), strings.Join(a.Reasons, "; "))
+	}
+	return w.Flush()
+}
+
+func runApprove(args []string) error {
+	return decideApproval("approve", args)
+}
+
+func runReject(args []string) error {
+	return decideApproval("reject", args)
+}
+
+// decideApproval runs the approve and reject commands
+func decideApproval(action string, args []string) error {
+	fs := flag.NewFlagSet(action, flag.ExitOnError)
+	operator := operatorFlag(fs)
+	reason := fs.String("reason", "", "Reason recorded with the decision (required to reject)")
+	fs.Parse(args)
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+	if *operator == "" {
+		return errors.New("-operator is required")
+	}
+
+	var result struct {
+		Approval Approval `json:"approval"`
+		Queued   bool     `json:"queued"`
+		Error    string   `json:"error"`
+	}
+	path := fmt.Sprintf("/v1/approvals/%d/%s", id, action)
+	if err := callAdmin(http.MethodPost, path, *operator, adminAction{Reason: *reason}, &result); err != nil {
+		return err
+	}
+
+	fmt.Printf("Redemption %d %s by %s\n", id, result.Approval.Status, result.Approval.Operator)
+	if !result.Queued {
+		fmt.Printf("Payment stage not queued: %s\n", result.Error)
+	}
+	return nil
+}
+
+func runFirelightApprove(args []string) error {
+	fs := flag.NewFlagSet("firelight-approve", flag.ExitOnError)
+	operator := operatorFlag(fs)
+	reason := fs.String("reason", "", "Reason recorded with the approval")
+	fs.Parse(args)
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+	if *operator == "" {
+		return errors.New("-operator is required")
+	}
+
+	var result struct {
+		Incident FirelightIncident `json:"incident"`
+	}
+	path := fmt.Sprintf("/v1/firelight/%d/approve", id)
+	if err := callAdmin(http.MethodPost, path, *operator, adminAction{Reason: *reason}, &result); err != nil {
+		return err
+	}
+	fmt.Printf("Firelight trigger for redemption %d (%s, amount %s) approved by %s; it runs on the next scan\n",
+		id, result.Incident.Cause, result.Incident.Amount, *operator)
+	return nil
+}
+
+// auditJournalPath returns -journal, or audit.journal from the config
+func auditJournalPath(journal string) (string, error) {
+	if journal != "" {
+		return journal, nil
+	}
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if config.Audit.Journal == "" {
+		return defaultAuditJournal, nil
+	}
+	return config.Audit.Journal, nil
+}
+
+func runAuditVerify(args []string) error {
+	fs := flag.NewFlagSet("audit-verify", flag.ExitOnError)
+	journal := fs.String("journal", "", "Audit journal (default: audit.journal from the config)")
+	asJSON := fs.Bool("json", false, "Print JSON")
+	fs.Parse(args)
+
+	path, err := auditJournalPath(*journal)
+	if err != nil {
+		return err
+	}
+	_, result, err := ReadAuditJournal(path)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range result.Problems {
+			fmt.Printf("line %d (seq %d): %s\n", p.Line, p.Seq, p.Problem)
+		}
+		fmt.Printf("%d entries, head seq %d, head hash %s\n", result.Entries, result.HeadSeq, result.HeadHash)
+	}
+	if !result.Valid() {
+		return fmt.Errorf("audit journal %s failed verification with %d problems", path, len(result.Problems))
+	}
+	if !*asJSON {
+		fmt.Println("Chain intact. Compare the head hash with a copy kept elsewhere to detect truncation.")
+	}
+	return nil
+}
+
+// AuditExport is the bundle written by audit-export
+type AuditExport struct {
+	Journal      string            `json:"journal"`
+	ExportedAt   time.Time         `json:"exported_at"`
+	Verification AuditVerification `json:"verification"` // Of the whole journal, not just the exported entries
+	Filter       map[string]string `json:"filter,omitempty"`
+	Entries      []AuditEntry      `json:"entries"`
+}
+
+func runAuditExport(args []string) error {
+	fs := flag.NewFlagSet("audit-export", flag.ExitOnError)
+	journal := fs.String("journal", "", "Audit journal (default: audit.journal from the config)")
+	workflow := fs.String("workflow", "", "Only entries for this workflow (redemption or minting)")
+	id := fs.Int64("id", -1, "Only entries for this ID (needs -workflow)")
+	from := fs.String("from", "", "Only entries at or after this RFC 3339 time")
+	to := fs.String("to", "", "Only entries before this RFC 3339 time")
+	out := fs.String("o", "", "Write to this file instead of stdout")
+	fs.Parse(args)
+
+	if *id >= 0 && *workflow == "" {
+		return errors.New("-id needs -workflow")
+	}
+	filter := make(map[string]string)
+	var fromTime, toTime time.Time
+	var err error
+	if *from != "" {
+		if fromTime, err = time.Parse(time.RFC3339, *from); err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+		filter["from"] = *from
+	}
+	if *to != "" {
+		if toTime, err = time.Parse(time.RFC3339, *to); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+		filter["to"] = *to
+	}
+	if *workflow != "" {
+		filter["workflow"] = *workflow
+	}
+	if *id >= 0 {
+		filter["id"] = strconv.FormatInt(*id, 10)
+	}
+
+	path, err := auditJournalPath(*journal)
+	if err != nil {
+		return err
+	}
+	entries, result, err := ReadAuditJournal(path)
+	if err != nil {
+		return err
+	}
+
+	// Filtered entries keep their seq, prev_hash and hash so they can be
+	// checked against the full journal
+	export := AuditExport{
+		Journal:      path,
+		ExportedAt:   time.Now().UTC(),
+		Verification: *result,
+		Filter:       filter,
+		Entries:      []AuditEntry{},
+	}
+	for _, e := range entries {
+		if *workflow != "" && e.Workflow != *workflow {
+			continue
+		}
+		if *id >= 0 && (e.ID == nil || *e.ID != uint64(*id)) {
+			continue
+		}
+		if !fromTime.IsZero() && e.Time.Before(fromTime) {
+			continue
+		}
+		if !toTime.IsZero() && !e.Time.Before(toTime) {
+			continue
+		}
+		export.Entries = append(export.Entries, e)
+	}
+
+	if *out == "" {
+		return printJSON(export)
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	fmt.Printf("Exported %d of %d entries to %s", len(export.Entries), result.Entries, *out)
+	if !result.Valid() {
+		fmt.Printf(" (journal failed verification with %d problems)", len(result.Problems))
+	}
+	fmt.Println()
+	return nil
+}
+
+func runReconcile(args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	ledgers := fs.Uint64("ledgers", 0, "XRPL ledgers of payment history to check (default: reconcile.ledger_lookback)")
+	blocks := fs.Uint64("blocks", 0, "Flare blocks of recorded payments to check (default: reconcile.block_lookback)")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	out := fs.String("o", "", "Write the report to this file (default: a new file in reconcile.report_dir)")
+	verbose := fs.Bool("v", false, "Show agent logs")
+	fs.Parse(args)
+
+	a, err := openAgent(*verbose)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	if *ledgers == 0 {
+		*ledgers = a.reconciler.ledgers
+	}
+	if *blocks == 0 {
+		*blocks = a.reconciler.blocks
+	}
+	report, err := a.reconciler.Reconcile(ctx, *ledgers, *blocks)
+	if err != nil {
+		return err
+	}
+
+	path := *out
+	if path == "" {
+		if path, err = a.reconciler.WriteReport(report); err != nil {
+			return err
+		}
+	} else {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	if *asJSON {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else if len(report.Findings) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tREDEMPTION\tDETAIL\tACTION")
+		for _, f := range report.Findings {
+			id := "-"
+			if f.RedemptionID != nil {
+				id = strconv.FormatUint(*f.RedemptionID, 10)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Kind, id, f.Detail, f.Action)
+		}
+		w.Flush()
+	}
+	if !*asJSON {
+		fmt.Printf("Checked %d payments and %d redemptions from ledger %d and blocks %d-%d; report written to %s\n",
+			report.PaymentsChecked, report.RecordsChecked, report.FromLedger, report.FromBlock, report.ToBlock, path)
+	}
+	if len(report.Findings) > 0 {
+		return fmt.Errorf("reconciliation found %d problems", len(report.Findings))
+	}
+	return nil
+}
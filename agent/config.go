@@ -1,38 +1,94 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Flare FlareConfig `yaml:"flare"`
-	XRPL  XRPLConfig  `yaml:"xrpl"`
-	FDC   FDCConfig   `yaml:"fdc"`
-	Agent AgentConfig `yaml:"agent"`
+	Network       string              `yaml:"network"` // Network profile: coston2, coston, songbird, flare
+	Flare         FlareConfig         `yaml:"flare"`
+	XRPL          XRPLConfig          `yaml:"xrpl"`
+	FDC           FDCConfig           `yaml:"fdc"`
+	Agent         AgentConfig         `yaml:"agent"`
+	Keeper        KeeperConfig        `yaml:"keeper"`
+	Firelight     FirelightConfig     `yaml:"firelight"`
+	Scoring       ScoringConfig       `yaml:"scoring"`
+	Metrics       MetricsConfig       `yaml:"metrics"`
+	Admin         AdminConfig         `yaml:"admin"`
+	Shadow        ShadowConfig        `yaml:"shadow"`
+	Breakers      BreakersConfig      `yaml:"breakers"`
+	Limits        LimitsConfig        `yaml:"limits"`
+	Screening     ScreeningConfig     `yaml:"screening"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Audit         AuditConfig         `yaml:"audit"`
+	Reconcile     ReconcileConfig     `yaml:"reconcile"`
+
+	Profile NetworkProfile `yaml:"-"` // Resolved from Network by LoadConfig
 }
 
 type FlareConfig struct {
-	RPCURL             string `yaml:"rpc_url"`
-	ChainID            int64  `yaml:"chain_id"`
-	FLIPCoreAddress    string `yaml:"flip_core_address"`
-	EscrowVaultAddress string `yaml:"escrow_vault_address"`
-	PrivateKey         string // Loaded from PRIVATE_KEY env var (not in YAML)
+	RPCURL                   string `yaml:"rpc_url"`
+	ChainID                  int64  `yaml:"chain_id"`
+	FLIPCoreAddress          string `yaml:"flip_core_address"`
+	EscrowVaultAddress       string `yaml:"escrow_vault_address"`
+	OperatorRegistryAddress  string `yaml:"operator_registry_address"`
+	LPRegistryAddress        string `yaml:"lp_registry_address"`
+	SettlementReceiptAddress string `yaml:"settlement_receipt_address"`
+	BlazeVaultAddress        string `yaml:"blaze_vault_address"`
+	InsurancePoolAddress     string `yaml:"insurance_pool_address"` // Optional; only watched by the circuit breakers
+	MulticallAddress         string `yaml:"multicall_address"`      // Defaults to the canonical Multicall3 address
+	PrivateKey               string // Loaded from PRIVATE_KEY env var (not in YAML)
+
+	// Flare ContractRegistry used to resolve FdcHub, Relay and friends
+	ContractRegistryAddress string `yaml:"contract_registry_address"`
+	RegistryRefreshInterval int    `yaml:"registry_refresh_interval"` // seconds
 }
 
 type XRPLConfig struct {
-	TestnetWS   string `yaml:"testnet_ws"`
-	TestnetRPC  string `yaml:"testnet_rpc"`
-	WalletSeed  string `yaml:"wallet_seed"`
+	WSURL      string `yaml:"ws_url"`  // Defaults to the network profile's XRPL endpoint
+	RPCURL     string `yaml:"rpc_url"` // Defaults to the network profile's XRPL endpoint
+	WalletSeed string `yaml:"wallet_seed"`
+
+	// Deprecated: use ws_url and rpc_url. Still read, and copied over when
+	// the new keys are unset.
+	LegacyTestnetWS  string `yaml:"testnet_ws"`
+	LegacyTestnetRPC string `yaml:"testnet_rpc"`
+
+	// Addresses minting deposits must be paid to. agent_vault_addresses maps
+	// an FAssets agent vault (EVM) to its XRPL underlying address;
+	// deposit_address is used for vaults not listed there.
+	DepositAddress      string            `yaml:"deposit_address"`
+	AgentVaultAddresses map[string]string `yaml:"agent_vault_addresses"`
 }
 
 type FDCConfig struct {
 	VerifierURL string `yaml:"verifier_url"`
 	APIKey      string `yaml:"api_key"`
+
+	// Optional overrides of the selected network profile
+	DALayerURL                 string `yaml:"da_layer_url"`
+	FdcHubAddress              string `yaml:"fdc_hub_address"`
+	FeeConfigAddress           string `yaml:"fee_config_address"`
+	RelayAddress               string `yaml:"relay_address"`
+	FdcVerificationAddress     string `yaml:"fdc_verification_address"`
+	FlareSystemsManagerAddress string `yaml:"flare_systems_manager_address"`
+
+	// Redundant endpoints tried after verifier_url / da_layer_url
+	VerifierURLs               []string `yaml:"verifier_urls"`
+	DALayerURLs                []string `yaml:"da_layer_urls"`
+	EndpointFailureThreshold   int      `yaml:"endpoint_failure_threshold"` // Consecutive failures before an endpoint's circuit opens
+	EndpointCooldown           int      `yaml:"endpoint_cooldown"`          // Seconds before an open circuit is retried
+	ProofAgreement             int      `yaml:"proof_agreement"`            // DA providers that must return identical proofs (0 = off)
+	SourceID                   string   `yaml:"source_id"`
+	FirstVotingRoundStartTs    uint64   `yaml:"first_voting_round_start_ts"`
+	VotingEpochDurationSeconds uint64   `yaml:"voting_epoch_duration_seconds"`
 }
 
 type AgentConfig struct {
@@ -41,20 +97,142 @@ type AgentConfig struct {
 	PaymentRetryDelay int    `yaml:"payment_retry_delay"`
 	FDCTimeout        int    `yaml:"fdc_timeout"`
 	MinXRPBalance     uint64 `yaml:"min_xrp_balance"`
+	SkipSelectorCheck bool   `yaml:"skip_selector_check"` // e.g. for contracts behind a proxy
+	RecoveryStateFile string `yaml:"recovery_state_file"` // Low-water marks for startup recovery
+	RecoveryBatchSize int    `yaml:"recovery_batch_size"` // Calls per Multicall3 request
+	LogLevel          string `yaml:"log_level"`           // trace, debug, info, warn or error
+	WorkflowStateFile string `yaml:"workflow_state_file"` // Per-ID state, holds and pauses for the admin API
+
+	ShutdownGracePeriod int `yaml:"shutdown_grace_period"` // Seconds in-flight work may run after SIGINT/SIGTERM
+}
+
+// KeeperConfig controls the timeout keeper that calls checkTimeout and
+// checkMintingTimeout on escrows past their FDC window
+type KeeperConfig struct {
+	Enabled         bool   `yaml:"enabled"`
+	Interval        int    `yaml:"interval"`           // Seconds between scans
+	MaxGasPriceGwei uint64 `yaml:"max_gas_price_gwei"` // Skip timeouts above this gas price (0 = no ceiling)
+	OutcomeLog      string `yaml:"outcome_log"`        // JSON lines file of timeout outcomes
+}
+
+// FirelightConfig controls the watcher that triggers the Firelight backstop
+// for redemptions that failed or timed out with a failed escrow
+type FirelightConfig struct {
+	Enabled           bool   `yaml:"enabled"`
+	Interval          int    `yaml:"interval"`           // Seconds between scans
+	DryRun            bool   `yaml:"dry_run"`            // Only preview and report, never trigger
+	ApprovalThreshold string `yaml:"approval_threshold"` // Redemption amount (base units) above which an operator must approve
+	ReportDir         string `yaml:"report_dir"`         // Per-incident reports and approval files
+}
+
+// ScoringConfig controls the inputs the agent scores requests with before
+// choosing the provisional fast lane or the FDC queue
+type ScoringConfig struct {
+	DefaultFeed        string            `yaml:"default_feed"`         // FTSOv2 feed for assets not in feeds, e.g. "XRP/USD"
+	Feeds              map[string]string `yaml:"feeds"`                // Asset address -> FTSOv2 feed name
+	SampleInterval     int               `yaml:"sample_interval"`      // Seconds between FTSO samples
+	VolatilityWindow   int               `yaml:"volatility_window"`    // Samples used for volatility
+	DefaultVolatility  uint64            `yaml:"default_volatility"`   // Used until enough samples exist (1000000 = 100%)
+	SuccessRateRefresh int               `yaml:"success_rate_refresh"` // Seconds between success rate recomputations
+	FtsoV2Address      string            `yaml:"ftso_v2_address"`      // Override of the ContractRegistry lookup
+}
+
+// MetricsConfig controls the /metrics, /healthz and /readyz HTTP server
+type MetricsConfig struct {
+	ListenAddr     string `yaml:"listen_addr"`     // e.g. ":9090" (empty = disabled)
+	StallThreshold int    `yaml:"stall_threshold"` // Seconds without a successful poll before /healthz fails
+}
+
+// AdminConfig controls the operator admin API
+type AdminConfig struct {
+	ListenAddr string `yaml:"listen_addr"` // e.g. "127.0.0.1:9091" (empty = disabled)
+	Token      string `yaml:"token"`       // Bearer token; set FLIP_ADMIN_TOKEN rather than committing it
+}
+
+// ShadowConfig controls dry-run mode, where the agent runs its full pipeline
+// against live traffic but only records the XRPL payments and Flare
+// transactions it would have sent
+type ShadowConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	StateDir string `yaml:"state_dir"` // Holds the shadow agent's state files so production's are untouched
+	Journal  string `yaml:"journal"`   // JSON lines file of would-have-sent transactions (default <state_dir>/journal.jsonl)
+}
+
+// BreakersConfig controls the circuit breakers that halt XRPL payouts. A
+// threshold of 0 disables its breaker.
+type BreakersConfig struct {
+	Interval           int    `yaml:"interval"`             // Seconds between checks
+	StateFile          string `yaml:"state_file"`           // Tripped breakers, kept until an operator resets them
+	MinXRPBalance      uint64 `yaml:"min_xrp_balance"`      // Trip when the XRPL wallet drops below this (drops)
+	FDCWindow          int    `yaml:"fdc_window"`           // Seconds of FDC proof outcomes the FDC breakers look at
+	FDCMinSamples      int    `yaml:"fdc_min_samples"`      // Outcomes needed in the window before the failure rate counts
+	MaxFDCFailureRate  int    `yaml:"max_fdc_failure_rate"` // Trip when more than this percentage of FDC proofs failed
+	ProofMismatchLimit int    `yaml:"proof_mismatch_limit"` // Trip once this many DA proof mismatches happen in the window
+	MaxBlockLag        int    `yaml:"max_block_lag"`        // Trip when an event cursor is this many blocks behind head
+}
+
+// LimitsConfig caps XRPL payouts. Redemptions over a limit wait in the
+// approval queue until an operator approves or rejects them. Amounts are in
+// drops; 0 disables a limit.
+type LimitsConfig struct {
+	MaxPerRedemption       uint64 `yaml:"max_per_redemption"`
+	MaxPerDestinationDaily uint64 `yaml:"max_per_destination_daily"` // To one XRPL address in a rolling 24 hours
+	MaxHourlyOutflow       uint64 `yaml:"max_hourly_outflow"`        // Across all payouts in a rolling hour
+	StateFile              string `yaml:"state_file"`                // Recent payouts and the approval queue
+	DecisionLog            string `yaml:"decision_log"`              // JSON lines journal of queued, approved and rejected redemptions
+}
+
+// ScreeningConfig enables compliance screening of the XRPL destination and
+// EVM user before a payout or minting fast lane. A hit puts the ID on a
+// screened hold. Both screeners may be set; either one's hit holds the ID.
+type ScreeningConfig struct {
+	DenylistFile string `yaml:"denylist_file"` // One address per line, re-read when the file changes
+	URL          string `yaml:"url"`           // Screening service the subject is POSTed to
+	APIKey       string `yaml:"api_key"`       // Sent to the screening service as a bearer token
+	Timeout      int    `yaml:"timeout"`       // Seconds per screening request
+	FailOpen     bool   `yaml:"fail_open"`     // Proceed when screening fails instead of stopping the ID
 }
 
+// NotificationsConfig pushes lifecycle and incident events to sinks
+type NotificationsConfig struct {
+	DedupWindow  int          `yaml:"dedup_window"`  // Seconds an event for the same subject is not sent again
+	MaxAttempts  int          `yaml:"max_attempts"`  // Delivery attempts per notification and sink
+	RetryBackoff int          `yaml:"retry_backoff"` // Seconds before the first retry, doubled after each
+	LowBalance   uint64       `yaml:"low_balance"`   // Send low_balance below this XRPL balance (drops); 0 disables
+	Sinks        []SinkConfig `yaml:"sinks"`         // Set in the config file only
+}
+
+// SinkConfig is one notification destination
+type SinkConfig struct {
+	Name   string   `yaml:"name"`   // Used in logs and metrics; defaults to type-index
+	Type   string   `yaml:"type"`   // webhook, slack or file
+	URL    string   `yaml:"url"`    // webhook and slack
+	Secret string   `yaml:"secret"` // webhook: HMAC-SHA256 key for X-Flip-Signature
+	Path   string   `yaml:"path"`   // file: JSON lines file, or "-" for stdout
+	Events []string `yaml:"events"` // Events to send; empty sends all
+}
+
+// AuditConfig locates the hash-chained audit journal
+type AuditConfig struct {
+	Journal string `yaml:"journal"` // Append-only JSON lines, one entry per decision or side effect
+}
+
+// ReconcileConfig controls the job that compares XRPL payouts with the
+// payment hashes recorded on FLIPCore
+type ReconcileConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	Interval       int      `yaml:"interval"`        // Seconds between runs
+	LedgerLookback int      `yaml:"ledger_lookback"` // XRPL ledgers of payment history to check
+	BlockLookback  int      `yaml:"block_lookback"`  // Flare blocks of XrplPaymentRecorded events to check
+	Wallets        []string `yaml:"wallets"`         // Payout wallets besides the agent's own, e.g. retired ones
+	ReportDir      string   `yaml:"report_dir"`      // One JSON report per run
+}
+
+// LoadConfig reads config.yaml, applies FLIP_ environment overrides, the
+// network profile and defaults, then validates the result
 func LoadConfig(path string) (*Config, error) {
-	// Load .env file from project root (one level up from agent directory)
-	configDir := filepath.Dir(path)
-	envPath := filepath.Join(configDir, "..", ".env")
-	if err := godotenv.Load(envPath); err != nil {
-		// Try current directory as fallback
-		if err := godotenv.Load(".env"); err != nil {
-			// Try project root directly
-			if err := godotenv.Load("../.env"); err != nil {
-				fmt.Printf("Warning: Could not load .env file: %v\n", err)
-			}
-		}
+	if err := loadEnvFile(path); err != nil {
+		return nil, err
 	}
 
 	data, err := os.ReadFile(path)
@@ -67,18 +245,23 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	if err := config.applyEnvOverrides(); err != nil {
+		return nil, err
+	}
+
+	config.applyDeprecatedKeys()
+
 	// Load private key from environment variable
 	config.Flare.PrivateKey = os.Getenv("PRIVATE_KEY")
 
-	// Validate required fields
-	if config.Flare.RPCURL == "" {
-		return nil, fmt.Errorf("flare.rpc_url is required")
+	// Fill endpoints and FDC parameters from the network profile
+	if err := config.applyNetworkProfile(); err != nil {
+		return nil, fmt.Errorf("invalid network: %w", err)
 	}
-	if config.Flare.FLIPCoreAddress == "" {
-		return nil, fmt.Errorf("flare.flip_core_address is required")
-	}
-	if config.XRPL.WalletSeed == "" || config.XRPL.WalletSeed == "sYOUR_WALLET_SEED_HERE" {
-		return nil, fmt.Errorf("xrpl.wallet_seed must be set")
+	config.applyDefaults()
+
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 	if config.Flare.PrivateKey == "" {
 		fmt.Println("Warning: PRIVATE_KEY not set in .env - automatic redemption processing disabled")
@@ -87,3 +270,41 @@ func LoadConfig(path string) (*Config, error) {
 	return &config, nil
 }
 
+// applyDeprecatedKeys maps renamed config keys onto their replacements,
+// warning for each one still in use. A value under the new key wins.
+func (c *Config) applyDeprecatedKeys() {
+	for _, key := range []struct {
+		oldKey, newKey string
+		from           string
+		to             *string
+	}{
+		{"xrpl.testnet_ws", "xrpl.ws_url", c.XRPL.LegacyTestnetWS, &c.XRPL.WSURL},
+		{"xrpl.testnet_rpc", "xrpl.rpc_url", c.XRPL.LegacyTestnetRPC, &c.XRPL.RPCURL},
+	} {
+		if key.from == "" {
+			continue
+		}
+		log.Warn().Str("key", key.oldKey).Str("use", key.newKey).Msg("deprecated config key")
+		if *key.to == "" {
+			*key.to = key.from
+		}
+	}
+}
+
+// loadEnvFile loads FLIP_ENV_FILE if set, otherwise the .env in the project
+// root one level above the config file. Variables already set in the
+// environment take precedence.
+func loadEnvFile(configPath string) error {
+	if envPath := os.Getenv("FLIP_ENV_FILE"); envPath != "" {
+		if err := godotenv.Load(envPath); err != nil {
+			return fmt.Errorf("failed to load FLIP_ENV_FILE %s: %w", envPath, err)
+		}
+		return nil
+	}
+
+	envPath := filepath.Join(filepath.Dir(configPath), "..", ".env")
+	if err := godotenv.Load(envPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to load %s: %w", envPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog"
+)
+
+// Agent defaults used when config.yaml and the environment leave them unset
+const (
+	defaultPollingInterval   = 10 // seconds
+	defaultMaxPaymentRetries = 3
+	defaultPaymentRetryDelay = 5 // seconds
+	defaultLogLevel          = "info"
+	defaultStallThreshold    = 120 // seconds
+	defaultShutdownGrace     = 30  // seconds
+	minAdminTokenLength      = 24
+)
+
+// envPrefix prefixes the environment variable that overrides each config
+// field, e.g. FLIP_AGENT_POLLING_INTERVAL for agent.polling_interval
+const envPrefix = "FLIP_"
+
+// hotReloadFields can change on SIGHUP without a restart
+var hotReloadFields = map[string]bool{
+	"agent.polling_interval":           true,
+	"agent.max_payment_retries":        true,
+	"agent.payment_retry_delay":        true,
+	"agent.min_xrp_balance":            true,
+	"agent.log_level":                  true,
+	"limits.max_per_redemption":        true,
+	"limits.max_per_destination_daily": true,
+	"limits.max_hourly_outflow":        true,
+	"flare.lp_registry_address":        true, // Via FlipContracts.Reload
+	"flare.operator_registry_address":  true,
+	"flare.settlement_receipt_address": true,
+	"flare.blaze_vault_address":        true,
+	"flare.insurance_pool_address":     true,
+}
+
+// walkConfig calls fn for every leaf field that has a yaml tag, with its
+// dotted yaml path such as "agent.polling_interval"
+func walkConfig(v reflect.Value, prefix string, fn func(path string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		path := tag
+		if prefix != "" {
+			path = prefix + "." + tag
+		}
+
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			walkConfig(field, path, fn)
+			continue
+		}
+		fn(path, field)
+	}
+}
+
+// envName returns the environment variable that overrides a config path
+func envName(path string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// applyEnvOverrides sets every field whose FLIP_ variable is present. Lists
+// are comma separated and maps are comma separated key=value pairs.
+func (c *Config) applyEnvOverrides() error {
+	var problems []string
+	walkConfig(reflect.ValueOf(c).Elem(), "", func(path string, field reflect.Value) {
+		name := envName(path)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if err := setConfigField(field, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	})
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid environment overrides:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func setConfigField(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		field.SetBool(v)
+	case reflect.Int, reflect.Int64:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		field.SetInt(v)
+	case reflect.Uint64:
+		v, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected a non-negative integer, got %q", raw)
+		}
+		field.SetUint(v)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("%s cannot be set from the environment", field.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		items := make(map[string]string)
+		for _, pair := range strings.Split(raw, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value pairs, got %q", pair)
+			}
+			items[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// applyDefaults fills fields whose zero value would break the agent
+func (c *Config) applyDefaults() {
+	if c.Agent.PollingInterval == 0 {
+		c.Agent.PollingInterval = defaultPollingInterval
+	}
+	if c.Agent.MaxPaymentRetries == 0 {
+		c.Agent.MaxPaymentRetries = defaultMaxPaymentRetries
+	}
+	if c.Agent.PaymentRetryDelay == 0 {
+		c.Agent.PaymentRetryDelay = defaultPaymentRetryDelay
+	}
+	if c.Agent.LogLevel == "" {
+		c.Agent.LogLevel = defaultLogLevel
+	}
+	if c.Agent.ShutdownGracePeriod == 0 {
+		c.Agent.ShutdownGracePeriod = defaultShutdownGrace
+	}
+	if c.Metrics.StallThreshold == 0 {
+		c.Metrics.StallThreshold = defaultStallThreshold
+	}
+	c.applyShadow()
+}
+
+// configValidator collects every problem so one run reports them all
+type configValidator struct {
+	problems []string
+}
+
+func (v *configValidator) fail(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+func (v *configValidator) required(path, value string) {
+	if value == "" {
+		v.fail(path, "is required (or set %s)", envName(path))
+	}
+}
+
+func (v *configValidator) intRange(path string, value, min, max int) {
+	if value < min || value > max {
+		v.fail(path, "must be between %d and %d, got %d", min, max, value)
+	}
+}
+
+func (v *configValidator) address(path, value string) {
+	if value != "" && !common.IsHexAddress(value) {
+		v.fail(path, "is not a valid address: %q", value)
+	}
+}
+
+func (v *configValidator) url(path, value string, schemes ...string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		v.fail(path, "is not a valid URL: %q", value)
+		return
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return
+		}
+	}
+	v.fail(path, "must use %s, got %q", strings.Join(schemes, " or "), u.Scheme)
+}
+
+// Validate checks required fields, formats and ranges
+func (c *Config) Validate() error {
+	v := &configValidator{}
+
+	v.required("flare.rpc_url", c.Flare.RPCURL)
+	v.url("flare.rpc_url", c.Flare.RPCURL, "http", "https", "ws", "wss")
+	if c.Flare.ChainID <= 0 {
+		v.fail("flare.chain_id", "must be positive, got %d", c.Flare.ChainID)
+	}
+	v.required("flare.flip_core_address", c.Flare.FLIPCoreAddress)
+	for path, value := range map[string]string{
+		"flare.flip_core_address":           c.Flare.FLIPCoreAddress,
+		"flare.escrow_vault_address":        c.Flare.EscrowVaultAddress,
+		"flare.operator_registry_address":   c.Flare.OperatorRegistryAddress,
+		"flare.lp_registry_address":         c.Flare.LPRegistryAddress,
+		"flare.settlement_receipt_address":  c.Flare.SettlementReceiptAddress,
+		"flare.blaze_vault_address":         c.Flare.BlazeVaultAddress,
+		"flare.insurance_pool_address":      c.Flare.InsurancePoolAddress,
+		"flare.multicall_address":           c.Flare.MulticallAddress,
+		"flare.contract_registry_address":   c.Flare.ContractRegistryAddress,
+		"fdc.fdc_hub_address":               c.FDC.FdcHubAddress,
+		"fdc.fee_config_address":            c.FDC.FeeConfigAddress,
+		"fdc.relay_address":                 c.FDC.RelayAddress,
+		"fdc.fdc_verification_address":      c.FDC.FdcVerificationAddress,
+		"fdc.flare_systems_manager_address": c.FDC.FlareSystemsManagerAddress,
+		"scoring.ftso_v2_address":           c.Scoring.FtsoV2Address,
+	} {
+		v.address(path, value)
+	}
+	// Without a profile fallback, a failed ContractRegistry lookup would leave
+	// the agent with no FdcHub to submit attestation requests to
+	if c.Profile.FdcHubAddress == "" && c.FDC.FdcHubAddress == "" {
+		v.fail("fdc.fdc_hub_address", "is required on %s, which has no built-in fallback (or set %s)", c.Network, envName("fdc.fdc_hub_address"))
+	}
+	if c.Profile.FdcFeeConfigAddress == "" && c.FDC.FeeConfigAddress == "" {
+		v.fail("fdc.fee_config_address", "is required on %s, which has no built-in fallback (or set %s)", c.Network, envName("fdc.fee_config_address"))
+	}
+	v.intRange("flare.registry_refresh_interval", c.Flare.RegistryRefreshInterval, 0, 86400)
+
+	if c.XRPL.WalletSeed == "" || c.XRPL.WalletSeed == "sYOUR_WALLET_SEED_HERE" {
+		v.fail("xrpl.wallet_seed", "must be set (or set %s)", envName("xrpl.wallet_seed"))
+	}
+	v.url("xrpl.ws_url", c.XRPL.WSURL, "ws", "wss")
+	v.url("xrpl.rpc_url", c.XRPL.RPCURL, "http", "https")
+	for vault := range c.XRPL.AgentVaultAddresses {
+		v.address("xrpl.agent_vault_addresses", vault)
+	}
+
+	v.url("fdc.verifier_url", c.FDC.VerifierURL, "http", "https")
+	v.url("fdc.da_layer_url", c.FDC.DALayerURL, "http", "https")
+	for _, u := range c.FDC.VerifierURLs {
+		v.url("fdc.verifier_urls", u, "http", "https")
+	}
+	for _, u := range c.FDC.DALayerURLs {
+		v.url("fdc.da_layer_urls", u, "http", "https")
+	}
+	v.intRange("fdc.endpoint_failure_threshold", c.FDC.EndpointFailureThreshold, 0, 100)
+	v.intRange("fdc.endpoint_cooldown", c.FDC.EndpointCooldown, 0, 86400)
+	if c.FDC.ProofAgreement < 0 || c.FDC.ProofAgreement > 1+len(c.FDC.DALayerURLs) {
+		v.fail("fdc.proof_agreement", "is %d but only %d DA layer endpoints are configured", c.FDC.ProofAgreement, 1+len(c.FDC.DALayerURLs))
+	}
+
+	v.intRange("agent.polling_interval", c.Agent.PollingInterval, 1, 3600)
+	v.intRange("agent.max_payment_retries", c.Agent.MaxPaymentRetries, 1, 20)
+	v.intRange("agent.payment_retry_delay", c.Agent.PaymentRetryDelay, 0, 3600)
+	v.intRange("agent.fdc_timeout", c.Agent.FDCTimeout, 0, 3600)
+	v.intRange("agent.recovery_batch_size", c.Agent.RecoveryBatchSize, 0, 1000)
+	v.intRange("agent.shutdown_grace_period", c.Agent.ShutdownGracePeriod, 1, 3600)
+	if _, err := zerolog.ParseLevel(c.Agent.LogLevel); err != nil {
+		v.fail("agent.log_level", "must be one of trace, debug, info, warn, error, got %q", c.Agent.LogLevel)
+	}
+
+	v.intRange("keeper.interval", c.Keeper.Interval, 0, 86400)
+	v.intRange("firelight.interval", c.Firelight.Interval, 0, 86400)
+	if c.Firelight.ApprovalThreshold != "" {
+		if _, ok := new(big.Int).SetString(c.Firelight.ApprovalThreshold, 10); !ok {
+			v.fail("firelight.approval_threshold", "must be an integer amount in base units, got %q", c.Firelight.ApprovalThreshold)
+		}
+	}
+
+	for asset := range c.Scoring.Feeds {
+		v.address("scoring.feeds", asset)
+	}
+	v.intRange("scoring.sample_interval", c.Scoring.SampleInterval, 0, 3600)
+	v.intRange("scoring.volatility_window", c.Scoring.VolatilityWindow, 0, 10000)
+	v.intRange("scoring.success_rate_refresh", c.Scoring.SuccessRateRefresh, 0, 86400)
+	if c.Scoring.DefaultVolatility > 1000000 {
+		v.fail("scoring.default_volatility", "must be at most 1000000 (100%%), got %d", c.Scoring.DefaultVolatility)
+	}
+
+	if c.Admin.ListenAddr != "" && len(c.Admin.Token) < minAdminTokenLength {
+		v.fail("admin.token", "must be at least %d characters when admin.listen_addr is set (or set %s)", minAdminTokenLength, envName("admin.token"))
+	}
+
+	if c.Shadow.Enabled {
+		// Sends are intercepted in the HTTP transport
+		v.url("flare.rpc_url", c.Flare.RPCURL, "http", "https")
+	}
+
+	v.intRange("breakers.interval", c.Breakers.Interval, 0, 3600)
+	v.intRange("breakers.fdc_window", c.Breakers.FDCWindow, 0, 7*86400)
+	v.intRange("breakers.fdc_min_samples", c.Breakers.FDCMinSamples, 0, 10000)
+	v.intRange("breakers.max_fdc_failure_rate", c.Breakers.MaxFDCFailureRate, 0, 100)
+	v.intRange("breakers.proof_mismatch_limit", c.Breakers.ProofMismatchLimit, 0, 10000)
+	v.intRange("breakers.max_block_lag", c.Breakers.MaxBlockLag, 0, 1000000)
+
+	v.url("screening.url", c.Screening.URL, "http", "https")
+	v.intRange("screening.timeout", c.Screening.Timeout, 0, 300)
+
+	v.intRange("notifications.dedup_window", c.Notifications.DedupWindow, 0, 7*86400)
+	v.intRange("notifications.max_attempts", c.Notifications.MaxAttempts, 0, 100)
+	v.intRange("notifications.retry_backoff", c.Notifications.RetryBackoff, 0, 3600)
+	for i, sink := range c.Notifications.Sinks {
+		path := fmt.Sprintf("notifications.sinks[%d]", i)
+		switch sink.Type {
+		case SinkWebhook, SinkSlack:
+			if sink.URL == "" {
+				v.fail(path+".url", "is required for %s sinks", sink.Type)
+			}
+			v.url(path+".url", sink.URL, "http", "https")
+		case SinkFile:
+			if sink.Path == "" {
+				v.fail(path+".path", "is required for file sinks")
+			}
+		default:
+			v.fail(path+".type", "must be %s, %s or %s, got %q", SinkWebhook, SinkSlack, SinkFile, sink.Type)
+		}
+		for _, event := range sink.Events {
+			if _, ok := notifyEvents[event]; !ok {
+				v.fail(path+".events", "unknown event %q", event)
+			}
+		}
+	}
+
+	v.intRange("reconcile.interval", c.Reconcile.Interval, 0, 7*86400)
+	v.intRange("reconcile.ledger_lookback", c.Reconcile.LedgerLookback, 0, 10000000)
+	v.intRange("reconcile.block_lookback", c.Reconcile.BlockLookback, 0, 10000000)
+
+	v.intRange("metrics.stall_threshold", c.Metrics.StallThreshold, 1, 86400)
+	if c.Metrics.StallThreshold < 2*c.Agent.PollingInterval {
+		v.fail("metrics.stall_threshold", "must be at least twice agent.polling_interval (%ds), got %d", c.Agent.PollingInterval, c.Metrics.StallThreshold)
+	}
+
+	if len(v.problems) > 0 {
+		sort.Strings(v.problems)
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(v.problems, "\n  "))
+	}
+	return nil
+}
+
+// changedFields returns the yaml paths whose values differ between two configs
+func changedFields(old, new *Config) []string {
+	values := make(map[string]string)
+	walkConfig(reflect.ValueOf(old).Elem(), "", func(path string, field reflect.Value) {
+		values[path] = fmt.Sprint(field.Interface())
+	})
+
+	var changed []string
+	walkConfig(reflect.ValueOf(new).Elem(), "", func(path string, field reflect.Value) {
+		if values[path] != fmt.Sprint(field.Interface()) {
+			changed = append(changed, path)
+		}
+	})
+	return changed
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog/log"
+)
+
+// FlareContractRegistryAddress is the ContractRegistry address, identical on
+// Flare, Songbird, Coston and Coston2
+const FlareContractRegistryAddress = "0xaD67FE66660Fb8dFE9d6b1b4240d8650e30F6019"
+
+// Flare system contract names as registered in the ContractRegistry
+const (
+	ContractFdcHub                      = "FdcHub"
+	ContractFdcRequestFeeConfigurations = "FdcRequestFeeConfigurations"
+	ContractRelay                       = "Relay"
+	ContractFdcVerification             = "FdcVerification"
+	ContractFlareSystemsManager         = "FlareSystemsManager"
+	ContractFtsoV2                      = "FtsoV2"
+)
+
+// systemContractNames lists every contract the resolver keeps up to date
+var systemContractNames = []string{
+	ContractFdcHub,
+	ContractFdcRequestFeeConfigurations,
+	ContractRelay,
+	ContractFdcVerification,
+	ContractFlareSystemsManager,
+	ContractFtsoV2,
+}
+
+// requiredSystemContracts must resolve to a non-zero address at startup
+var requiredSystemContracts = []string{
+	ContractFdcHub,
+	ContractFdcRequestFeeConfigurations,
+}
+
+const contractRegistryABI = `[{
+	"inputs": [{"name": "_name", "type": "string"}],
+	"name": "getContractAddressByName",
+	"outputs": [{"name": "", "type": "address"}],
+	"stateMutability": "view",
+	"type": "function"
+}]`
+
+// ContractResolver looks up Flare system contracts by name through the
+// on-chain ContractRegistry and caches the result. Flare replaces system
+// contracts on protocol upgrades, so the cache is refreshed periodically.
+type ContractResolver struct {
+	registry        *bind.BoundContract
+	registryAddr    common.Address
+	overrides       map[string]common.Address // Set explicitly in config.yaml
+	fallbacks       map[string]common.Address // From the network profile
+	refreshInterval time.Duration
+
+	mu       sync.RWMutex
+	resolved map[string]common.Address
+}
+
+// NewContractResolver creates a resolver for the configured network
+func NewContractResolver(client *ethclient.Client, config *Config) (*ContractResolver, error) {
+	parsed, err := abi.JSON(strings.NewReader(contractRegistryABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ContractRegistry ABI: %w", err)
+	}
+
+	registryAddr := common.HexToAddress(config.Flare.ContractRegistryAddress)
+
+	overrides := make(map[string]common.Address)
+	for name, addr := range map[string]string{
+		ContractFdcHub:                      config.FDC.FdcHubAddress,
+		ContractFdcRequestFeeConfigurations: config.FDC.FeeConfigAddress,
+		ContractRelay:                       config.FDC.RelayAddress,
+		ContractFdcVerification:             config.FDC.FdcVerificationAddress,
+		ContractFlareSystemsManager:         config.FDC.FlareSystemsManagerAddress,
+		ContractFtsoV2:                      config.Scoring.FtsoV2Address,
+	} {
+		if addr != "" {
+			overrides[name] = common.HexToAddress(addr)
+		}
+	}
+
+	fallbacks := make(map[string]common.Address)
+	if config.Profile.FdcHubAddress != "" {
+		fallbacks[ContractFdcHub] = common.HexToAddress(config.Profile.FdcHubAddress)
+	}
+	if config.Profile.FdcFeeConfigAddress != "" {
+		fallbacks[ContractFdcRequestFeeConfigurations] = common.HexToAddress(config.Profile.FdcFeeConfigAddress)
+	}
+
+	return &ContractResolver{
+		registry:        bind.NewBoundContract(registryAddr, parsed, client, client, client),
+		registryAddr:    registryAddr,
+		overrides:       overrides,
+		fallbacks:       fallbacks,
+		refreshInterval: time.Duration(config.Flare.RegistryRefreshInterval) * time.Second,
+		resolved:        make(map[string]common.Address),
+	}, nil
+}
+
+// Resolve looks up every system contract and updates the cache. A configured
+// override always wins, but is reported when the registry disagrees with it.
+func (r *ContractResolver) Resolve(ctx context.Context) error {
+	for _, name := range systemContractNames {
+		onChain, err := r.lookup(ctx, name)
+		if err != nil {
+			log.Warn().Err(err).Str("contract", name).Msg("ContractRegistry lookup failed")
+		}
+
+		addr := onChain
+		if override, ok := r.overrides[name]; ok {
+			if onChain != (common.Address{}) && onChain != override {
+				log.Warn().
+					Str("contract", name).
+					Str("registry_address", onChain.Hex()).
+					Str("override_address", override.Hex()).
+					Msg("Configured override differs from ContractRegistry address")
+			}
+			addr = override
+		} else if addr == (common.Address{}) {
+			if fallback, ok := r.fallbacks[name]; ok {
+				log.Warn().
+					Str("contract", name).
+					Str("fallback_address", fallback.Hex()).
+					Msg("Using network profile address, ContractRegistry did not resolve it")
+				addr = fallback
+			}
+		}
+
+		r.mu.Lock()
+		previous, known := r.resolved[name]
+		if addr == (common.Address{}) && known {
+			// Keep the last good address through transient RPC failures
+			addr = previous
+		}
+		r.resolved[name] = addr
+		r.mu.Unlock()
+
+		if known && previous != addr {
+			log.Warn().
+				Str("contract", name).
+				Str("old_address", previous.Hex()).
+				Str("new_address", addr.Hex()).
+				Msg("Flare system contract address changed")
+		} else if !known {
+			log.Info().
+				Str("contract", name).
+				Str("address", addr.Hex()).
+				Msg("Resolved Flare system contract")
+		}
+	}
+
+	for _, name := range requiredSystemContracts {
+		if r.Address(name) == (common.Address{}) {
+			return fmt.Errorf("could not resolve %s via ContractRegistry %s and no override is configured", name, r.registryAddr.Hex())
+		}
+	}
+
+	return nil
+}
+
+// lookup queries ContractRegistry.getContractAddressByName
+func (r *ContractResolver) lookup(ctx context.Context, name string) (common.Address, error) {
+	var result []interface{}
+	err := r.registry.Call(&bind.CallOpts{Context: ctx}, &result, "getContractAddressByName", name)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(result) == 0 {
+		return common.Address{}, fmt.Errorf("empty result")
+	}
+	addr, ok := result[0].(common.Address)
+	if !ok {
+		return common.Address{}, fmt.Errorf("unexpected result type %T", result[0])
+	}
+	return addr, nil
+}
+
+// Address returns the cached address for a system contract
+func (r *ContractResolver) Address(name string) common.Address {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolved[name]
+}
+
+// Watch re-resolves all system contracts until ctx is cancelled
+func (r *ContractResolver) Watch(ctx context.Context) {
+	if r.refreshInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Resolve(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to refresh Flare system contracts")
+			}
+		}
+	}
+}
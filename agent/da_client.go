@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrProofMismatch is returned when DA layer providers disagree on a proof
+var ErrProofMismatch = errors.New("DA layer providers returned different proofs")
+
+// errNoHealthyEndpoint is returned when every endpoint's circuit is open
+var errNoHealthyEndpoint = errors.New("no healthy endpoint available")
+
+// Circuit breaker defaults used when the config leaves them unset
+const (
+	defaultEndpointFailureThreshold = 3
+	defaultEndpointCooldown         = 60 * time.Second
+)
+
+// httpStatusError is a non-200 response from an endpoint
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Body)
+}
+
+// isEndpointFailure reports whether err reflects on the endpoint's health.
+// Client errors (e.g. proof not yet available) do not count against it.
+func isEndpointFailure(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// endpoint tracks the health of a single base URL
+type endpoint struct {
+	url                 string
+	index               int     // Position in config, used as tie-breaker
+	score               float64 // EWMA of successes, 1.0 = always healthy
+	consecutiveFailures int
+	openUntil           time.Time
+}
+
+// EndpointPool orders a set of equivalent endpoints by health and opens a
+// circuit on endpoints that keep failing
+type EndpointPool struct {
+	name             string
+	failureThreshold int
+	cooldown         time.Duration
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+}
+
+// NewEndpointPool creates a pool from a list of base URLs. Duplicates and
+// empty entries are dropped.
+func NewEndpointPool(name string, urls []string, failureThreshold int, cooldown time.Duration) *EndpointPool {
+	if failureThreshold <= 0 {
+		failureThreshold = defaultEndpointFailureThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultEndpointCooldown
+	}
+
+	pool := &EndpointPool{
+		name:             name,
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+	}
+
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		u = strings.TrimSuffix(strings.TrimSpace(u), "/")
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		pool.endpoints = append(pool.endpoints, &endpoint{url: u, index: len(pool.endpoints), score: 1})
+	}
+
+	return pool
+}
+
+// Len returns the number of endpoints in the pool
+func (p *EndpointPool) Len() int {
+	return len(p.endpoints)
+}
+
+// candidates returns endpoints whose circuit is closed or half-open, best
+// score first
+func (p *EndpointPool) candidates() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	out := make([]*endpoint, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		if now.Before(ep.openUntil) {
+			continue
+		}
+		out = append(out, ep)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].score != out[j].score {
+			return out[i].score > out[j].score
+		}
+		return out[i].index < out[j].index
+	})
+	return out
+}
+
+// report records the outcome of a request against an endpoint
+func (p *EndpointPool) report(ep *endpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil || !isEndpointFailure(err) {
+		ep.score = 0.8*ep.score + 0.2
+		ep.consecutiveFailures = 0
+		ep.openUntil = time.Time{}
+		return
+	}
+
+	ep.score = 0.8 * ep.score
+	ep.consecutiveFailures++
+	if ep.consecutiveFailures >= p.failureThreshold {
+		ep.openUntil = time.Now().Add(p.cooldown)
+		log.Warn().
+			Str("pool", p.name).
+			Str("endpoint", ep.url).
+			Int("consecutive_failures", ep.consecutiveFailures).
+			Dur("cooldown", p.cooldown).
+			Msg("Endpoint circuit opened")
+	}
+}
+
+// Do runs fn against the healthiest endpoint and fails over to the next one
+// on error. Returns the last error if every endpoint fails.
+func (p *EndpointPool) Do(ctx context.Context, fn func(ctx context.Context, baseURL string) error) error {
+	candidates := p.candidates()
+	if len(candidates) == 0 {
+		return fmt.Errorf("%s: %w", p.name, errNoHealthyEndpoint)
+	}
+
+	var lastErr error
+	for _, ep := range candidates {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		err := fn(ctx, ep.url)
+		p.report(ep, err)
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+		log.Debug().
+			Err(err).
+			Str("pool", p.name).
+			Str("endpoint", ep.url).
+			Msg("Endpoint request failed, trying next")
+	}
+
+	return fmt.Errorf("%s: all endpoints failed: %w", p.name, lastErr)
+}
+
+// DAClient talks to the FDC verifiers and DA layer providers with failover,
+// and optionally requires several DA providers to agree on each proof
+type DAClient struct {
+	verifiers      *EndpointPool
+	daLayers       *EndpointPool
+	apiKey         string
+	proofAgreement int // Number of DA providers that must return identical proofs
+	httpClient     *http.Client
+}
+
+// NewDAClient creates a DA client from the FDC config
+func NewDAClient(config *Config) *DAClient {
+	cooldown := time.Duration(config.FDC.EndpointCooldown) * time.Second
+
+	verifierURLs := append([]string{config.FDC.VerifierURL}, config.FDC.VerifierURLs...)
+	daURLs := append([]string{config.FDC.DALayerURL}, config.FDC.DALayerURLs...)
+
+	return &DAClient{
+		verifiers:      NewEndpointPool("fdc-verifier", verifierURLs, config.FDC.EndpointFailureThreshold, cooldown),
+		daLayers:       NewEndpointPool("da-layer", daURLs, config.FDC.EndpointFailureThreshold, cooldown),
+		apiKey:         config.FDC.APIKey,
+		proofAgreement: config.FDC.ProofAgreement,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do performs a single HTTP request and returns the body of a 200 response
+func (c *DAClient) do(ctx context.Context, method, url string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-KEY", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return respBody, nil
+}
+
+// PostVerifier POSTs a JSON body to a verifier path with failover
+func (c *DAClient) PostVerifier(ctx context.Context, path string, request interface{}) ([]byte, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	err = c.verifiers.Do(ctx, func(ctx context.Context, baseURL string) error {
+		resp, err := c.do(ctx, http.MethodPost, baseURL+path, body)
+		if err != nil {
+			return err
+		}
+		out = resp
+		return nil
+	})
+	return out, err
+}
+
+// GetDALayer GETs a DA layer path with failover
+func (c *DAClient) GetDALayer(ctx context.Context, path string) ([]byte, error) {
+	var out []byte
+	err := c.daLayers.Do(ctx, func(ctx context.Context, baseURL string) error {
+		resp, err := c.do(ctx, http.MethodGet, baseURL+path, nil)
+		if err != nil {
+			return err
+		}
+		out = resp
+		return nil
+	})
+	return out, err
+}
+
+// PostDALayerProof POSTs a proof request to the DA layer. With agreement
+// disabled it fails over between providers; otherwise it keeps querying
+// providers until proofAgreement of them return byte-identical bodies.
+func (c *DAClient) PostDALayerProof(ctx context.Context, path string, request interface{}) ([]byte, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.proofAgreement <= 1 {
+		var out []byte
+		err = c.daLayers.Do(ctx, func(ctx context.Context, baseURL string) error {
+			resp, err := c.do(ctx, http.MethodPost, baseURL+path, body)
+			if err != nil {
+				return err
+			}
+			out = resp
+			return nil
+		})
+		return out, err
+	}
+
+	candidates := c.daLayers.candidates()
+	if len(candidates) < c.proofAgreement {
+		return nil, fmt.Errorf("proof agreement needs %d DA providers, only %d healthy: %w", c.proofAgreement, len(candidates), errNoHealthyEndpoint)
+	}
+
+	var (
+		agreed    []byte
+		matches   int
+		lastErr   error
+		providers []string
+	)
+	for _, ep := range candidates {
+		if matches >= c.proofAgreement {
+			break
+		}
+
+		resp, err := c.do(ctx, http.MethodPost, ep.url+path, body)
+		c.daLayers.report(ep, err)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		resp = bytes.TrimSpace(resp)
+		if agreed == nil {
+			agreed = resp
+		} else if !bytes.Equal(agreed, resp) {
+			log.Error().
+				Strs("agreeing_providers", providers).
+				Str("disagreeing_provider", ep.url).
+				Msg("DA layer proof mismatch")
+			return nil, fmt.Errorf("%w: %s disagrees with %s", ErrProofMismatch, ep.url, strings.Join(providers, ", "))
+		}
+		matches++
+		providers = append(providers, ep.url)
+	}
+
+	if matches < c.proofAgreement {
+		return nil, fmt.Errorf("only %d of %d required DA providers returned a proof: %v", matches, c.proofAgreement, lastErr)
+	}
+
+	return agreed, nil
+}
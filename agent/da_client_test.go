@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDAServer is a local DA layer or verifier that answers every request
+// with status and body, and counts the requests it served
+type fakeDAServer struct {
+	*httptest.Server
+	status atomic.Int32
+	body   atomic.Value
+	hits   atomic.Int32
+}
+
+func newFakeDAServer(t *testing.T, status int, body string) *fakeDAServer {
+	t.Helper()
+	s := &fakeDAServer{}
+	s.status.Store(int32(status))
+	s.body.Store(body)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		w.WriteHeader(int(s.status.Load()))
+		fmt.Fprint(w, s.body.Load().(string))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestDAClient(daURLs []string, proofAgreement int) *DAClient {
+	return &DAClient{
+		verifiers:      NewEndpointPool("fdc-verifier", nil, 0, 0),
+		daLayers:       NewEndpointPool("da-layer", daURLs, 2, 50*time.Millisecond),
+		proofAgreement: proofAgreement,
+		httpClient:     &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func TestDAClientFailsOverToNextEndpoint(t *testing.T) {
+	down := newFakeDAServer(t, http.StatusBadGateway, "down")
+	up := newFakeDAServer(t, http.StatusOK, `{"proof":"ok"}`)
+	client := newTestDAClient([]string{down.URL, up.URL}, 1)
+
+	body, err := client.GetDALayer(context.Background(), "/api/v1/fdc/proof")
+	if err != nil {
+		t.Fatalf("GetDALayer: %v", err)
+	}
+	if string(body) != `{"proof":"ok"}` {
+		t.Fatalf("body = %q", body)
+	}
+	if down.hits.Load() != 1 || up.hits.Load() != 1 {
+		t.Fatalf("hits = %d, %d; want 1, 1", down.hits.Load(), up.hits.Load())
+	}
+
+	// The failed endpoint drops behind the healthy one
+	if _, err := client.GetDALayer(context.Background(), "/"); err != nil {
+		t.Fatalf("GetDALayer: %v", err)
+	}
+	if down.hits.Load() != 1 || up.hits.Load() != 2 {
+		t.Fatalf("hits = %d, %d; want 1, 2", down.hits.Load(), up.hits.Load())
+	}
+}
+
+func TestDAClientClientErrorsDoNotFailOver(t *testing.T) {
+	pending := newFakeDAServer(t, http.StatusBadRequest, "not ready")
+	other := newFakeDAServer(t, http.StatusOK, "ok")
+	client := newTestDAClient([]string{pending.URL, other.URL}, 1)
+
+	// Do still tries the next endpoint, but a 4xx leaves the score alone
+	if _, err := client.GetDALayer(context.Background(), "/"); err != nil {
+		t.Fatalf("GetDALayer: %v", err)
+	}
+	if got := client.daLayers.candidates(); got[0].url != pending.URL || got[0].score != 1 {
+		t.Fatalf("first candidate = %s (score %v), want %s with score 1", got[0].url, got[0].score, pending.URL)
+	}
+}
+
+func TestEndpointPoolCircuitOpensAndHalfOpens(t *testing.T) {
+	server := newFakeDAServer(t, http.StatusInternalServerError, "boom")
+	client := newTestDAClient([]string{server.URL}, 1)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetDALayer(context.Background(), "/"); err == nil {
+			t.Fatal("GetDALayer succeeded against a failing server")
+		}
+	}
+
+	// Open: requests fail fast without reaching the server
+	_, err := client.GetDALayer(context.Background(), "/")
+	if !errors.Is(err, errNoHealthyEndpoint) {
+		t.Fatalf("err = %v, want errNoHealthyEndpoint", err)
+	}
+	if server.hits.Load() != 2 {
+		t.Fatalf("hits = %d while open, want 2", server.hits.Load())
+	}
+
+	// Half-open after the cooldown: one trial request goes through
+	time.Sleep(60 * time.Millisecond)
+	server.status.Store(http.StatusOK)
+	if _, err := client.GetDALayer(context.Background(), "/"); err != nil {
+		t.Fatalf("GetDALayer after cooldown: %v", err)
+	}
+	if server.hits.Load() != 3 {
+		t.Fatalf("hits = %d after cooldown, want 3", server.hits.Load())
+	}
+
+	// The success closed the circuit: a single failure does not reopen it
+	server.status.Store(http.StatusInternalServerError)
+	client.GetDALayer(context.Background(), "/")
+	if len(client.daLayers.candidates()) != 1 {
+		t.Fatal("circuit reopened after one failure")
+	}
+}
+
+func TestEndpointPoolHalfOpenFailureReopens(t *testing.T) {
+	pool := NewEndpointPool("test", []string{"http://a"}, 2, 50*time.Millisecond)
+	ep := pool.endpoints[0]
+	boom := &httpStatusError{StatusCode: http.StatusServiceUnavailable}
+
+	pool.report(ep, boom)
+	pool.report(ep, boom)
+	if len(pool.candidates()) != 0 {
+		t.Fatal("circuit did not open")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if len(pool.candidates()) != 1 {
+		t.Fatal("circuit did not half-open after the cooldown")
+	}
+	pool.report(ep, boom)
+	if len(pool.candidates()) != 0 {
+		t.Fatal("failed trial request did not reopen the circuit")
+	}
+}
+
+func TestEndpointPoolOrdersByEWMA(t *testing.T) {
+	pool := NewEndpointPool("test", []string{"http://a", "http://b", "http://c"}, 10, time.Minute)
+	a, b, c := pool.endpoints[0], pool.endpoints[1], pool.endpoints[2]
+	boom := &httpStatusError{StatusCode: http.StatusInternalServerError}
+
+	order := func() string {
+		var s string
+		for _, ep := range pool.candidates() {
+			s += ep.url[len("http://"):]
+		}
+		return s
+	}
+
+	if got := order(); got != "abc" {
+		t.Fatalf("initial order = %s, want config order abc", got)
+	}
+
+	pool.report(a, boom)
+	pool.report(a, boom)
+	pool.report(b, boom)
+	if got := order(); got != "cba" {
+		t.Fatalf("order after failures = %s, want cba", got)
+	}
+
+	// Successes pull a back up past b, but not past the never-failed c
+	pool.report(a, nil)
+	pool.report(a, nil)
+	pool.report(a, nil)
+	if got := order(); got != "cab" {
+		t.Fatalf("order after recovery = %s, want cab (scores a=%.3f b=%.3f c=%.3f)", got, a.score, b.score, c.score)
+	}
+}
+
+func TestDAClientProofAgreement(t *testing.T) {
+	first := newFakeDAServer(t, http.StatusOK, `{"proof":["0x01"]}`)
+	second := newFakeDAServer(t, http.StatusOK, "{\"proof\":[\"0x01\"]}\n")
+	third := newFakeDAServer(t, http.StatusOK, `{"proof":["0x01"]}`)
+	client := newTestDAClient([]string{first.URL, second.URL, third.URL}, 2)
+
+	body, err := client.PostDALayerProof(context.Background(), "/proof", map[string]int{"round": 1})
+	if err != nil {
+		t.Fatalf("PostDALayerProof: %v", err)
+	}
+	if string(body) != `{"proof":["0x01"]}` {
+		t.Fatalf("body = %q", body)
+	}
+	if third.hits.Load() != 0 {
+		t.Fatal("queried a third provider after two agreed")
+	}
+}
+
+func TestDAClientProofAgreementMismatch(t *testing.T) {
+	honest := newFakeDAServer(t, http.StatusOK, `{"proof":["0x01"]}`)
+	forged := newFakeDAServer(t, http.StatusOK, `{"proof":["0x02"]}`)
+	client := newTestDAClient([]string{honest.URL, forged.URL}, 2)
+
+	_, err := client.PostDALayerProof(context.Background(), "/proof", map[string]int{"round": 1})
+	if !errors.Is(err, ErrProofMismatch) {
+		t.Fatalf("err = %v, want ErrProofMismatch", err)
+	}
+}
+
+func TestDAClientProofAgreementNeedsEnoughProviders(t *testing.T) {
+	up := newFakeDAServer(t, http.StatusOK, `{"proof":["0x01"]}`)
+	down := newFakeDAServer(t, http.StatusInternalServerError, "boom")
+	client := newTestDAClient([]string{up.URL, down.URL}, 2)
+
+	_, err := client.PostDALayerProof(context.Background(), "/proof", nil)
+	if err == nil || errors.Is(err, ErrProofMismatch) {
+		t.Fatalf("err = %v, want a shortfall error", err)
+	}
+}
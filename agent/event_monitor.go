@@ -5,7 +5,9 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -69,11 +71,30 @@ type EventMonitor struct {
 	client       *ethclient.Client
 	flipCore     common.Address
 	lastBlock    uint64
-	pollInterval time.Duration
+	settings     *LiveSettings
+
+	pollMu sync.Mutex
+	polls  map[string]time.Time // Last successful poll per cursor
+	lags   map[string]uint64    // Blocks behind head at the last poll, per cursor
 }
 
+// FLIPCore events the agent acts on. Each has the request ID as its first
+// indexed topic.
+const (
+	escrowCreatedSignature       = "EscrowCreated(uint256,address,uint256,uint256,uint256)"
+	redemptionRequestedSignature = "RedemptionRequested(uint256,address,address,uint256,string,uint256)"
+	mintingRequestedSignature    = "MintingRequested(uint256,address,address,uint256,string,uint256,uint256,uint256)"
+)
+
+// Event cursors, one per monitor loop
+const (
+	CursorEscrowCreated       = "escrow_created"
+	CursorRedemptionRequested = "redemption_requested"
+	CursorMintingRequested    = "minting_requested"
+)
+
 // NewEventMonitor creates a new event monitor
-func NewEventMonitor(config *Config) (*EventMonitor, error) {
+func NewEventMonitor(config *Config, settings *LiveSettings) (*EventMonitor, error) {
 	client, err := ethclient.Dial(config.Flare.RPCURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Flare RPC: %w", err)
@@ -96,15 +117,137 @@ func NewEventMonitor(config *Config) (*EventMonitor, error) {
 		client:       client,
 		flipCore:     common.HexToAddress(config.Flare.FLIPCoreAddress),
 		lastBlock:    startBlock,
-		pollInterval: time.Duration(config.Agent.PollingInterval) * time.Second,
+		settings:     settings,
+		polls:        make(map[string]time.Time),
+		lags:         make(map[string]uint64),
 	}, nil
 }
 
+// observePoll records a successful poll and the cursor's lag behind head
+func (em *EventMonitor) observePoll(cursor string, head uint64) {
+	var lag uint64
+	if head > em.lastBlock {
+		lag = head - em.lastBlock
+	}
+
+	now := time.Now()
+	em.pollMu.Lock()
+	em.polls[cursor] = now
+	em.lags[cursor] = lag
+	em.pollMu.Unlock()
+
+	blockLag.WithLabelValues(cursor).Set(float64(lag))
+	lastPoll.WithLabelValues(cursor).Set(float64(now.Unix()))
+}
+
+// startCursor registers a monitor loop so StalledCursors watches it from now
+func (em *EventMonitor) startCursor(cursor string) {
+	em.pollMu.Lock()
+	em.polls[cursor] = time.Now()
+	em.pollMu.Unlock()
+}
+
+// StalledCursors returns the running cursors that have not polled
+// successfully within threshold
+func (em *EventMonitor) StalledCursors(threshold time.Duration) []string {
+	em.pollMu.Lock()
+	defer em.pollMu.Unlock()
+
+	var stalled []string
+	for cursor, last := range em.polls {
+		if time.Since(last) > threshold {
+			stalled = append(stalled, cursor)
+		}
+	}
+	sort.Strings(stalled)
+	return stalled
+}
+
+// MaxBlockLag returns the cursor furthest behind head at its last poll and
+// by how many blocks
+func (em *EventMonitor) MaxBlockLag() (string, uint64) {
+	em.pollMu.Lock()
+	defer em.pollMu.Unlock()
+
+	var cursor string
+	var max uint64
+	for c, lag := range em.lags {
+		if lag > max || cursor == "" {
+			cursor, max = c, lag
+		}
+	}
+	return cursor, max
+}
+
+// HistoricalEvent is a FLIPCore event found by EventsInRange
+type HistoricalEvent struct {
+	Block    uint64
+	TxHash   common.Hash
+	Name     string // EscrowCreated, RedemptionRequested or MintingRequested
+	Workflow string // WorkflowRedemption or WorkflowMinting
+	ID       uint64
+}
+
+// EventsInRange returns the events the agent acts on between from and to
+// (inclusive), oldest first, querying in the same block ranges as the monitor
+// loops
+func (em *EventMonitor) EventsInRange(ctx context.Context, from, to uint64) ([]HistoricalEvent, error) {
+	const maxBlockRange uint64 = 29
+
+	topics := make(map[common.Hash]HistoricalEvent)
+	for signature, e := range map[string]HistoricalEvent{
+		escrowCreatedSignature:       {Name: "EscrowCreated", Workflow: WorkflowRedemption},
+		redemptionRequestedSignature: {Name: "RedemptionRequested", Workflow: WorkflowRedemption},
+		mintingRequestedSignature:    {Name: "MintingRequested", Workflow: WorkflowMinting},
+	} {
+		topics[crypto.Keccak256Hash([]byte(signature))] = e
+	}
+	var topicList []common.Hash
+	for topic := range topics {
+		topicList = append(topicList, topic)
+	}
+
+	var events []HistoricalEvent
+	for start := from; start <= to; start += maxBlockRange + 1 {
+		end := min(start+maxBlockRange, to)
+		logs, err := em.client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{em.flipCore},
+			Topics:    [][]common.Hash{topicList},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter logs %d-%d: %w", start, end, err)
+		}
+
+		// FilterLogs returns logs in block and index order
+		for _, vLog := range logs {
+			if len(vLog.Topics) < 2 {
+				continue
+			}
+			e := topics[vLog.Topics[0]]
+			e.Block = vLog.BlockNumber
+			e.TxHash = vLog.TxHash
+			e.ID = new(big.Int).SetBytes(vLog.Topics[1].Bytes()).Uint64()
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// followPollInterval resets ticker if the polling interval was reloaded
+func (em *EventMonitor) followPollInterval(ticker *time.Ticker, current *time.Duration) {
+	if interval := em.settings.PollInterval(); interval != *current {
+		ticker.Reset(interval)
+		*current = interval
+	}
+}
+
 // Monitor monitors for EscrowCreated events
 func (em *EventMonitor) Monitor(ctx context.Context, eventChan chan<- EscrowCreatedEvent) error {
 	// EscrowCreated event signature
 	// event EscrowCreated(uint256 indexed redemptionId, address indexed user, uint256 receiptId, uint256 amount, uint256 timestamp)
-	eventSignature := []byte("EscrowCreated(uint256,address,uint256,uint256,uint256)")
+	eventSignature := []byte(escrowCreatedSignature)
 	eventTopic := common.BytesToHash(crypto.Keccak256(eventSignature))
 
 	log.Info().
@@ -112,7 +255,9 @@ func (em *EventMonitor) Monitor(ctx context.Context, eventChan chan<- EscrowCrea
 		Str("flip_core", em.flipCore.Hex()).
 		Msg("Starting event monitoring")
 
-	ticker := time.NewTicker(em.pollInterval)
+	em.startCursor(CursorEscrowCreated)
+	interval := em.settings.PollInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -120,12 +265,14 @@ func (em *EventMonitor) Monitor(ctx context.Context, eventChan chan<- EscrowCrea
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
+			em.followPollInterval(ticker, &interval)
 			// Get current block
 			currentBlock, err := em.client.BlockNumber(ctx)
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to get block number")
 				continue
 			}
+			em.observePoll(CursorEscrowCreated, currentBlock)
 
 			if currentBlock <= em.lastBlock {
 				continue
@@ -183,7 +330,7 @@ func (em *EventMonitor) Monitor(ctx context.Context, eventChan chan<- EscrowCrea
 func (em *EventMonitor) MonitorRedemptionRequests(ctx context.Context, eventChan chan<- RedemptionRequestedEvent) error {
 	// RedemptionRequested event signature
 	// event RedemptionRequested(uint256 indexed redemptionId, address indexed user, address indexed asset, uint256 amount, string xrplAddress, uint256 timestamp)
-	eventSignature := []byte("RedemptionRequested(uint256,address,address,uint256,string,uint256)")
+	eventSignature := []byte(redemptionRequestedSignature)
 	eventTopic := common.BytesToHash(crypto.Keccak256(eventSignature))
 
 	log.Info().
@@ -191,7 +338,9 @@ func (em *EventMonitor) MonitorRedemptionRequests(ctx context.Context, eventChan
 		Str("flip_core", em.flipCore.Hex()).
 		Msg("Starting RedemptionRequested event monitoring")
 
-	ticker := time.NewTicker(em.pollInterval)
+	em.startCursor(CursorRedemptionRequested)
+	interval := em.settings.PollInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -199,12 +348,14 @@ func (em *EventMonitor) MonitorRedemptionRequests(ctx context.Context, eventChan
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
+			em.followPollInterval(ticker, &interval)
 			// Get current block
 			currentBlock, err := em.client.BlockNumber(ctx)
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to get block number")
 				continue
 			}
+			em.observePoll(CursorRedemptionRequested, currentBlock)
 
 			if currentBlock <= em.lastBlock {
 				continue
@@ -429,7 +580,7 @@ func generatePaymentReference(redemptionID *big.Int) string {
 func (em *EventMonitor) MonitorMintingRequests(ctx context.Context, eventChan chan<- MintingRequestedEvent) error {
 	// MintingRequested event signature
 	// event MintingRequested(uint256 indexed mintingId, address indexed user, address indexed asset, uint256 collateralReservationId, string xrplTxHash, uint256 xrpAmount, uint256 fxrpAmount, uint256 timestamp)
-	eventSignature := []byte("MintingRequested(uint256,address,address,uint256,string,uint256,uint256,uint256)")
+	eventSignature := []byte(mintingRequestedSignature)
 	eventTopic := common.BytesToHash(crypto.Keccak256(eventSignature))
 
 	log.Info().
@@ -437,7 +588,9 @@ func (em *EventMonitor) MonitorMintingRequests(ctx context.Context, eventChan ch
 		Str("flip_core", em.flipCore.Hex()).
 		Msg("Starting MintingRequested event monitoring")
 
-	ticker := time.NewTicker(em.pollInterval)
+	em.startCursor(CursorMintingRequested)
+	interval := em.settings.PollInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -445,11 +598,13 @@ func (em *EventMonitor) MonitorMintingRequests(ctx context.Context, eventChan ch
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
+			em.followPollInterval(ticker, &interval)
 			currentBlock, err := em.client.BlockNumber(ctx)
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to get block number")
 				continue
 			}
+			em.observePoll(CursorMintingRequested, currentBlock)
 
 			if currentBlock <= em.lastBlock {
 				continue
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/flip-protocol/agent/bindings"
+	"github.com/rs/zerolog/log"
+)
+
+// fassetsABI covers the FAsset token and AssetManager calls used to read a
+// collateral reservation
+const fassetsABI = `[
+	{"inputs":[],"name":"assetManager","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"name":"_collateralReservationId","type":"uint256"}],"name":"collateralReservationInfo","outputs":[{"components":[
+		{"name":"minter","type":"address"},
+		{"name":"agentVault","type":"address"},
+		{"name":"lots","type":"uint256"},
+		{"name":"valueUBA","type":"uint256"},
+		{"name":"feeUBA","type":"uint256"},
+		{"name":"lastUnderlyingBlock","type":"uint256"},
+		{"name":"lastUnderlyingTimestamp","type":"uint256"},
+		{"name":"paymentReference","type":"bytes32"}
+	],"name":"_info","type":"tuple"}],"stateMutability":"view","type":"function"}
+]`
+
+// MintingAttestationRequest identifies the XRPL deposit behind a minting
+type MintingAttestationRequest struct {
+	MintingID               *big.Int
+	Asset                   common.Address
+	CollateralReservationID *big.Int
+	XrplTxHash              string
+	XrpAmount               *big.Int // Drops
+	FxrpAmount              *big.Int // Used for routing only
+}
+
+// CollateralReservation is the FAssets reservation a minting deposit pays for
+type CollateralReservation struct {
+	Minter           common.Address
+	AgentVault       common.Address
+	ValueUBA         *big.Int
+	FeeUBA           *big.Int
+	PaymentReference common.Hash
+	PaymentAddress   string // XRPL address of the agent vault, "" if not configured
+}
+
+// collateralReservationInfo mirrors the AssetManager.collateralReservationInfo tuple
+type collateralReservationInfo struct {
+	Minter                  common.Address
+	AgentVault              common.Address
+	Lots                    *big.Int
+	ValueUBA                *big.Int
+	FeeUBA                  *big.Int
+	LastUnderlyingBlock     *big.Int
+	LastUnderlyingTimestamp *big.Int
+	PaymentReference        [32]byte
+}
+
+// PaymentAttestation is the response body of an FDC Payment proof
+type PaymentAttestation struct {
+	TransactionID            string
+	BlockNumber              uint64
+	ReceivingAddressHash     common.Hash
+	StandardPaymentReference common.Hash
+	ReceivedAmount           *big.Int
+	Status                   uint8 // 0=SUCCESS, 1=SENDER_FAILURE, 2=RECEIVER_FAILURE
+}
+
+// readCollateralReservation looks up a reservation on the asset's AssetManager
+func (fs *FDCSubmitter) readCollateralReservation(ctx context.Context, asset common.Address, reservationID *big.Int) (*CollateralReservation, error) {
+	parsed, err := abi.JSON(strings.NewReader(fassetsABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FAssets ABI: %w", err)
+	}
+
+	token := bind.NewBoundContract(asset, parsed, fs.client, fs.client, fs.client)
+	var managerResult []interface{}
+	if err := token.Call(&bind.CallOpts{Context: ctx}, &managerResult, "assetManager"); err != nil {
+		return nil, fmt.Errorf("failed to get AssetManager for %s: %w", asset.Hex(), err)
+	}
+	assetManager := managerResult[0].(common.Address)
+
+	manager := bind.NewBoundContract(assetManager, parsed, fs.client, fs.client, fs.client)
+	var infoResult []interface{}
+	if err := manager.Call(&bind.CallOpts{Context: ctx}, &infoResult, "collateralReservationInfo", reservationID); err != nil {
+		return nil, fmt.Errorf("failed to get collateral reservation %s: %w", reservationID, err)
+	}
+
+	info := abi.ConvertType(infoResult[0], new(collateralReservationInfo)).(*collateralReservationInfo)
+
+	return &CollateralReservation{
+		Minter:           info.Minter,
+		AgentVault:       info.AgentVault,
+		ValueUBA:         info.ValueUBA,
+		FeeUBA:           info.FeeUBA,
+		PaymentReference: info.PaymentReference,
+		PaymentAddress:   fs.depositAddressFor(info.AgentVault),
+	}, nil
+}
+
+// depositAddressFor returns the XRPL address deposits for an agent vault must
+// be paid to
+func (fs *FDCSubmitter) depositAddressFor(agentVault common.Address) string {
+	if addr, ok := fs.depositAddresses[agentVault]; ok {
+		return addr
+	}
+	return fs.defaultDepositAddress
+}
+
+// decodePaymentAttestation extracts the response body of a Payment proof
+func decodePaymentAttestation(proof *FDCProof) (*PaymentAttestation, error) {
+	raw, err := json.Marshal(proof.Response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode proof response: %w", err)
+	}
+
+	var response struct {
+		RequestBody struct {
+			TransactionID string `json:"transactionId"`
+		} `json:"requestBody"`
+		ResponseBody struct {
+			BlockNumber              json.Number `json:"blockNumber"`
+			ReceivingAddressHash     string      `json:"receivingAddressHash"`
+			StandardPaymentReference string      `json:"standardPaymentReference"`
+			ReceivedAmount           json.Number `json:"receivedAmount"`
+			Status                   json.Number `json:"status"`
+		} `json:"responseBody"`
+	}
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode Payment response: %w", err)
+	}
+
+	body := response.ResponseBody
+	received, ok := new(big.Int).SetString(body.ReceivedAmount.String(), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid receivedAmount %q", body.ReceivedAmount)
+	}
+	status, err := body.Status.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid status %q", body.Status)
+	}
+	blockNumber, _ := body.BlockNumber.Int64()
+
+	return &PaymentAttestation{
+		TransactionID:            response.RequestBody.TransactionID,
+		BlockNumber:              uint64(blockNumber),
+		ReceivingAddressHash:     common.HexToHash(body.ReceivingAddressHash),
+		StandardPaymentReference: common.HexToHash(body.StandardPaymentReference),
+		ReceivedAmount:           received,
+		Status:                   uint8(status),
+	}, nil
+}
+
+// checkMintingPayment compares an attested payment against the minting and
+// its collateral reservation. Returns the reason the payment does not back
+// the minting, or "" if it does.
+func checkMintingPayment(payment *PaymentAttestation, req MintingAttestationRequest, reservation *CollateralReservation) string {
+	if payment.Status != 0 {
+		return fmt.Sprintf("payment status %d is not SUCCESS", payment.Status)
+	}
+	if payment.ReceivedAmount.Cmp(req.XrpAmount) < 0 {
+		return fmt.Sprintf("received %s drops, minting requires %s", payment.ReceivedAmount, req.XrpAmount)
+	}
+
+	required := new(big.Int).Add(reservation.ValueUBA, reservation.FeeUBA)
+	if payment.ReceivedAmount.Cmp(required) < 0 {
+		return fmt.Sprintf("received %s drops, reservation requires %s", payment.ReceivedAmount, required)
+	}
+	if reservation.PaymentAddress != "" && payment.ReceivingAddressHash != crypto.Keccak256Hash([]byte(reservation.PaymentAddress)) {
+		return fmt.Sprintf("payment was not received by agent vault address %s", reservation.PaymentAddress)
+	}
+	if payment.StandardPaymentReference != reservation.PaymentReference {
+		return fmt.Sprintf("payment reference %s does not match reservation %s", payment.StandardPaymentReference.Hex(), reservation.PaymentReference.Hex())
+	}
+
+	return ""
+}
+
+// FinalizeMinting proves the user's XRPL deposit through FDC, checks it
+// against the minting and its collateral reservation, and reports the outcome
+// to FLIPCore. Errors leave the minting untouched so it can be retried.
+func (fs *FDCSubmitter) FinalizeMinting(ctx context.Context, req MintingAttestationRequest) error {
+	// FLIPCore releases the LP's minting escrow on attestation; without one
+	// the call reverts, so don't pay the FDC fee
+	open, err := fs.mintingEscrowOpen(ctx, req.MintingID)
+	if err != nil {
+		return err
+	}
+	if !open {
+		return fmt.Errorf("minting %s has no open escrow to release", req.MintingID)
+	}
+
+	reservation, err := fs.readCollateralReservation(ctx, req.Asset, req.CollateralReservationID)
+	if err != nil {
+		return err
+	}
+
+	proof, err := fs.GetFDCProof(ctx, req.XrplTxHash)
+	if err != nil {
+		var rejected *verifierRejectedError
+		if errors.As(err, &rejected) && rejected.Status == "INVALID" {
+			// The deposit is not a valid XRPL payment, there is nothing to prove
+			log.Warn().
+				Uint64("minting_id", req.MintingID.Uint64()).
+				Str("xrpl_tx", req.XrplTxHash).
+				Msg("Verifier rejected minting deposit, failing minting")
+			return fs.handleMintingFDCAttestation(ctx, req.MintingID, big.NewInt(0), false)
+		}
+		return fmt.Errorf("failed to get FDC proof: %w", err)
+	}
+
+	payment, err := decodePaymentAttestation(proof)
+	if err != nil {
+		return err
+	}
+
+	success := true
+	if reason := checkMintingPayment(payment, req, reservation); reason != "" {
+		success = false
+		log.Warn().
+			Uint64("minting_id", req.MintingID.Uint64()).
+			Str("xrpl_tx", req.XrplTxHash).
+			Str("reason", reason).
+			Msg("Attested deposit does not back minting")
+	}
+
+	return fs.handleMintingFDCAttestation(ctx, req.MintingID, new(big.Int).SetUint64(proof.RoundID), success)
+}
+
+// mintingEscrowOpen reports whether EscrowVault holds a Created minting escrow
+func (fs *FDCSubmitter) mintingEscrowOpen(ctx context.Context, mintingID *big.Int) (bool, error) {
+	vault, err := bindings.NewEscrowVaultCaller(fs.escrowVault, fs.client)
+	if err != nil {
+		return false, fmt.Errorf("failed to bind EscrowVault: %w", err)
+	}
+
+	status, err := vault.GetMintingEscrowStatus(&bind.CallOpts{Context: ctx}, mintingID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get minting escrow status: %w", err)
+	}
+
+	return status == 1, nil // 1 = Created
+}
+
+// handleMintingFDCAttestation reports the FDC outcome for a minting to FLIPCore
+func (fs *FDCSubmitter) handleMintingFDCAttestation(ctx context.Context, mintingID, requestID *big.Int, success bool) error {
+	// Releases the minting escrow and settles the LP and price hedge
+	tx, err := fs.transactFLIPCore(ctx, "handleMintingFDCAttestation", 500000, mintingID, requestID, success)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("tx_hash", tx.Hash().Hex()).
+		Uint64("minting_id", mintingID.Uint64()).
+		Bool("success", success).
+		Msg("Minting FDC attestation finalized on FLIPCore")
+
+	return nil
+}
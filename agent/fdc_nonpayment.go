@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrPaymentExists is returned when the verifier finds a payment matching a
+// nonexistence query, i.e. the payment was in fact made
+var ErrPaymentExists = errors.New("referenced payment exists on XRPL")
+
+// Ledger window parameters for nonexistence proofs
+const (
+	// nonPaymentDeadlineLag keeps the deadline ledger behind the validated tip
+	// so the verifier can find the first ledger after the deadline
+	nonPaymentDeadlineLag = 10
+	// minLedgerCloseSeconds underestimates ledger close time so the window
+	// start lands at or before the redemption request
+	minLedgerCloseSeconds = 3
+)
+
+// NonPaymentQuery describes a payment whose absence on XRPL should be proven
+type NonPaymentQuery struct {
+	Destination       string   // XRPL destination address
+	AmountDrops       *big.Int // Minimum amount that would count as payment
+	PaymentReference  string   // 32-byte standard payment reference (hex)
+	MinimalLedger     uint64   // First ledger of the search window
+	DeadlineLedger    uint64   // Last ledger of the search window
+	DeadlineTimestamp uint64   // Unix close time of the deadline ledger
+}
+
+// GetNonPaymentProof obtains a ReferencedPaymentNonexistence proof that no
+// payment with the reference of at least the amount reached the destination
+// within the ledger window
+func (fs *FDCSubmitter) GetNonPaymentProof(ctx context.Context, q NonPaymentQuery) (*FDCProof, error) {
+	if q.DeadlineLedger <= q.MinimalLedger {
+		return nil, fmt.Errorf("invalid ledger window [%d, %d]", q.MinimalLedger, q.DeadlineLedger)
+	}
+
+	reference := strings.TrimPrefix(q.PaymentReference, "0x")
+	if len(reference) != 64 {
+		return nil, fmt.Errorf("payment reference must be 32 bytes, got %q", q.PaymentReference)
+	}
+
+	requestBody := map[string]interface{}{
+		"minimalBlockNumber":       fmt.Sprintf("%d", q.MinimalLedger),
+		"deadlineBlockNumber":      fmt.Sprintf("%d", q.DeadlineLedger),
+		"deadlineTimestamp":        fmt.Sprintf("%d", q.DeadlineTimestamp),
+		"destinationAddressHash":   crypto.Keccak256Hash([]byte(q.Destination)).Hex(),
+		"amount":                   q.AmountDrops.String(),
+		"standardPaymentReference": "0x" + reference,
+		"checkSourceAddresses":     false,
+		"sourceAddressesRoot":      common.Hash{}.Hex(),
+	}
+
+	proof, err := fs.getAttestationProof(ctx, AttestationReferencedPaymentNonexistence, requestBody)
+	if err != nil {
+		var rejected *verifierRejectedError
+		if errors.As(err, &rejected) {
+			return nil, fmt.Errorf("%w: %v", ErrPaymentExists, err)
+		}
+		return nil, err
+	}
+
+	log.Info().
+		Str("destination", q.Destination).
+		Str("amount", q.AmountDrops.String()).
+		Str("reference", reference).
+		Uint64("minimal_ledger", q.MinimalLedger).
+		Uint64("deadline_ledger", q.DeadlineLedger).
+		Uint64("round_id", proof.RoundID).
+		Msg("FDC ReferencedPaymentNonexistence proof obtained")
+
+	return proof, nil
+}
+
+// SubmitNonPaymentProof reports a proven non-payment to FLIPCore, which
+// fails the redemption and releases the escrow on the failure path
+func (fs *FDCSubmitter) SubmitNonPaymentProof(ctx context.Context, redemptionID *big.Int, proof *FDCProof) error {
+	requestID := new(big.Int).SetUint64(proof.RoundID)
+	return fs.handleFDCAttestation(ctx, redemptionID, requestID, false)
+}
+
+// NonPaymentWindow builds the ledger window from the ledger closing around
+// `since` up to a recently validated ledger
+func (c *XRPLClient) NonPaymentWindow(ctx context.Context, since time.Time) (minimal uint64, deadline *LedgerInfo, err error) {
+	latest, err := c.GetLedger(ctx, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get validated ledger: %w", err)
+	}
+	if latest.Index <= nonPaymentDeadlineLag {
+		return 0, nil, fmt.Errorf("validated ledger %d too low", latest.Index)
+	}
+
+	deadlineIndex := latest.Index - nonPaymentDeadlineLag
+	deadline, err = c.GetLedger(ctx, &deadlineIndex)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get deadline ledger %d: %w", deadlineIndex, err)
+	}
+
+	elapsed := deadline.CloseTime.Sub(since)
+	if elapsed <= 0 {
+		return 0, nil, fmt.Errorf("deadline ledger closed before %s", since.Format(time.RFC3339))
+	}
+
+	ledgersBack := uint64(elapsed.Seconds()) / minLedgerCloseSeconds
+	if ledgersBack >= deadline.Index {
+		return 1, deadline, nil
+	}
+	return deadline.Index - ledgersBack, deadline, nil
+}
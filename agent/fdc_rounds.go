@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+)
+
+// fdcProtocolID is the Flare Systems Protocol ID under which FDC Merkle roots
+// are relayed
+const fdcProtocolID = 200
+
+// defaultFDCTimeout bounds round finalization when agent.fdc_timeout is unset
+const defaultFDCTimeout = 5 * time.Minute
+
+const flareSystemsManagerABI = `[
+	{"inputs":[],"name":"firstVotingRoundStartTs","outputs":[{"name":"","type":"uint64"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"votingEpochDurationSeconds","outputs":[{"name":"","type":"uint64"}],"stateMutability":"view","type":"function"}
+]`
+
+const relayABI = `[
+	{"inputs":[{"name":"_timestamp","type":"uint256"}],"name":"getVotingRoundId","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"name":"_protocolId","type":"uint256"},{"name":"_votingRoundId","type":"uint256"}],"name":"isFinalized","outputs":[{"name":"","type":"bool"}],"stateMutability":"view","type":"function"}
+]`
+
+// VotingEpoch holds the voting round timing parameters of the Flare Systems
+// Protocol
+type VotingEpoch struct {
+	FirstVotingRoundStartTs uint64
+	DurationSeconds         uint64
+	Source                  string // "config", "chain" or "profile"
+}
+
+// RoundForTimestamp returns the voting round that contains the timestamp
+func (e VotingEpoch) RoundForTimestamp(ts uint64) uint64 {
+	if e.DurationSeconds == 0 || ts < e.FirstVotingRoundStartTs {
+		return 0
+	}
+	return (ts - e.FirstVotingRoundStartTs) / e.DurationSeconds
+}
+
+// loadVotingEpoch determines the epoch parameters. Explicit config values
+// win, then FlareSystemsManager, then the network profile.
+func (fs *FDCSubmitter) loadVotingEpoch(ctx context.Context, config *Config) VotingEpoch {
+	if config.FDC.FirstVotingRoundStartTs != 0 && config.FDC.VotingEpochDurationSeconds != 0 {
+		return VotingEpoch{
+			FirstVotingRoundStartTs: config.FDC.FirstVotingRoundStartTs,
+			DurationSeconds:         config.FDC.VotingEpochDurationSeconds,
+			Source:                  "config",
+		}
+	}
+
+	epoch, err := fs.readVotingEpochFromChain(ctx)
+	if err == nil {
+		return epoch
+	}
+
+	log.Warn().Err(err).Msg("Failed to read voting epoch from FlareSystemsManager, using network profile values")
+	return VotingEpoch{
+		FirstVotingRoundStartTs: config.Profile.FirstVotingRoundStartTs,
+		DurationSeconds:         config.Profile.VotingEpochDurationSeconds,
+		Source:                  "profile",
+	}
+}
+
+// readVotingEpochFromChain reads the epoch parameters from FlareSystemsManager
+func (fs *FDCSubmitter) readVotingEpochFromChain(ctx context.Context) (VotingEpoch, error) {
+	addr := fs.contracts.Address(ContractFlareSystemsManager)
+	if addr == (common.Address{}) {
+		return VotingEpoch{}, fmt.Errorf("FlareSystemsManager not resolved")
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(flareSystemsManagerABI))
+	if err != nil {
+		return VotingEpoch{}, fmt.Errorf("failed to parse FlareSystemsManager ABI: %w", err)
+	}
+	contract := bind.NewBoundContract(addr, parsed, fs.client, fs.client, fs.client)
+
+	var startResult []interface{}
+	if err := contract.Call(&bind.CallOpts{Context: ctx}, &startResult, "firstVotingRoundStartTs"); err != nil {
+		return VotingEpoch{}, fmt.Errorf("failed to read firstVotingRoundStartTs: %w", err)
+	}
+	var durationResult []interface{}
+	if err := contract.Call(&bind.CallOpts{Context: ctx}, &durationResult, "votingEpochDurationSeconds"); err != nil {
+		return VotingEpoch{}, fmt.Errorf("failed to read votingEpochDurationSeconds: %w", err)
+	}
+
+	start, ok := startResult[0].(uint64)
+	if !ok {
+		return VotingEpoch{}, fmt.Errorf("unexpected firstVotingRoundStartTs type %T", startResult[0])
+	}
+	duration, ok := durationResult[0].(uint64)
+	if !ok || duration == 0 {
+		return VotingEpoch{}, fmt.Errorf("invalid votingEpochDurationSeconds %v", durationResult[0])
+	}
+
+	return VotingEpoch{
+		FirstVotingRoundStartTs: start,
+		DurationSeconds:         duration,
+		Source:                  "chain",
+	}, nil
+}
+
+// votingRoundForTimestamp asks the Relay contract which voting round contains
+// the timestamp, falling back to the local epoch parameters
+func (fs *FDCSubmitter) votingRoundForTimestamp(ctx context.Context, ts uint64) uint64 {
+	computed := fs.epoch.RoundForTimestamp(ts)
+
+	relayAddr := fs.contracts.Address(ContractRelay)
+	if relayAddr == (common.Address{}) {
+		return computed
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(relayABI))
+	if err != nil {
+		return computed
+	}
+	contract := bind.NewBoundContract(relayAddr, parsed, fs.client, fs.client, fs.client)
+
+	var result []interface{}
+	if err := contract.Call(&bind.CallOpts{Context: ctx}, &result, "getVotingRoundId", new(big.Int).SetUint64(ts)); err != nil {
+		log.Debug().Err(err).Msg("Relay.getVotingRoundId failed, using epoch parameters")
+		return computed
+	}
+
+	roundID := result[0].(*big.Int).Uint64()
+	if roundID != computed {
+		log.Warn().
+			Uint64("relay_round", roundID).
+			Uint64("computed_round", computed).
+			Str("epoch_source", fs.epoch.Source).
+			Msg("Relay voting round differs from computed round, using Relay")
+	}
+	return roundID
+}
+
+// isRoundFinalized reports whether the FDC Merkle root for the round has been
+// relayed. Uses Relay.isFinalized, or the DA layer status if Relay is unknown.
+func (fs *FDCSubmitter) isRoundFinalized(ctx context.Context, roundID uint64) (bool, error) {
+	relayAddr := fs.contracts.Address(ContractRelay)
+	if relayAddr == (common.Address{}) {
+		return fs.isRoundFinalizedOnDALayer(ctx, roundID)
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(relayABI))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse Relay ABI: %w", err)
+	}
+	contract := bind.NewBoundContract(relayAddr, parsed, fs.client, fs.client, fs.client)
+
+	var result []interface{}
+	err = contract.Call(&bind.CallOpts{Context: ctx}, &result, "isFinalized", big.NewInt(fdcProtocolID), new(big.Int).SetUint64(roundID))
+	if err != nil {
+		return false, fmt.Errorf("failed to call Relay.isFinalized: %w", err)
+	}
+
+	return result[0].(bool), nil
+}
+
+// isRoundFinalizedOnDALayer checks the DA layer's latest finalized FDC round
+func (fs *FDCSubmitter) isRoundFinalizedOnDALayer(ctx context.Context, roundID uint64) (bool, error) {
+	body, err := fs.da.GetDALayer(ctx, "/api/v0/fsp/status")
+	if err != nil {
+		return false, err
+	}
+
+	var status struct {
+		LatestFDC struct {
+			VotingRoundID uint64 `json:"voting_round_id"`
+		} `json:"latest_fdc"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return false, fmt.Errorf("failed to decode DA status: %w", err)
+	}
+
+	return status.LatestFDC.VotingRoundID >= roundID, nil
+}
@@ -1,14 +1,12 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"math/big"
-	"net/http"
 	"strings"
 	"time"
 
@@ -18,22 +16,10 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/flip-protocol/agent/bindings"
 	"github.com/rs/zerolog/log"
 )
 
-// FDC constants for Coston2
-const (
-	// FdcHub contract on Coston2
-	FdcHubAddress = "0x48aC463d7975828989331F4De43341627b9c5f1D"
-	// FdcRequestFeeConfigurations on Coston2
-	FdcFeeConfigAddress = "0x191a1282Ac700edE65c5B0AaF313BAcC3eA7fC7e"
-	// DA Layer for Coston2
-	DALayerBaseURL = "https://ctn2-data-availability.flare.network"
-	// Voting round epoch parameters
-	firstVotingRoundStartTs    = uint64(1658430000)
-	votingEpochDurationSeconds = uint64(90)
-)
-
 // FDCProof represents an FDC proof with Merkle verification
 type FDCProof struct {
 	MerkleProof []string    `json:"proof"`
@@ -46,52 +32,147 @@ type FDCSubmitter struct {
 	client      *ethclient.Client
 	flipCore    common.Address
 	escrowVault common.Address
-	verifierURL string
+	contracts   *ContractResolver
+	da          *DAClient
+	sourceID    string
 	apiKey      string
 	privateKey  string
 	chainID     int64
 	timeout     time.Duration
+	epoch       VotingEpoch
+	breakers    *CircuitBreakers // Told the outcome of every proof
+	notifier    *Notifier
+	audit       *AuditJournal
+
+	// XRPL addresses minting deposits must be paid to, by agent vault
+	depositAddresses      map[common.Address]string
+	defaultDepositAddress string
 }
 
 // NewFDCSubmitter creates a new FDC submitter
-func NewFDCSubmitter(config *Config) (*FDCSubmitter, error) {
-	client, err := ethclient.Dial(config.Flare.RPCURL)
+func NewFDCSubmitter(config *Config, contracts *ContractResolver, breakers *CircuitBreakers, notifier *Notifier) (*FDCSubmitter, error) {
+	client, err := dialFlare(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Flare RPC: %w", err)
 	}
 
-	return &FDCSubmitter{
+	audit, err := openAudit(config)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(config.Agent.FDCTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultFDCTimeout
+	}
+
+	fs := &FDCSubmitter{
 		client:      client,
 		flipCore:    common.HexToAddress(config.Flare.FLIPCoreAddress),
 		escrowVault: common.HexToAddress(config.Flare.EscrowVaultAddress),
-		verifierURL: config.FDC.VerifierURL,
+		contracts:   contracts,
+		da:          NewDAClient(config),
+		sourceID:    config.FDC.SourceID,
 		apiKey:      config.FDC.APIKey,
 		privateKey:  config.Flare.PrivateKey,
 		chainID:     int64(config.Flare.ChainID),
-		timeout:     time.Duration(config.Agent.FDCTimeout) * time.Second,
-	}, nil
+		timeout:     timeout,
+		breakers:    breakers,
+		notifier:    notifier,
+		audit:       audit,
+
+		depositAddresses:      make(map[common.Address]string),
+		defaultDepositAddress: config.XRPL.DepositAddress,
+	}
+	for vault, xrplAddress := range config.XRPL.AgentVaultAddresses {
+		fs.depositAddresses[common.HexToAddress(vault)] = xrplAddress
+	}
+
+	fs.epoch = fs.loadVotingEpoch(context.Background(), config)
+	log.Info().
+		Uint64("first_voting_round_start_ts", fs.epoch.FirstVotingRoundStartTs).
+		Uint64("voting_epoch_duration", fs.epoch.DurationSeconds).
+		Str("source", fs.epoch.Source).
+		Msg("FDC voting epoch parameters loaded")
+
+	return fs, nil
 }
 
+// Attestation types supported by the XRP verifier
+const (
+	AttestationPayment                       = "Payment"
+	AttestationReferencedPaymentNonexistence = "ReferencedPaymentNonexistence"
+)
+
 // GetFDCProof executes the complete FDC flow for an XRPL payment
 func (fs *FDCSubmitter) GetFDCProof(ctx context.Context, xrplTxHash string) (*FDCProof, error) {
+	requestBody := map[string]interface{}{
+		"transactionId": xrplTxHash,
+		"inUtxo":        "0",
+		"utxo":          "0",
+	}
+
+	proof, err := fs.getAttestationProof(ctx, AttestationPayment, requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("xrpl_tx", xrplTxHash).
+		Uint64("round_id", proof.RoundID).
+		Msg("FDC Payment proof obtained")
+
+	return proof, nil
+}
+
+// getAttestationProof prepares, submits and fetches the proof for a single
+// attestation request of the given type
+func (fs *FDCSubmitter) getAttestationProof(ctx context.Context, attestationType string, requestBody map[string]interface{}) (*FDCProof, error) {
+	proof, err := fs.attestationProof(ctx, attestationType, requestBody)
+	fs.breakers.ObserveFDC(err)
+	if errors.Is(err, ErrProofMismatch) {
+		subject := attestationType
+		for _, key := range []string{"transactionId", "standardPaymentReference"} {
+			if value, ok := requestBody[key].(string); ok {
+				subject = value
+				break
+			}
+		}
+		fs.notifier.Notify(EventProofMismatch, subject, err.Error(), map[string]string{"attestation_type": attestationType})
+	}
+	if err == nil {
+		fs.audit.Record(AuditFDCProof, map[string]interface{}{
+			"attestation_type": attestationType,
+			"request":          requestBody,
+			"round_id":         proof.RoundID,
+		})
+	}
+	return proof, err
+}
+
+func (fs *FDCSubmitter) attestationProof(ctx context.Context, attestationType string, requestBody map[string]interface{}) (*FDCProof, error) {
 	// Step 1: Prepare attestation request via verifier
-	abiEncodedRequest, err := fs.prepareAttestationRequest(ctx, xrplTxHash)
+	done := observeStage(StageFDCPrepare)
+	abiEncodedRequest, err := fs.prepareAttestationRequest(ctx, attestationType, requestBody)
+	done(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare attestation request: %w", err)
 	}
 
 	log.Info().
-		Str("xrpl_tx", xrplTxHash).
+		Str("attestation_type", attestationType).
 		Msg("FDC attestation request prepared")
 
 	// Step 2: Submit on-chain to FdcHub
+	done = observeStage(StageFDCRequest)
 	submissionTimestamp, err := fs.submitOnChain(ctx, abiEncodedRequest)
+	done(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to submit FDC request on-chain: %w", err)
 	}
 
-	// Step 3: Calculate voting round ID
-	roundID := (submissionTimestamp - firstVotingRoundStartTs) / votingEpochDurationSeconds
+	// Step 3: Determine voting round ID
+	roundID := fs.votingRoundForTimestamp(ctx, submissionTimestamp)
 
 	log.Info().
 		Uint64("round_id", roundID).
@@ -99,13 +180,17 @@ func (fs *FDCSubmitter) GetFDCProof(ctx context.Context, xrplTxHash string) (*FD
 		Msg("FDC request submitted, waiting for round finalization")
 
 	// Step 4: Wait for round finalization
+	done = observeStage(StageFDCRound)
 	err = fs.waitForRoundFinalization(ctx, roundID)
+	done(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed waiting for round finalization: %w", err)
 	}
 
 	// Step 5: Fetch proof from DA layer
+	done = observeStage(StageFDCProof)
 	proof, err := fs.fetchProofFromDALayer(ctx, roundID, abiEncodedRequest)
+	done(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch proof from DA layer: %w", err)
 	}
@@ -122,57 +207,26 @@ func encodeToHex32(s string) string {
 }
 
 // prepareAttestationRequest calls the FDC verifier to get the abiEncodedRequest
-func (fs *FDCSubmitter) prepareAttestationRequest(ctx context.Context, txHash string) (string, error) {
-	url := fmt.Sprintf("%s/verifier/xrp/Payment/prepareRequest", fs.verifierURL)
+func (fs *FDCSubmitter) prepareAttestationRequest(ctx context.Context, attestationType string, body map[string]interface{}) (string, error) {
+	path := fmt.Sprintf("/verifier/xrp/%s/prepareRequest", attestationType)
 
 	requestBody := map[string]interface{}{
-		"attestationType": encodeToHex32("Payment"),
-		"sourceId":        encodeToHex32("testXRP"),
-		"requestBody": map[string]interface{}{
-			"transactionId": txHash,
-			"inUtxo":        "0",
-			"utxo":          "0",
-		},
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", err
+		"attestationType": encodeToHex32(attestationType),
+		"sourceId":        encodeToHex32(fs.sourceID),
+		"requestBody":     body,
 	}
 
 	log.Debug().
-		Str("url", url).
-		Str("request", string(jsonData)).
+		Str("path", path).
+		Interface("request", requestBody).
 		Msg("Sending FDC attestation request to verifier")
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if fs.apiKey != "" {
-		req.Header.Set("X-API-KEY", fs.apiKey)
-	}
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	respBody, err := fs.da.PostVerifier(ctx, path, requestBody)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
 		log.Error().
-			Int("status", resp.StatusCode).
-			Str("body", string(respBody)).
+			Err(err).
 			Msg("FDC verifier error")
-		return "", fmt.Errorf("verifier returned status %d: %s", resp.StatusCode, string(respBody))
+		return "", fmt.Errorf("verifier request failed: %w", err)
 	}
 
 	log.Debug().
@@ -180,6 +234,7 @@ func (fs *FDCSubmitter) prepareAttestationRequest(ctx context.Context, txHash st
 		Msg("FDC verifier response")
 
 	var result struct {
+		Status            string `json:"status"`
 		AbiEncodedRequest string `json:"abiEncodedRequest"`
 	}
 
@@ -188,12 +243,26 @@ func (fs *FDCSubmitter) prepareAttestationRequest(ctx context.Context, txHash st
 	}
 
 	if result.AbiEncodedRequest == "" {
+		if result.Status != "" && result.Status != "VALID" {
+			return "", &verifierRejectedError{AttestationType: attestationType, Status: result.Status}
+		}
 		return "", fmt.Errorf("verifier returned empty abiEncodedRequest (tx may not be indexed yet)")
 	}
 
 	return result.AbiEncodedRequest, nil
 }
 
+// verifierRejectedError is returned when the verifier refuses to attest, e.g.
+// a nonexistence request for a payment that does exist
+type verifierRejectedError struct {
+	AttestationType string
+	Status          string
+}
+
+func (e *verifierRejectedError) Error() string {
+	return fmt.Sprintf("verifier rejected %s request with status %s", e.AttestationType, e.Status)
+}
+
 // submitOnChain submits the attestation request to FdcHub on-chain
 func (fs *FDCSubmitter) submitOnChain(ctx context.Context, abiEncodedRequest string) (uint64, error) {
 	// Get the fee required for attestation
@@ -254,13 +323,13 @@ func (fs *FDCSubmitter) submitOnChain(ctx context.Context, abiEncodedRequest str
 	auth.Value = fee
 
 	// Submit to FdcHub
-	fdcHubAddr := common.HexToAddress(FdcHubAddress)
-	contract := bind.NewBoundContract(fdcHubAddr, parsed, fs.client, fs.client, fs.client)
+	contract := bind.NewBoundContract(fs.contracts.Address(ContractFdcHub), parsed, fs.client, fs.client, fs.client)
 
 	tx, err := contract.Transact(auth, "requestAttestation", requestBytes)
 	if err != nil {
 		return 0, fmt.Errorf("failed to submit attestation: %w", err)
 	}
+	fs.audit.RecordFlareTx(tx)
 
 	log.Info().
 		Str("tx_hash", tx.Hash().Hex()).
@@ -271,10 +340,12 @@ func (fs *FDCSubmitter) submitOnChain(ctx context.Context, abiEncodedRequest str
 	if err != nil {
 		return 0, fmt.Errorf("failed to wait for FDC tx: %w", err)
 	}
+	recordGas("requestAttestation", tx, receipt)
 
 	if receipt.Status != 1 {
 		return 0, fmt.Errorf("FDC attestation tx failed with status %d", receipt.Status)
 	}
+	fdcFeesSpent.Add(weiFloat(fee))
 
 	// Get block timestamp for voting round calculation
 	block, err := fs.client.BlockByNumber(ctx, receipt.BlockNumber)
@@ -312,8 +383,7 @@ func (fs *FDCSubmitter) getAttestationFee(ctx context.Context, abiEncodedRequest
 		return nil, err
 	}
 
-	feeConfigAddr := common.HexToAddress(FdcFeeConfigAddress)
-	contract := bind.NewBoundContract(feeConfigAddr, parsed, fs.client, fs.client, fs.client)
+	contract := bind.NewBoundContract(fs.contracts.Address(ContractFdcRequestFeeConfigurations), parsed, fs.client, fs.client, fs.client)
 
 	var result []interface{}
 	err = contract.Call(&bind.CallOpts{Context: ctx}, &result, "getRequestFee", requestBytes)
@@ -324,16 +394,15 @@ func (fs *FDCSubmitter) getAttestationFee(ctx context.Context, abiEncodedRequest
 	return result[0].(*big.Int), nil
 }
 
-// waitForRoundFinalization polls the DA layer until the voting round is finalized
+// waitForRoundFinalization polls the Relay contract until the voting round is
+// finalized, giving up after the configured FDC timeout
 func (fs *FDCSubmitter) waitForRoundFinalization(ctx context.Context, roundID uint64) error {
-	statusURL := fmt.Sprintf("%s/api/v0/fsp/status", DALayerBaseURL)
-	maxWait := 5 * time.Minute
 	pollInterval := 10 * time.Second
-	deadline := time.Now().Add(maxWait)
+	deadline := time.Now().Add(fs.timeout)
 
 	for {
 		if time.Now().After(deadline) {
-			return fmt.Errorf("timeout waiting for round %d finalization", roundID)
+			return fmt.Errorf("timeout waiting for round %d finalization after %s", roundID, fs.timeout)
 		}
 
 		select {
@@ -342,39 +411,18 @@ func (fs *FDCSubmitter) waitForRoundFinalization(ctx context.Context, roundID ui
 		case <-time.After(pollInterval):
 		}
 
-		req, err := http.NewRequestWithContext(ctx, "GET", statusURL, nil)
+		finalized, err := fs.isRoundFinalized(ctx, roundID)
 		if err != nil {
+			log.Debug().Err(err).Msg("Failed to check FDC round finalization, retrying")
 			continue
 		}
-		if fs.apiKey != "" {
-			req.Header.Set("X-API-KEY", fs.apiKey)
-		}
-
-		client := &http.Client{Timeout: 10 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Debug().Err(err).Msg("Failed to check FDC status, retrying")
-			continue
-		}
-
-		var status struct {
-			LatestFDC struct {
-				VotingRoundID uint64 `json:"voting_round_id"`
-			} `json:"latest_fdc"`
-		}
-
-		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
-			resp.Body.Close()
-			continue
-		}
-		resp.Body.Close()
 
 		log.Debug().
 			Uint64("target_round", roundID).
-			Uint64("latest_fdc_round", status.LatestFDC.VotingRoundID).
+			Bool("finalized", finalized).
 			Msg("Checking FDC round finalization")
 
-		if status.LatestFDC.VotingRoundID >= roundID {
+		if finalized {
 			log.Info().
 				Uint64("round_id", roundID).
 				Msg("FDC round finalized")
@@ -385,47 +433,21 @@ func (fs *FDCSubmitter) waitForRoundFinalization(ctx context.Context, roundID ui
 
 // fetchProofFromDALayer fetches the proof from the Data Availability Layer
 func (fs *FDCSubmitter) fetchProofFromDALayer(ctx context.Context, roundID uint64, requestBytes string) (*FDCProof, error) {
-	url := fmt.Sprintf("%s/api/v1/fdc/proof-by-request-round", DALayerBaseURL)
+	path := "/api/v1/fdc/proof-by-request-round"
 
 	requestBody := map[string]interface{}{
 		"votingRoundId": roundID,
 		"requestBytes":  requestBytes,
 	}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, err
-	}
-
 	log.Debug().
-		Str("url", url).
+		Str("path", path).
 		Uint64("round_id", roundID).
 		Msg("Fetching proof from DA layer")
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	respBody, err := fs.da.PostDALayerProof(ctx, path, requestBody)
 	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if fs.apiKey != "" {
-		req.Header.Set("x-api-key", fs.apiKey)
-	}
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read DA response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("DA layer returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("DA layer request failed: %w", err)
 	}
 
 	log.Debug().
@@ -455,38 +477,16 @@ func (fs *FDCSubmitter) fetchProofFromDALayer(ctx context.Context, roundID uint6
 // fundEscrowForRedemption sends the required amount to the EscrowVault so releaseOnFDC can transfer funds
 func (fs *FDCSubmitter) fundEscrowForRedemption(ctx context.Context, redemptionID *big.Int) error {
 	// Query escrow amount from the vault
-	const escrowABI = `[{
-		"inputs": [{"name": "", "type": "uint256"}],
-		"name": "escrows",
-		"outputs": [
-			{"name": "redemptionId", "type": "uint256"},
-			{"name": "user", "type": "address"},
-			{"name": "lp", "type": "address"},
-			{"name": "asset", "type": "address"},
-			{"name": "amount", "type": "uint256"},
-			{"name": "createdAt", "type": "uint256"},
-			{"name": "fdcRoundId", "type": "uint256"},
-			{"name": "status", "type": "uint8"},
-			{"name": "lpFunded", "type": "bool"}
-		],
-		"stateMutability": "view",
-		"type": "function"
-	}]`
-
-	parsed, err := abi.JSON(strings.NewReader(escrowABI))
+	vault, err := bindings.NewEscrowVaultCaller(fs.escrowVault, fs.client)
 	if err != nil {
-		return fmt.Errorf("failed to parse escrow ABI: %w", err)
+		return fmt.Errorf("failed to bind escrow vault: %w", err)
 	}
-
-	contract := bind.NewBoundContract(fs.escrowVault, parsed, fs.client, fs.client, fs.client)
-	var result []interface{}
-	err = contract.Call(&bind.CallOpts{Context: ctx}, &result, "escrows", redemptionID)
+	escrow, err := vault.Escrows(&bind.CallOpts{Context: ctx}, redemptionID)
 	if err != nil {
 		return fmt.Errorf("failed to query escrow: %w", err)
 	}
 
-	// amount is the 5th field (index 4)
-	amount := result[4].(*big.Int)
+	amount := escrow.Amount
 	if amount.Sign() == 0 {
 		return nil // No funding needed
 	}
@@ -543,6 +543,7 @@ func (fs *FDCSubmitter) fundEscrowForRedemption(ctx context.Context, redemptionI
 	if err != nil {
 		return fmt.Errorf("failed to send funding tx: %w", err)
 	}
+	fs.audit.RecordFlareTx(signedTx)
 
 	log.Info().
 		Str("tx_hash", signedTx.Hash().Hex()).
@@ -553,6 +554,7 @@ func (fs *FDCSubmitter) fundEscrowForRedemption(ctx context.Context, redemptionI
 	if err != nil {
 		return fmt.Errorf("failed to wait for funding tx: %w", err)
 	}
+	recordGas("fundEscrow", signedTx, receipt)
 
 	if receipt.Status != 1 {
 		return fmt.Errorf("funding tx failed with status %d", receipt.Status)
@@ -563,6 +565,13 @@ func (fs *FDCSubmitter) fundEscrowForRedemption(ctx context.Context, redemptionI
 
 // SubmitProof submits the FDC proof to FLIPCore to finalize the redemption
 func (fs *FDCSubmitter) SubmitProof(ctx context.Context, redemptionID *big.Int, proof *FDCProof) error {
+	done := observeStage(StageFDCSubmit)
+	err := fs.submitProof(ctx, redemptionID, proof)
+	done(err)
+	return err
+}
+
+func (fs *FDCSubmitter) submitProof(ctx context.Context, redemptionID *big.Int, proof *FDCProof) error {
 	// Parse the FDC response to determine payment success
 	// The Payment response includes status: 0=SUCCESS, 1=SENDER_FAILURE, 2=RECEIVER_FAILURE
 	success := true // Default to success if we got a valid proof
@@ -585,74 +594,91 @@ func (fs *FDCSubmitter) SubmitProof(ctx context.Context, redemptionID *big.Int,
 		}
 	}
 
-	const handleFDCABI = `[{
-		"inputs": [
-			{"name": "_redemptionId", "type": "uint256"},
-			{"name": "_requestId", "type": "uint256"},
-			{"name": "_success", "type": "bool"}
-		],
-		"name": "handleFDCAttestation",
-		"outputs": [],
-		"stateMutability": "nonpayable",
-		"type": "function"
-	}]`
+	return fs.handleFDCAttestation(ctx, redemptionID, requestID, success)
+}
+
+// handleFDCAttestation reports the FDC outcome for a redemption to FLIPCore
+func (fs *FDCSubmitter) handleFDCAttestation(ctx context.Context, redemptionID, requestID *big.Int, success bool) error {
+	// Increased gas - handleFDCAttestation calls multiple contracts
+	tx, err := fs.transactFLIPCore(ctx, "handleFDCAttestation", 500000, redemptionID, requestID, success)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("tx_hash", tx.Hash().Hex()).
+		Uint64("redemption_id", redemptionID.Uint64()).
+		Bool("success", success).
+		Msg("FDC attestation finalized on FLIPCore")
+
+	return nil
+}
+
+// ErrGasPriceAboveCeiling is returned when the network gas price exceeds the
+// ceiling passed to transactFLIPCoreCapped
+var ErrGasPriceAboveCeiling = errors.New("gas price above ceiling")
+
+// transactFLIPCore sends an operator transaction to FLIPCore through its
+// generated binding and waits for it to be mined successfully
+func (fs *FDCSubmitter) transactFLIPCore(ctx context.Context, method string, gasLimit uint64, args ...interface{}) (*types.Transaction, error) {
+	return fs.transactFLIPCoreCapped(ctx, nil, method, gasLimit, args...)
+}
 
-	parsed, err := abi.JSON(strings.NewReader(handleFDCABI))
+// transactFLIPCoreCapped is transactFLIPCore that refuses to send while the
+// suggested gas price is above maxGasPrice (nil = no ceiling)
+func (fs *FDCSubmitter) transactFLIPCoreCapped(ctx context.Context, maxGasPrice *big.Int, method string, gasLimit uint64, args ...interface{}) (*types.Transaction, error) {
+	core, err := bindings.NewFLIPCoreTransactor(fs.flipCore, fs.client)
 	if err != nil {
-		return fmt.Errorf("failed to parse ABI: %w", err)
+		return nil, fmt.Errorf("failed to bind FLIPCore: %w", err)
 	}
 
 	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(fs.privateKey, "0x"))
 	if err != nil {
-		return fmt.Errorf("failed to parse private key: %w", err)
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
 	}
 
 	chainID := big.NewInt(fs.chainID)
 	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
 	if err != nil {
-		return fmt.Errorf("failed to create transactor: %w", err)
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
 
 	nonce, err := fs.client.PendingNonceAt(ctx, auth.From)
 	if err != nil {
-		return fmt.Errorf("failed to get nonce: %w", err)
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
 	}
 	auth.Nonce = big.NewInt(int64(nonce))
 
 	gasPrice, err := fs.client.SuggestGasPrice(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get gas price: %w", err)
+		return nil, fmt.Errorf("failed to get gas price: %w", err)
+	}
+	if maxGasPrice != nil && gasPrice.Cmp(maxGasPrice) > 0 {
+		return nil, fmt.Errorf("%w: %s > %s wei", ErrGasPriceAboveCeiling, gasPrice, maxGasPrice)
 	}
 	auth.GasPrice = gasPrice
-	auth.GasLimit = uint64(500000) // Increased - handleFDCAttestation calls multiple contracts
-
-	contract := bind.NewBoundContract(fs.flipCore, parsed, fs.client, fs.client, fs.client)
+	auth.GasLimit = gasLimit
 
-	tx, err := contract.Transact(auth, "handleFDCAttestation", redemptionID, requestID, success)
+	tx, err := (&bindings.FLIPCoreTransactorRaw{Contract: core}).Transact(auth, method, args...)
 	if err != nil {
-		return fmt.Errorf("failed to send handleFDCAttestation tx: %w", err)
+		return nil, fmt.Errorf("failed to send %s tx: %w", method, err)
 	}
+	fs.audit.RecordFlareTx(tx)
 
 	log.Info().
 		Str("tx_hash", tx.Hash().Hex()).
-		Uint64("redemption_id", redemptionID.Uint64()).
-		Bool("success", success).
-		Msg("Submitted FDC attestation to FLIPCore")
+		Str("method", method).
+		Msg("Submitted transaction to FLIPCore")
 
 	receipt, err := bind.WaitMined(ctx, fs.client, tx)
 	if err != nil {
-		return fmt.Errorf("failed to wait for handleFDCAttestation tx: %w", err)
+		return nil, fmt.Errorf("failed to wait for %s tx: %w", method, err)
 	}
+	recordGas(method, tx, receipt)
 
 	if receipt.Status != 1 {
-		return fmt.Errorf("handleFDCAttestation tx failed with status %d", receipt.Status)
+		return nil, fmt.Errorf("%s tx failed with status %d", method, receipt.Status)
 	}
 
-	log.Info().
-		Str("tx_hash", tx.Hash().Hex()).
-		Uint64("redemption_id", redemptionID.Uint64()).
-		Bool("success", success).
-		Msg("FDC attestation finalized on FLIPCore")
-
-	return nil
+	return tx, nil
 }
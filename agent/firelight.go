@@ -0,0 +1,527 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/flip-protocol/agent/bindings"
+	"github.com/rs/zerolog/log"
+)
+
+// Firelight watcher defaults used when the config leaves them unset
+const (
+	defaultFirelightInterval  = 5 * time.Minute
+	defaultFirelightReportDir = "incidents"
+)
+
+// Incident states, in the order an incident normally moves through them
+const (
+	IncidentDetected         = "detected"
+	IncidentPreviewed        = "previewed" // Dry run: would have triggered
+	IncidentAwaitingApproval = "awaiting_approval"
+	IncidentTriggered        = "triggered"
+	IncidentTriggerFailed    = "trigger_failed"
+)
+
+// TimelineEntry is one step in an incident or workflow history
+type TimelineEntry struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	Detail   string    `json:"detail,omitempty"`
+	Operator string    `json:"operator,omitempty"` // Set for operator actions
+}
+
+// FirelightPreview is the result of simulating triggerFirelight
+type FirelightPreview struct {
+	WouldSucceed bool   `json:"would_succeed"`
+	GasEstimate  uint64 `json:"gas_estimate,omitempty"`
+	RevertReason string `json:"revert_reason,omitempty"`
+}
+
+// FirelightIncident is the per-incident report written to the report dir
+type FirelightIncident struct {
+	RedemptionID     uint64            `json:"redemption_id"`
+	User             string            `json:"user"`
+	Asset            string            `json:"asset"`
+	Amount           string            `json:"amount"`
+	XRPLAddress      string            `json:"xrpl_address"`
+	XRPLTxHash       string            `json:"xrpl_tx_hash,omitempty"`
+	RedemptionStatus string            `json:"redemption_status"`
+	EscrowStatus     string            `json:"escrow_status"`
+	LP               string            `json:"lp,omitempty"`
+	LPFunded         bool              `json:"lp_funded"`
+	FDCRoundID       uint64            `json:"fdc_round_id,omitempty"`
+	Cause            string            `json:"cause"`
+	ApprovalRequired bool              `json:"approval_required"`
+	State            string            `json:"state"`
+	Preview          *FirelightPreview `json:"preview,omitempty"`
+	TriggerTxHash    string            `json:"trigger_tx_hash,omitempty"`
+	Timeline         []TimelineEntry   `json:"timeline"`
+}
+
+func (inc *FirelightIncident) addEvent(t time.Time, format string, args ...interface{}) {
+	inc.Timeline = append(inc.Timeline, TimelineEntry{Time: t.UTC(), Event: fmt.Sprintf(format, args...)})
+}
+
+var redemptionStatusNames = []string{"Pending", "QueuedForFDC", "EscrowCreated", "ReceiptRedeemed", "Finalized", "Failed", "Timeout"}
+var escrowStatusNames = []string{"None", "Created", "Released", "Failed", "Timeout"}
+
+func statusName(names []string, status uint8) string {
+	if int(status) < len(names) {
+		return names[status]
+	}
+	return fmt.Sprintf("Unknown(%d)", status)
+}
+
+// FirelightWatcher finds redemptions that qualify for the Firelight backstop
+// and triggers it, subject to dry run and operator approval
+type FirelightWatcher struct {
+	client            *ethclient.Client
+	submitter         *FDCSubmitter
+	flipCoreAddr      common.Address
+	flipCoreABI       *abi.ABI // Packs the triggerFirelight simulation
+	core              *bindings.FLIPCoreCaller
+	vault             *bindings.EscrowVaultCaller
+	interval          time.Duration
+	dryRun            bool
+	approvalThreshold *big.Int // nil = never require approval
+	reportDir         string
+	audit             *AuditJournal
+}
+
+// FirelightApproval is the operator decision stored in an approval file
+type FirelightApproval struct {
+	Operator string    `json:"operator"`
+	Reason   string    `json:"reason,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// NewFirelightWatcher creates a watcher from the config
+func NewFirelightWatcher(client *ethclient.Client, submitter *FDCSubmitter, config *Config) (*FirelightWatcher, error) {
+	flipCoreABI, err := bindings.ABI(ContractFLIPCore)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := time.Duration(config.Firelight.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultFirelightInterval
+	}
+
+	var threshold *big.Int
+	if config.Firelight.ApprovalThreshold != "" {
+		var ok bool
+		threshold, ok = new(big.Int).SetString(config.Firelight.ApprovalThreshold, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid firelight.approval_threshold %q", config.Firelight.ApprovalThreshold)
+		}
+	}
+
+	reportDir := config.Firelight.ReportDir
+	if reportDir == "" {
+		reportDir = defaultFirelightReportDir
+	}
+
+	audit, err := openAudit(config)
+	if err != nil {
+		return nil, err
+	}
+
+	flipCoreAddr := common.HexToAddress(config.Flare.FLIPCoreAddress)
+	escrowVaultAddr := common.HexToAddress(config.Flare.EscrowVaultAddress)
+	core, err := bindings.NewFLIPCoreCaller(flipCoreAddr, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind FLIPCore: %w", err)
+	}
+	vault, err := bindings.NewEscrowVaultCaller(escrowVaultAddr, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind EscrowVault: %w", err)
+	}
+
+	return &FirelightWatcher{
+		client:            client,
+		submitter:         submitter,
+		flipCoreAddr:      flipCoreAddr,
+		flipCoreABI:       flipCoreABI,
+		core:              core,
+		vault:             vault,
+		interval:          interval,
+		dryRun:            config.Firelight.DryRun,
+		approvalThreshold: threshold,
+		reportDir:         reportDir,
+		audit:             audit,
+	}, nil
+}
+
+// Run scans for eligible redemptions every interval until ctx is cancelled
+func (w *FirelightWatcher) Run(ctx context.Context) {
+	if err := os.MkdirAll(w.reportDir, 0755); err != nil {
+		log.Error().Err(err).Str("dir", w.reportDir).Msg("Failed to create Firelight report dir, watcher disabled")
+		return
+	}
+
+	log.Info().
+		Dur("interval", w.interval).
+		Bool("dry_run", w.dryRun).
+		Str("report_dir", w.reportDir).
+		Msg("Firelight watcher started")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Scan(ctx); err != nil {
+			log.Warn().Err(err).Msg("Firelight scan failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan checks every redemption once and advances its incident
+func (w *FirelightWatcher) Scan(ctx context.Context) error {
+	next, err := w.core.NextRedemptionId(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return fmt.Errorf("failed to get nextRedemptionId: %w", err)
+	}
+	nextID := next.Uint64()
+
+	for i := uint64(0); i < nextID; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := w.process(ctx, new(big.Int).SetUint64(i)); err != nil {
+			log.Warn().Err(err).Uint64("redemption_id", i).Msg("Failed to process Firelight candidate")
+		}
+	}
+	return nil
+}
+
+// eligible mirrors the triggerFirelight preconditions
+func (w *FirelightWatcher) eligible(ctx context.Context, redemptionID *big.Int) (uint8, uint8, bool, error) {
+	status, err := w.core.GetRedemptionStatus(&bind.CallOpts{Context: ctx}, redemptionID)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to get redemption status: %w", err)
+	}
+	if status != 5 && status != 6 { // Failed, Timeout
+		return status, 0, false, nil
+	}
+
+	escrowStatus, err := w.vault.GetEscrowStatus(&bind.CallOpts{Context: ctx}, redemptionID)
+	if err != nil {
+		return status, 0, false, fmt.Errorf("failed to get escrow status: %w", err)
+	}
+
+	return status, escrowStatus, escrowStatus == 3 || escrowStatus == 4, nil // Failed, Timeout
+}
+
+// process detects, previews and triggers a single redemption's incident
+func (w *FirelightWatcher) process(ctx context.Context, redemptionID *big.Int) error {
+	incident, err := w.loadIncident(redemptionID.Uint64())
+	if err != nil {
+		return err
+	}
+	if incident != nil && (incident.State == IncidentTriggered || (w.dryRun && incident.State == IncidentPreviewed)) {
+		return nil
+	}
+
+	if incident == nil {
+		status, escrowStatus, ok, err := w.eligible(ctx, redemptionID)
+		if err != nil || !ok {
+			return err
+		}
+		incident, err = w.buildIncident(ctx, redemptionID, status, escrowStatus)
+		if err != nil {
+			return err
+		}
+
+		log.Warn().
+			Uint64("redemption_id", incident.RedemptionID).
+			Str("user", incident.User).
+			Str("amount", incident.Amount).
+			Str("cause", incident.Cause).
+			Msg("Redemption eligible for Firelight backstop")
+	}
+
+	preview := w.preview(ctx, redemptionID)
+	previous := incident.Preview
+	incident.Preview = preview
+	if !preview.WouldSucceed {
+		if previous == nil || previous.RevertReason != preview.RevertReason {
+			incident.addEvent(time.Now(), "Preview: triggerFirelight would revert: %s", preview.RevertReason)
+		}
+		return w.saveIncident(incident)
+	}
+
+	if w.dryRun {
+		incident.State = IncidentPreviewed
+		incident.addEvent(time.Now(), "Dry run: would trigger Firelight (gas estimate %d)", preview.GasEstimate)
+		return w.saveIncident(incident)
+	}
+
+	if incident.ApprovalRequired {
+		approval, err := w.approval(incident.RedemptionID)
+		if err != nil {
+			return err
+		}
+		if approval == nil {
+			if incident.State != IncidentAwaitingApproval {
+				incident.State = IncidentAwaitingApproval
+				incident.addEvent(time.Now(), "Amount above approval threshold, awaiting operator approval (agent firelight-approve %d)", incident.RedemptionID)
+				log.Warn().
+					Uint64("redemption_id", incident.RedemptionID).
+					Msg("Firelight trigger awaiting operator approval")
+			}
+			return w.saveIncident(incident)
+		}
+
+		incident.Timeline = append(incident.Timeline, TimelineEntry{
+			Time:     time.Now().UTC(),
+			Event:    "Operator approval found",
+			Detail:   approval.Reason,
+			Operator: approval.Operator,
+		})
+		w.audit.RecordOverride(approval.Operator, WorkflowRedemption, &incident.RedemptionID, map[string]interface{}{
+			"action":      "firelight_approval_consumed",
+			"reason":      approval.Reason,
+			"approved_at": approval.Time,
+		})
+	}
+
+	tx, err := w.submitter.transactFLIPCore(ctx, "triggerFirelight", preview.GasEstimate*12/10, redemptionID)
+	if err != nil {
+		incident.State = IncidentTriggerFailed
+		incident.addEvent(time.Now(), "triggerFirelight failed: %v", err)
+		if saveErr := w.saveIncident(incident); saveErr != nil {
+			log.Error().Err(saveErr).Msg("Failed to save Firelight incident")
+		}
+		return err
+	}
+
+	incident.State = IncidentTriggered
+	incident.TriggerTxHash = tx.Hash().Hex()
+	incident.addEvent(time.Now(), "Firelight triggered in tx %s", incident.TriggerTxHash)
+	log.Info().
+		Uint64("redemption_id", incident.RedemptionID).
+		Str("tx_hash", incident.TriggerTxHash).
+		Msg("Firelight backstop triggered")
+
+	return w.saveIncident(incident)
+}
+
+// buildIncident gathers the redemption and escrow details for a new report
+func (w *FirelightWatcher) buildIncident(ctx context.Context, redemptionID *big.Int, status, escrowStatus uint8) (*FirelightIncident, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	redemption, err := w.core.Redemptions(opts, redemptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redemption: %w", err)
+	}
+	escrow, err := w.vault.Escrows(opts, redemptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get escrow: %w", err)
+	}
+	xrplTxHash, err := w.core.RedemptionXrplTxHash(opts, redemptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get XRPL tx hash: %w", err)
+	}
+
+	amount := redemption.Amount
+	requestedAt := redemption.RequestedAt.Int64()
+	lp := escrow.Lp
+	createdAt := escrow.CreatedAt.Int64()
+	fdcRoundID := escrow.FdcRoundId.Uint64()
+
+	incident := &FirelightIncident{
+		RedemptionID:     redemptionID.Uint64(),
+		User:             redemption.User.Hex(),
+		Asset:            redemption.Asset.Hex(),
+		Amount:           amount.String(),
+		XRPLAddress:      strings.Trim(redemption.XrplAddress, "\x00"),
+		XRPLTxHash:       xrplTxHash,
+		RedemptionStatus: statusName(redemptionStatusNames, status),
+		EscrowStatus:     statusName(escrowStatusNames, escrowStatus),
+		LPFunded:         escrow.LpFunded,
+		FDCRoundID:       fdcRoundID,
+		Cause:            failureCause(status, xrplTxHash),
+		ApprovalRequired: w.approvalThreshold != nil && amount.Cmp(w.approvalThreshold) > 0,
+		State:            IncidentDetected,
+	}
+	if lp != (common.Address{}) {
+		incident.LP = lp.Hex()
+	}
+
+	incident.addEvent(time.Unix(requestedAt, 0), "Redemption requested")
+	if createdAt > 0 {
+		if escrow.LpFunded {
+			incident.addEvent(time.Unix(createdAt, 0), "Escrow created, funded by LP %s", incident.LP)
+		} else {
+			incident.addEvent(time.Unix(createdAt, 0), "Escrow created, user-wait path")
+		}
+	}
+	if xrplTxHash != "" {
+		incident.addEvent(time.Unix(createdAt, 0), "XRPL payment recorded: %s", xrplTxHash)
+	}
+	if fdcRoundID != 0 {
+		incident.addEvent(time.Unix(createdAt, 0), "FDC attestation in round %d", fdcRoundID)
+	}
+	incident.addEvent(time.Now(), "Detected: redemption %s, escrow %s", incident.RedemptionStatus, incident.EscrowStatus)
+
+	return incident, nil
+}
+
+// failureCause explains why a redemption ended up on the catastrophic path
+func failureCause(status uint8, xrplTxHash string) string {
+	switch {
+	case status == 5 && xrplTxHash != "":
+		return "FDC attested the recorded XRPL payment as failed"
+	case status == 5:
+		return "FDC proved the XRPL payment was never made"
+	case status == 6 && xrplTxHash != "":
+		return "XRPL payment recorded but FDC did not attest it before the escrow timed out"
+	default:
+		return "No XRPL payment and no FDC attestation before the escrow timed out"
+	}
+}
+
+// preview simulates triggerFirelight without sending a transaction
+func (w *FirelightWatcher) preview(ctx context.Context, redemptionID *big.Int) *FirelightPreview {
+	data, err := w.flipCoreABI.Pack("triggerFirelight", redemptionID)
+	if err != nil {
+		return &FirelightPreview{RevertReason: err.Error()}
+	}
+
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(w.submitter.privateKey, "0x"))
+	if err != nil {
+		return &FirelightPreview{RevertReason: fmt.Sprintf("failed to parse private key: %v", err)}
+	}
+
+	msg := ethereum.CallMsg{
+		From: crypto.PubkeyToAddress(privateKey.PublicKey),
+		To:   &w.flipCoreAddr,
+		Data: data,
+	}
+	if _, err := w.client.CallContract(ctx, msg, nil); err != nil {
+		return &FirelightPreview{RevertReason: err.Error()}
+	}
+	gas, err := w.client.EstimateGas(ctx, msg)
+	if err != nil {
+		return &FirelightPreview{RevertReason: err.Error()}
+	}
+
+	return &FirelightPreview{WouldSucceed: true, GasEstimate: gas}
+}
+
+// approvalPath is the file Approve writes to approve a trigger
+func (w *FirelightWatcher) approvalPath(redemptionID uint64) string {
+	return filepath.Join(w.reportDir, fmt.Sprintf("redemption-%d.approved", redemptionID))
+}
+
+// approval returns the approval for a trigger, or nil if there is none. A
+// file that is not an approval written by Approve (e.g. one created by hand)
+// is accepted with an unknown operator so the audit journal shows it.
+func (w *FirelightWatcher) approval(redemptionID uint64) (*FirelightApproval, error) {
+	path := w.approvalPath(redemptionID)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approval: %w", err)
+	}
+
+	var approval FirelightApproval
+	if err := json.Unmarshal(data, &approval); err != nil || approval.Operator == "" {
+		approval = FirelightApproval{Operator: "unknown (approval file)"}
+		if info, err := os.Stat(path); err == nil {
+			approval.Time = info.ModTime().UTC()
+		}
+	}
+	return &approval, nil
+}
+
+// Approve records an operator's approval for a trigger awaiting approval.
+// The next scan consumes it and triggers Firelight.
+func (w *FirelightWatcher) Approve(redemptionID uint64, operator, reason string) (*FirelightIncident, error) {
+	incident, err := w.loadIncident(redemptionID)
+	if err != nil {
+		return nil, err
+	}
+	if incident == nil {
+		return nil, fmt.Errorf("no Firelight incident for redemption %d", redemptionID)
+	}
+	if incident.State != IncidentAwaitingApproval {
+		return incident, fmt.Errorf("Firelight incident for redemption %d is %s, not %s", redemptionID, incident.State, IncidentAwaitingApproval)
+	}
+
+	data, err := json.MarshalIndent(FirelightApproval{Operator: operator, Reason: reason, Time: time.Now().UTC()}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	path := w.approvalPath(redemptionID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write approval: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Uint64("redemption_id", redemptionID).
+		Str("operator", operator).
+		Msg("Firelight trigger approved")
+	return incident, nil
+}
+
+func (w *FirelightWatcher) reportPath(redemptionID uint64) string {
+	return filepath.Join(w.reportDir, fmt.Sprintf("redemption-%d.json", redemptionID))
+}
+
+// loadIncident reads an existing report, or returns nil if there is none
+func (w *FirelightWatcher) loadIncident(redemptionID uint64) (*FirelightIncident, error) {
+	data, err := os.ReadFile(w.reportPath(redemptionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read incident report: %w", err)
+	}
+
+	var incident FirelightIncident
+	if err := json.Unmarshal(data, &incident); err != nil {
+		return nil, fmt.Errorf("failed to decode incident report: %w", err)
+	}
+	return &incident, nil
+}
+
+// saveIncident writes the report atomically
+func (w *FirelightWatcher) saveIncident(incident *FirelightIncident) error {
+	data, err := json.MarshalIndent(incident, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := w.reportPath(incident.RedemptionID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write incident report: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
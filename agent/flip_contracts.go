@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog/log"
+)
+
+// FLIP contract names, matching the Solidity contract names
+const (
+	ContractFLIPCore          = "FLIPCore"
+	ContractEscrowVault       = "EscrowVault"
+	ContractLPRegistry        = "LiquidityProviderRegistry"
+	ContractOperatorRegistry  = "OperatorRegistry"
+	ContractSettlementReceipt = "SettlementReceipt"
+	ContractBlazeVault        = "BlazeFLIPVault"
+	ContractInsurancePool     = "InsurancePool"
+)
+
+// flipContractNames lists every FLIP contract in the order they are resolved
+var flipContractNames = []string{
+	ContractFLIPCore,
+	ContractEscrowVault,
+	ContractSettlementReceipt,
+	ContractLPRegistry,
+	ContractOperatorRegistry,
+	ContractBlazeVault,
+	ContractInsurancePool,
+}
+
+// requiredFlipContracts must be configured or derivable from FLIPCore
+var requiredFlipContracts = []string{
+	ContractFLIPCore,
+	ContractEscrowVault,
+	ContractLPRegistry,
+	ContractOperatorRegistry,
+}
+
+// restartFlipContracts are bound by long-running components at startup, so
+// changing them on reload is refused
+var restartFlipContracts = []string{
+	ContractFLIPCore,
+	ContractEscrowVault,
+}
+
+// flipReference is an address getter on one FLIP contract that must point at
+// another. References are also used, in order, to fill unconfigured addresses.
+type flipReference struct {
+	contract string
+	getter   string
+	target   string
+}
+
+var flipReferences = []flipReference{
+	{ContractFLIPCore, "escrowVault", ContractEscrowVault},
+	{ContractFLIPCore, "settlementReceipt", ContractSettlementReceipt},
+	{ContractFLIPCore, "lpRegistry", ContractLPRegistry},
+	{ContractFLIPCore, "operatorRegistry", ContractOperatorRegistry},
+	{ContractEscrowVault, "flipCore", ContractFLIPCore},
+	{ContractEscrowVault, "settlementReceipt", ContractSettlementReceipt},
+	{ContractSettlementReceipt, "flipCore", ContractFLIPCore},
+	{ContractSettlementReceipt, "escrowVault", ContractEscrowVault},
+	{ContractLPRegistry, "flipCore", ContractFLIPCore},
+	{ContractLPRegistry, "escrowVault", ContractEscrowVault},
+	{ContractLPRegistry, "backstopVault", ContractBlazeVault},
+	{ContractBlazeVault, "lpRegistry", ContractLPRegistry},
+}
+
+const flipReferenceABI = `[
+	{"inputs":[],"name":"escrowVault","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"settlementReceipt","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"lpRegistry","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"operatorRegistry","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"flipCore","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"backstopVault","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"}
+]`
+
+// FlipContracts holds the FLIP contract addresses from config.yaml, checked
+// against the chain: every address must have code and the contracts must
+// point at each other. Unset addresses are read from the contracts that
+// reference them.
+type FlipContracts struct {
+	client *ethclient.Client
+	parsed abi.ABI
+
+	mu        sync.RWMutex
+	addresses map[string]common.Address
+}
+
+// NewFlipContracts creates the registry. Call Resolve before use.
+func NewFlipContracts(client *ethclient.Client) (*FlipContracts, error) {
+	parsed, err := abi.JSON(strings.NewReader(flipReferenceABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FLIP reference ABI: %w", err)
+	}
+
+	return &FlipContracts{
+		client:    client,
+		parsed:    parsed,
+		addresses: make(map[string]common.Address),
+	}, nil
+}
+
+// configuredFlipAddresses parses the addresses set in config.yaml
+func configuredFlipAddresses(flare FlareConfig) (map[string]common.Address, error) {
+	configured := make(map[string]common.Address)
+	for _, entry := range []struct {
+		name, key, value string
+	}{
+		{ContractFLIPCore, "flip_core_address", flare.FLIPCoreAddress},
+		{ContractEscrowVault, "escrow_vault_address", flare.EscrowVaultAddress},
+		{ContractLPRegistry, "lp_registry_address", flare.LPRegistryAddress},
+		{ContractOperatorRegistry, "operator_registry_address", flare.OperatorRegistryAddress},
+		{ContractSettlementReceipt, "settlement_receipt_address", flare.SettlementReceiptAddress},
+		{ContractBlazeVault, "blaze_vault_address", flare.BlazeVaultAddress},
+		{ContractInsurancePool, "insurance_pool_address", flare.InsurancePoolAddress},
+	} {
+		if entry.value == "" {
+			continue
+		}
+		if !common.IsHexAddress(entry.value) {
+			return nil, fmt.Errorf("flare.%s is not a valid address: %q", entry.key, entry.value)
+		}
+		configured[entry.name] = common.HexToAddress(entry.value)
+	}
+	return configured, nil
+}
+
+// Resolve loads the configured addresses, fills unset ones from on-chain
+// references and validates the result
+func (f *FlipContracts) Resolve(ctx context.Context, flare FlareConfig) error {
+	addresses, err := f.resolve(ctx, flare)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.addresses = addresses
+	f.mu.Unlock()
+
+	for _, name := range flipContractNames {
+		if addr, ok := addresses[name]; ok {
+			log.Info().Str("contract", name).Str("address", addr.Hex()).Msg("FLIP contract verified")
+		}
+	}
+	return nil
+}
+
+// Reload re-resolves from a new config and swaps in the result. FLIPCore and
+// EscrowVault cannot change without a restart; any validation failure keeps
+// the current addresses.
+func (f *FlipContracts) Reload(ctx context.Context, flare FlareConfig) error {
+	addresses, err := f.resolve(ctx, flare)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, name := range restartFlipContracts {
+		if addresses[name] != f.addresses[name] {
+			return fmt.Errorf("%s changed from %s to %s, restart the agent to apply", name, f.addresses[name].Hex(), addresses[name].Hex())
+		}
+	}
+
+	for _, name := range flipContractNames {
+		if addresses[name] != f.addresses[name] {
+			log.Info().
+				Str("contract", name).
+				Str("previous", f.addresses[name].Hex()).
+				Str("address", addresses[name].Hex()).
+				Msg("FLIP contract address reloaded")
+		}
+	}
+	f.addresses = addresses
+	return nil
+}
+
+// Address returns a contract's address, or the zero address if it is not
+// configured
+func (f *FlipContracts) Address(name string) common.Address {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.addresses[name]
+}
+
+// Fill writes resolved addresses into unset config fields so components that
+// read the config at construction see derived addresses too
+func (f *FlipContracts) Fill(flare *FlareConfig) {
+	for name, field := range map[string]*string{
+		ContractFLIPCore:          &flare.FLIPCoreAddress,
+		ContractEscrowVault:       &flare.EscrowVaultAddress,
+		ContractLPRegistry:        &flare.LPRegistryAddress,
+		ContractOperatorRegistry:  &flare.OperatorRegistryAddress,
+		ContractSettlementReceipt: &flare.SettlementReceiptAddress,
+		ContractBlazeVault:        &flare.BlazeVaultAddress,
+		ContractInsurancePool:     &flare.InsurancePoolAddress,
+	} {
+		if addr := f.Address(name); *field == "" && addr != (common.Address{}) {
+			*field = addr.Hex()
+		}
+	}
+}
+
+func (f *FlipContracts) resolve(ctx context.Context, flare FlareConfig) (map[string]common.Address, error) {
+	addresses, err := configuredFlipAddresses(flare)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := addresses[ContractFLIPCore]; !ok {
+		return nil, fmt.Errorf("flare.flip_core_address is required")
+	}
+
+	// Fill unset addresses from the contracts that reference them
+	for _, ref := range flipReferences {
+		source, ok := addresses[ref.contract]
+		if _, known := addresses[ref.target]; known || !ok {
+			continue
+		}
+		target, err := f.readReference(ctx, source, ref.getter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive %s from %s.%s: %w", ref.target, ref.contract, ref.getter, err)
+		}
+		if target != (common.Address{}) {
+			log.Info().
+				Str("contract", ref.target).
+				Str("address", target.Hex()).
+				Str("source", ref.contract+"."+ref.getter).
+				Msg("FLIP contract address derived")
+			addresses[ref.target] = target
+		}
+	}
+
+	var problems []string
+	for _, name := range requiredFlipContracts {
+		if _, ok := addresses[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s is not configured and could not be derived", name))
+		}
+	}
+
+	for _, name := range flipContractNames {
+		addr, ok := addresses[name]
+		if !ok {
+			continue
+		}
+		code, err := f.client.CodeAt(ctx, addr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s code: %w", name, err)
+		}
+		if len(code) == 0 {
+			problems = append(problems, fmt.Sprintf("%s has no code at %s", name, addr.Hex()))
+		}
+	}
+
+	for _, ref := range flipReferences {
+		source, ok := addresses[ref.contract]
+		expected, known := addresses[ref.target]
+		if !ok || !known {
+			continue
+		}
+		actual, err := f.readReference(ctx, source, ref.getter)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s.%s: %v", ref.contract, ref.getter, err))
+			continue
+		}
+		if actual != expected {
+			problems = append(problems, fmt.Sprintf("%s.%s is %s, configured %s is %s", ref.contract, ref.getter, actual.Hex(), ref.target, expected.Hex()))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New("FLIP contract validation failed: " + strings.Join(problems, "; "))
+	}
+	return addresses, nil
+}
+
+func (f *FlipContracts) readReference(ctx context.Context, contract common.Address, getter string) (common.Address, error) {
+	bound := bind.NewBoundContract(contract, f.parsed, f.client, f.client, f.client)
+	var result []interface{}
+	if err := bound.Call(&bind.CallOpts{Context: ctx}, &result, getter); err != nil {
+		return common.Address{}, err
+	}
+	return result[0].(common.Address), nil
+}
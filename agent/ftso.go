@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/flip-protocol/scoring"
+	"github.com/rs/zerolog/log"
+)
+
+// Volatility tracker defaults used when the config leaves them unset
+const (
+	defaultFeedName         = "XRP/USD"
+	defaultSampleInterval   = 30 * time.Second
+	defaultVolatilityWindow = 60
+	defaultVolatilityScaled = 20000 // 2%, enough to keep requests off the fast lane
+	minVolatilitySamples    = 5
+	backfillBlocksPerSample = 20
+)
+
+// ftsoCategoryCrypto is the FTSOv2 feed ID category byte for crypto feeds
+const ftsoCategoryCrypto byte = 0x01
+
+const ftsoV2ABI = `[{
+	"inputs": [{"name": "_feedId", "type": "bytes21"}],
+	"name": "getFeedById",
+	"outputs": [
+		{"name": "value", "type": "uint256"},
+		{"name": "decimals", "type": "int8"},
+		{"name": "timestamp", "type": "uint64"}
+	],
+	"stateMutability": "payable",
+	"type": "function"
+}]`
+
+// feedIDFromName encodes an FTSOv2 crypto feed name such as "XRP/USD" as its
+// bytes21 feed ID
+func feedIDFromName(name string) [21]byte {
+	var id [21]byte
+	id[0] = ftsoCategoryCrypto
+	copy(id[1:], name)
+	return id
+}
+
+// VolatilityTracker samples FTSOv2 feeds and reports price volatility per
+// asset, scaled so that 1000000 = 100%
+type VolatilityTracker struct {
+	client      *ethclient.Client
+	contracts   *ContractResolver
+	parsed      abi.ABI
+	interval    time.Duration
+	window      int
+	fallback    *big.Int
+	defaultFeed string
+	assetFeeds  map[common.Address]string
+
+	mu      sync.RWMutex
+	samples map[string][]*big.Int // Feed name -> recent prices, oldest first
+}
+
+// NewVolatilityTracker creates a tracker from the scoring config
+func NewVolatilityTracker(client *ethclient.Client, contracts *ContractResolver, config *Config) (*VolatilityTracker, error) {
+	parsed, err := abi.JSON(strings.NewReader(ftsoV2ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FtsoV2 ABI: %w", err)
+	}
+
+	interval := time.Duration(config.Scoring.SampleInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultSampleInterval
+	}
+	window := config.Scoring.VolatilityWindow
+	if window < minVolatilitySamples {
+		window = defaultVolatilityWindow
+	}
+	fallback := big.NewInt(defaultVolatilityScaled)
+	if config.Scoring.DefaultVolatility > 0 {
+		fallback = new(big.Int).SetUint64(config.Scoring.DefaultVolatility)
+	}
+	defaultFeed := config.Scoring.DefaultFeed
+	if defaultFeed == "" {
+		defaultFeed = defaultFeedName
+	}
+
+	assetFeeds := make(map[common.Address]string)
+	for asset, feed := range config.Scoring.Feeds {
+		assetFeeds[common.HexToAddress(asset)] = feed
+	}
+
+	return &VolatilityTracker{
+		client:      client,
+		contracts:   contracts,
+		parsed:      parsed,
+		interval:    interval,
+		window:      window,
+		fallback:    fallback,
+		defaultFeed: defaultFeed,
+		assetFeeds:  assetFeeds,
+		samples:     make(map[string][]*big.Int),
+	}, nil
+}
+
+// feeds returns every feed the tracker samples
+func (t *VolatilityTracker) feeds() []string {
+	seen := map[string]bool{t.defaultFeed: true}
+	feeds := []string{t.defaultFeed}
+	for _, feed := range t.assetFeeds {
+		if !seen[feed] {
+			seen[feed] = true
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds
+}
+
+// Run backfills recent history and then samples every interval until ctx is
+// cancelled
+func (t *VolatilityTracker) Run(ctx context.Context) {
+	t.backfill(ctx)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, feed := range t.feeds() {
+				price, err := t.readPrice(ctx, feed, nil)
+				if err != nil {
+					log.Debug().Err(err).Str("feed", feed).Msg("FTSO sample failed")
+					continue
+				}
+				t.addSample(feed, price)
+			}
+		}
+	}
+}
+
+// backfill reads feed values at past blocks so volatility is available right
+// after startup. Nodes without historical state simply leave the window empty.
+func (t *VolatilityTracker) backfill(ctx context.Context) {
+	head, err := t.client.BlockNumber(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, feed := range t.feeds() {
+		loaded := 0
+		for i := t.window - 1; i >= 0; i-- {
+			offset := uint64(i * backfillBlocksPerSample)
+			if offset > head {
+				continue
+			}
+			price, err := t.readPrice(ctx, feed, new(big.Int).SetUint64(head-offset))
+			if err != nil {
+				continue
+			}
+			t.addSample(feed, price)
+			loaded++
+		}
+		log.Info().Str("feed", feed).Int("samples", loaded).Msg("FTSO volatility window backfilled")
+	}
+}
+
+// readPrice reads a feed value, optionally at a past block
+func (t *VolatilityTracker) readPrice(ctx context.Context, feed string, block *big.Int) (*big.Int, error) {
+	addr := t.contracts.Address(ContractFtsoV2)
+	if addr == (common.Address{}) {
+		return nil, fmt.Errorf("FtsoV2 not resolved")
+	}
+
+	contract := bind.NewBoundContract(addr, t.parsed, t.client, t.client, t.client)
+	var result []interface{}
+	if err := contract.Call(&bind.CallOpts{Context: ctx, BlockNumber: block}, &result, "getFeedById", feedIDFromName(feed)); err != nil {
+		return nil, fmt.Errorf("failed to read feed %s: %w", feed, err)
+	}
+
+	// Volatility is a ratio, so the feed's decimals do not matter
+	return result[0].(*big.Int), nil
+}
+
+func (t *VolatilityTracker) addSample(feed string, price *big.Int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples := append(t.samples[feed], price)
+	if len(samples) > t.window {
+		samples = samples[len(samples)-t.window:]
+	}
+	t.samples[feed] = samples
+}
+
+// Volatility returns the current volatility for an asset's feed. Without
+// enough samples it returns the configured conservative default.
+func (t *VolatilityTracker) Volatility(asset common.Address) (*big.Int, string, bool) {
+	feed, ok := t.assetFeeds[asset]
+	if !ok {
+		feed = t.defaultFeed
+	}
+
+	t.mu.RLock()
+	samples := t.samples[feed]
+	t.mu.RUnlock()
+
+	if len(samples) < minVolatilitySamples {
+		return new(big.Int).Set(t.fallback), feed, false
+	}
+	return scoring.GetPriceVolatility(samples), feed, true
+}
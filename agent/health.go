@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+// readinessTimeout bounds each dependency probe in /readyz
+const readinessTimeout = 5 * time.Second
+
+// HealthCheck is one entry in the /healthz or /readyz response
+type HealthCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// HealthServer serves /metrics, /healthz and /readyz.
+//
+// /healthz is liveness: it fails when an event cursor stops polling, so a
+// stalled agent is restarted. /readyz is readiness: Flare RPC and XRPL are
+// reachable, the key is loaded and the agent holds its FLIPCore role.
+type HealthServer struct {
+	agent          *Agent
+	stallThreshold time.Duration
+	server         *http.Server
+}
+
+// NewHealthServer creates a server listening on config.Metrics.ListenAddr
+func NewHealthServer(agent *Agent, config *Config) *HealthServer {
+	hs := &HealthServer{
+		agent:          agent,
+		stallThreshold: time.Duration(config.Metrics.StallThreshold) * time.Second,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", hs.handleHealthz)
+	mux.HandleFunc("/readyz", hs.handleReadyz)
+
+	hs.server = &http.Server{
+		Addr:              config.Metrics.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return hs
+}
+
+// Run serves until ctx is cancelled
+func (hs *HealthServer) Run(ctx context.Context) error {
+	errChan := make(chan error, 1)
+	go func() {
+		log.Info().Str("addr", hs.server.Addr).Msg("Metrics and health server listening")
+		if err := hs.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errChan <- fmt.Errorf("metrics server failed: %w", err)
+		}
+		close(errChan)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return hs.server.Shutdown(shutdownCtx)
+	}
+}
+
+func (hs *HealthServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	check := HealthCheck{Name: "event_cursors", OK: true}
+	if stalled := hs.agent.eventMonitor.StalledCursors(hs.stallThreshold); len(stalled) > 0 {
+		check.OK = false
+		check.Error = fmt.Sprintf("no successful poll within %s: %s", hs.stallThreshold, strings.Join(stalled, ", "))
+	}
+	writeHealth(w, []HealthCheck{check})
+}
+
+func (hs *HealthServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	writeHealth(w, hs.agent.Readiness(ctx))
+}
+
+// writeHealth responds 200 when every check passed and 503 otherwise
+func writeHealth(w http.ResponseWriter, checks []HealthCheck) {
+	status := http.StatusOK
+	for _, check := range checks {
+		if !check.OK {
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     status == http.StatusOK,
+		"checks": checks,
+	})
+}
+
+// Readiness probes the agent's dependencies
+func (a *Agent) Readiness(ctx context.Context) []HealthCheck {
+	probe := func(name string, err error) HealthCheck {
+		if err != nil {
+			return HealthCheck{Name: name, Error: err.Error()}
+		}
+		return HealthCheck{Name: name, OK: true}
+	}
+
+	_, rpcErr := a.flareClient.BlockNumber(ctx)
+	_, xrplErr := a.paymentProc.xrplClient.GetBalance(ctx)
+
+	var keyErr error
+	if a.config.Flare.PrivateKey == "" {
+		keyErr = errors.New("PRIVATE_KEY is not set")
+	} else if _, err := crypto.HexToECDSA(strings.TrimPrefix(a.config.Flare.PrivateKey, "0x")); err != nil {
+		keyErr = fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	var roleErr error
+	if !a.roleVerified.Load() {
+		roleErr = errors.New("agent is neither FLIPCore owner nor a registered operator")
+	}
+
+	return []HealthCheck{
+		probe("flare_rpc", rpcErr),
+		probe("xrpl", xrplErr),
+		probe("key_loaded", keyErr),
+		probe("role_verified", roleErr),
+	}
+}
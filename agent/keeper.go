@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/flip-protocol/agent/bindings"
+	"github.com/rs/zerolog/log"
+)
+
+// Keeper defaults used when the config leaves them unset
+const (
+	defaultKeeperInterval   = 60 * time.Second
+	defaultKeeperOutcomeLog = "keeper_outcomes.jsonl"
+)
+
+// Keeper outcome statuses
+const (
+	KeeperOutcomeTimedOut   = "timed_out"
+	KeeperOutcomeFailed     = "failed"
+	KeeperOutcomeGasSkipped = "skipped_gas_price"
+)
+
+// KeeperOutcome records a single timeout attempt
+type KeeperOutcome struct {
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"` // "redemption" or "minting"
+	ID       uint64    `json:"id"`
+	Method   string    `json:"method"`
+	Status   string    `json:"status"`
+	TxHash   string    `json:"tx_hash,omitempty"`
+	GasPrice string    `json:"gas_price,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// TimeoutKeeper calls FLIPCore.checkTimeout and checkMintingTimeout once
+// EscrowVault reports the escrow window has passed
+type TimeoutKeeper struct {
+	submitter       *FDCSubmitter
+	multicall       *Multicaller
+	flipCore        *bindings.FLIPCoreCaller
+	flipCoreAddr    common.Address
+	escrowVaultAddr common.Address
+	flipCoreABI     *abi.ABI
+	escrowABI       *abi.ABI
+	interval        time.Duration
+	maxGasPrice     *big.Int // nil = no ceiling
+	outcomeLog      string
+
+	// Lowest IDs that were not in a terminal state at the end of the last
+	// scan; everything below them is settled and is not read again
+	redemptionLowWater uint64
+	mintingLowWater    uint64
+
+	mu sync.Mutex // Serializes writes to outcomeLog
+}
+
+// NewTimeoutKeeper creates a keeper from the config
+func NewTimeoutKeeper(client *ethclient.Client, submitter *FDCSubmitter, multicall *Multicaller, config *Config) (*TimeoutKeeper, error) {
+	flipCoreABI, err := bindings.ABI(ContractFLIPCore)
+	if err != nil {
+		return nil, err
+	}
+	escrowABI, err := bindings.ABI(ContractEscrowVault)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := time.Duration(config.Keeper.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultKeeperInterval
+	}
+
+	var maxGasPrice *big.Int
+	if config.Keeper.MaxGasPriceGwei > 0 {
+		maxGasPrice = new(big.Int).Mul(new(big.Int).SetUint64(config.Keeper.MaxGasPriceGwei), big.NewInt(1e9))
+	}
+
+	outcomeLog := config.Keeper.OutcomeLog
+	if outcomeLog == "" {
+		outcomeLog = defaultKeeperOutcomeLog
+	}
+
+	flipCoreAddr := common.HexToAddress(config.Flare.FLIPCoreAddress)
+	flipCore, err := bindings.NewFLIPCoreCaller(flipCoreAddr, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind FLIPCore: %w", err)
+	}
+
+	return &TimeoutKeeper{
+		submitter:       submitter,
+		multicall:       multicall,
+		flipCore:        flipCore,
+		flipCoreAddr:    flipCoreAddr,
+		escrowVaultAddr: common.HexToAddress(config.Flare.EscrowVaultAddress),
+		flipCoreABI:     flipCoreABI,
+		escrowABI:       escrowABI,
+		interval:        interval,
+		maxGasPrice:     maxGasPrice,
+		outcomeLog:      outcomeLog,
+	}, nil
+}
+
+// Run scans for eligible timeouts every interval until ctx is cancelled
+func (k *TimeoutKeeper) Run(ctx context.Context) {
+	log.Info().
+		Dur("interval", k.interval).
+		Str("outcome_log", k.outcomeLog).
+		Msg("Timeout keeper started")
+
+	ticker := time.NewTicker(k.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := k.Scan(ctx); err != nil {
+			log.Warn().Err(err).Msg("Timeout keeper scan failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan checks every open redemption and minting escrow once, starting from
+// the low-water marks. Reads are batched through Multicall3.
+func (k *TimeoutKeeper) Scan(ctx context.Context) error {
+	opts := &bind.CallOpts{Context: ctx}
+	nextRedemption, err := k.flipCore.NextRedemptionId(opts)
+	if err != nil {
+		return fmt.Errorf("failed to call nextRedemptionId: %w", err)
+	}
+	nextMinting, err := k.flipCore.NextMintingId(opts)
+	if err != nil {
+		return fmt.Errorf("failed to call nextMintingId: %w", err)
+	}
+
+	// A redeployed FLIPCore starts from zero again
+	if k.redemptionLowWater > nextRedemption.Uint64() || k.mintingLowWater > nextMinting.Uint64() {
+		k.redemptionLowWater, k.mintingLowWater = 0, 0
+	}
+
+	lowWater, err := k.scanRedemptions(ctx, k.redemptionLowWater, nextRedemption.Uint64())
+	if err != nil {
+		return err
+	}
+	k.redemptionLowWater = lowWater
+
+	lowWater, err = k.scanMintings(ctx, k.mintingLowWater, nextMinting.Uint64())
+	if err != nil {
+		return err
+	}
+	k.mintingLowWater = lowWater
+	return nil
+}
+
+// scanRedemptions times out redemptions [from, to) whose escrow window has
+// passed and returns the new low-water mark
+func (k *TimeoutKeeper) scanRedemptions(ctx context.Context, from, to uint64) (uint64, error) {
+	var calls []BatchCall
+	for i := from; i < to; i++ {
+		id := new(big.Int).SetUint64(i)
+		calls = append(calls,
+			BatchCall{Target: k.flipCoreAddr, ABI: k.flipCoreABI, Method: "getRedemptionStatus", Args: []interface{}{id}},
+			BatchCall{Target: k.escrowVaultAddr, ABI: k.escrowABI, Method: "canTimeout", Args: []interface{}{id}},
+			BatchCall{Target: k.flipCoreAddr, ABI: k.flipCoreABI, Method: "redemptionXrplTxHash", Args: []interface{}{id}},
+		)
+	}
+	results, err := k.multicall.Call(ctx, calls)
+	if err != nil {
+		return from, err
+	}
+
+	lowWater := to
+	for i := from; i < to; i++ {
+		base := 3 * (i - from)
+		var status uint8
+		if err := results[base].Decode(&status); err != nil {
+			lowWater = min(lowWater, i)
+			continue
+		}
+		if status < 4 { // Not Finalized, Failed or Timeout
+			lowWater = min(lowWater, i)
+		}
+		if status != 2 { // 2 = EscrowCreated
+			continue
+		}
+
+		var eligible bool
+		if err := results[base+1].Decode(&eligible); err != nil || !eligible {
+			continue
+		}
+
+		// A recorded XRPL payment is finalized through FDC instead; timing it out
+		// would refund an escrow the user has already been paid from
+		var txHash string
+		if err := results[base+2].Decode(&txHash); err == nil && txHash != "" {
+			log.Debug().
+				Uint64("redemption_id", i).
+				Str("xrpl_tx_hash", txHash).
+				Msg("Timed-out redemption has a recorded payment, leaving it to FDC")
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return lowWater, ctx.Err()
+		}
+		k.timeout(ctx, "redemption", "checkTimeout", new(big.Int).SetUint64(i))
+	}
+	return lowWater, nil
+}
+
+// scanMintings times out provisionally settled mintings [from, to) past
+// their window and returns the new low-water mark
+func (k *TimeoutKeeper) scanMintings(ctx context.Context, from, to uint64) (uint64, error) {
+	var calls []BatchCall
+	for i := from; i < to; i++ {
+		id := new(big.Int).SetUint64(i)
+		calls = append(calls,
+			BatchCall{Target: k.flipCoreAddr, ABI: k.flipCoreABI, Method: "getMintingStatus", Args: []interface{}{id}},
+			BatchCall{Target: k.escrowVaultAddr, ABI: k.escrowABI, Method: "canMintingTimeout", Args: []interface{}{id}},
+		)
+	}
+	results, err := k.multicall.Call(ctx, calls)
+	if err != nil {
+		return from, err
+	}
+
+	lowWater := to
+	for i := from; i < to; i++ {
+		base := 2 * (i - from)
+		var status uint8
+		if err := results[base].Decode(&status); err != nil {
+			lowWater = min(lowWater, i)
+			continue
+		}
+		if status < 3 { // Not Finalized, Failed or Timeout
+			lowWater = min(lowWater, i)
+		}
+		if status != 1 { // 1 = ProvisionalSettled
+			continue
+		}
+
+		var eligible bool
+		if err := results[base+1].Decode(&eligible); err != nil || !eligible {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return lowWater, ctx.Err()
+		}
+		k.timeout(ctx, "minting", "checkMintingTimeout", new(big.Int).SetUint64(i))
+	}
+	return lowWater, nil
+}
+
+// timeout sends the timeout transaction and records the outcome
+func (k *TimeoutKeeper) timeout(ctx context.Context, kind, method string, id *big.Int) {
+	outcome := KeeperOutcome{Kind: kind, ID: id.Uint64(), Method: method}
+
+	tx, err := k.submitter.transactFLIPCoreCapped(ctx, k.maxGasPrice, method, 300000, id)
+	switch {
+	case errors.Is(err, ErrGasPriceAboveCeiling):
+		outcome.Status = KeeperOutcomeGasSkipped
+		outcome.Error = err.Error()
+	case err != nil:
+		outcome.Status = KeeperOutcomeFailed
+		outcome.Error = err.Error()
+	default:
+		outcome.Status = KeeperOutcomeTimedOut
+		outcome.TxHash = tx.Hash().Hex()
+		outcome.GasPrice = tx.GasPrice().String()
+	}
+
+	k.record(outcome)
+}
+
+// record logs an outcome and appends it to the outcome log
+func (k *TimeoutKeeper) record(outcome KeeperOutcome) {
+	outcome.Time = time.Now().UTC()
+
+	event := log.Info()
+	if outcome.Status == KeeperOutcomeFailed {
+		event = log.Error()
+	} else if outcome.Status == KeeperOutcomeGasSkipped {
+		event = log.Warn()
+	}
+	event.
+		Str("kind", outcome.Kind).
+		Uint64("id", outcome.ID).
+		Str("method", outcome.Method).
+		Str("status", outcome.Status).
+		Str("tx_hash", outcome.TxHash).
+		Str("error", outcome.Error).
+		Msg("Timeout keeper outcome")
+
+	line, err := json.Marshal(outcome)
+	if err != nil {
+		return
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	f, err := os.OpenFile(k.outcomeLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Warn().Err(err).Str("path", k.outcomeLog).Msg("Failed to open keeper outcome log")
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Warn().Err(err).Str("path", k.outcomeLog).Msg("Failed to write keeper outcome")
+	}
+}
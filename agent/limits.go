@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Risk limit defaults used when the config leaves them unset
+const (
+	defaultLimitsStateFile   = "limits_state.json"
+	defaultLimitsDecisionLog = "approvals.jsonl"
+)
+
+// Approval statuses
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// Outflow is one XRPL payout counted against the rolling limits
+type Outflow struct {
+	Time         time.Time `json:"time"`
+	RedemptionID uint64    `json:"redemption_id"`
+	Destination  string    `json:"destination"`
+	Drops        string    `json:"drops"`
+	Pending      bool      `json:"pending,omitempty"` // Reserved by Check, not yet confirmed sent
+}
+
+// Approval is a redemption held in the manual approval queue
+type Approval struct {
+	RedemptionID uint64     `json:"redemption_id"`
+	Destination  string     `json:"destination"`
+	Drops        string     `json:"drops"`
+	Reasons      []string   `json:"reasons"` // Limits the payout would exceed
+	Status       string     `json:"status"`
+	QueuedAt     time.Time  `json:"queued_at"`
+	Operator     string     `json:"operator,omitempty"`
+	Note         string     `json:"note,omitempty"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty"`
+}
+
+// ApprovalDecision is one line of the decision log
+type ApprovalDecision struct {
+	Time         time.Time `json:"time"`
+	RedemptionID uint64    `json:"redemption_id"`
+	Status       string    `json:"status"`
+	Operator     string    `json:"operator,omitempty"` // Empty when the agent queued the redemption
+	Destination  string    `json:"destination"`
+	Drops        string    `json:"drops"`
+	Reasons      []string  `json:"reasons,omitempty"`
+	Note         string    `json:"note,omitempty"`
+}
+
+// limitsState is the persisted form of RiskLimiter
+type limitsState struct {
+	Outflows  []Outflow  `json:"outflows"`
+	Approvals []Approval `json:"approvals"`
+}
+
+// RiskLimiter caps XRPL payouts per redemption, per destination per day and
+// in aggregate per hour. A payout over any limit goes into the approval queue
+// and is only paid once an operator approves it. Limits come from
+// LiveSettings, so SIGHUP changes them.
+type RiskLimiter struct {
+	settings    *LiveSettings
+	path        string
+	decisionLog string
+
+	mu        sync.Mutex
+	outflows  []Outflow            // Payouts and reservations in the last 24 hours, oldest first
+	approvals map[uint64]*Approval // Pending, and decided within the last 24 hours
+}
+
+// NewRiskLimiter loads recent payouts and the approval queue from
+// limits.state_file
+func NewRiskLimiter(config *Config, settings *LiveSettings) (*RiskLimiter, error) {
+	path := config.Limits.StateFile
+	if path == "" {
+		path = defaultLimitsStateFile
+	}
+	decisionLog := config.Limits.DecisionLog
+	if decisionLog == "" {
+		decisionLog = defaultLimitsDecisionLog
+	}
+
+	l := &RiskLimiter{
+		settings:    settings,
+		path:        path,
+		decisionLog: decisionLog,
+		approvals:   make(map[uint64]*Approval),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read limits state: %w", err)
+	}
+
+	var state limitsState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode limits state: %w", err)
+	}
+	l.outflows = state.Outflows
+	for i := range state.Approvals {
+		a := state.Approvals[i]
+		l.approvals[a.RedemptionID] = &a
+	}
+	l.prune()
+	return l, nil
+}
+
+// Check decides whether a payout may be sent. It returns the approval entry
+// for the redemption, or nil when the payout is within every limit. A payout
+// over a limit is queued, and the returned entry is pending until an
+// operator decides. A payout that may be sent is reserved against the limits
+// straight away, so concurrent checks cannot both pass on the same headroom;
+// the caller confirms it with RecordPayout or gives it back with Release.
+func (l *RiskLimiter) Check(redemptionID uint64, destination string, amount *big.Int) *Approval {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.release(redemptionID)
+	if a, ok := l.approvals[redemptionID]; ok {
+		if a.Status == ApprovalApproved {
+			l.reserve(redemptionID, destination, amount)
+		}
+		copied := *a
+		return &copied
+	}
+
+	reasons := l.exceeded(destination, amount)
+	if len(reasons) == 0 {
+		l.reserve(redemptionID, destination, amount)
+		return nil
+	}
+
+	a := &Approval{
+		RedemptionID: redemptionID,
+		Destination:  destination,
+		Drops:        amount.String(),
+		Reasons:      reasons,
+		Status:       ApprovalPending,
+		QueuedAt:     time.Now().UTC(),
+	}
+	l.approvals[redemptionID] = a
+	l.save()
+	l.journal(ApprovalDecision{
+		RedemptionID: redemptionID,
+		Status:       ApprovalPending,
+		Destination:  destination,
+		Drops:        a.Drops,
+		Reasons:      reasons,
+	})
+
+	copied := *a
+	return &copied
+}
+
+// exceeded lists the limits a payout would break. Callers hold l.mu.
+func (l *RiskLimiter) exceeded(destination string, amount *big.Int) []string {
+	limits := l.settings.RiskLimits()
+	l.prune()
+
+	now := time.Now()
+	daily := new(big.Int)
+	hourly := new(big.Int)
+	for _, o := range l.outflows {
+		drops, ok := new(big.Int).SetString(o.Drops, 10)
+		if !ok {
+			continue
+		}
+		if o.Destination == destination {
+			daily.Add(daily, drops)
+		}
+		if now.Sub(o.Time) < time.Hour {
+			hourly.Add(hourly, drops)
+		}
+	}
+
+	var reasons []string
+	over := func(limit uint64, used *big.Int, format string) {
+		if limit == 0 {
+			return
+		}
+		total := new(big.Int).Add(used, amount)
+		if total.Cmp(new(big.Int).SetUint64(limit)) > 0 {
+			reasons = append(reasons, fmt.Sprintf(format, total, limit))
+		}
+	}
+	over(limits.MaxPerRedemption, new(big.Int), "amount %s drops exceeds the per-redemption limit of %d")
+	over(limits.MaxPerDestinationDaily, daily, "%s drops to this destination in 24h exceeds the daily limit of %d")
+	over(limits.MaxHourlyOutflow, hourly, "%s drops paid out in the last hour exceeds the hourly limit of %d")
+	return reasons
+}
+
+// RecordPayout confirms the reservation Check made for a sent payout, or
+// counts the payout if there was none
+func (l *RiskLimiter) RecordPayout(redemptionID uint64, destination string, amount *big.Int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.outflows {
+		if o := &l.outflows[i]; o.Pending && o.RedemptionID == redemptionID {
+			o.Pending = false
+			o.Destination = destination
+			o.Drops = amount.String()
+			l.prune()
+			l.save()
+			return
+		}
+	}
+	l.outflows = append(l.outflows, Outflow{
+		Time:         time.Now().UTC(),
+		RedemptionID: redemptionID,
+		Destination:  destination,
+		Drops:        amount.String(),
+	})
+	l.prune()
+	l.save()
+}
+
+// Release gives back the reservation for a payout that was not sent
+func (l *RiskLimiter) Release(redemptionID uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.release(redemptionID) {
+		l.save()
+	}
+}
+
+// reserve counts a payout about to be sent. Callers hold l.mu.
+func (l *RiskLimiter) reserve(redemptionID uint64, destination string, amount *big.Int) {
+	l.outflows = append(l.outflows, Outflow{
+		Time:         time.Now().UTC(),
+		RedemptionID: redemptionID,
+		Destination:  destination,
+		Drops:        amount.String(),
+		Pending:      true,
+	})
+	l.save()
+}
+
+// release drops a redemption's reservation, reporting whether it had one.
+// Callers hold l.mu.
+func (l *RiskLimiter) release(redemptionID uint64) bool {
+	for i, o := range l.outflows {
+		if o.Pending && o.RedemptionID == redemptionID {
+			l.outflows = append(l.outflows[:i], l.outflows[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Decide approves or rejects a queued redemption
+func (l *RiskLimiter) Decide(redemptionID uint64, approve bool, operator, note string) (Approval, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	a, ok := l.approvals[redemptionID]
+	if !ok {
+		return Approval{}, fmt.Errorf("redemption %d is not in the approval queue", redemptionID)
+	}
+	if a.Status != ApprovalPending {
+		return Approval{}, fmt.Errorf("redemption %d was already %s by %s", redemptionID, a.Status, a.Operator)
+	}
+
+	now := time.Now().UTC()
+	a.Status = ApprovalRejected
+	if approve {
+		a.Status = ApprovalApproved
+	}
+	a.Operator = operator
+	a.Note = note
+	a.DecidedAt = &now
+	l.save()
+	l.journal(ApprovalDecision{
+		RedemptionID: redemptionID,
+		Status:       a.Status,
+		Operator:     operator,
+		Destination:  a.Destination,
+		Drops:        a.Drops,
+		Reasons:      a.Reasons,
+		Note:         note,
+	})
+	return *a, nil
+}
+
+// Approvals returns queue entries with status, or all of them when status is
+// "", ordered by redemption ID
+func (l *RiskLimiter) Approvals(status string) []Approval {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := make([]Approval, 0, len(l.approvals))
+	for _, a := range l.approvals {
+		if status == "" || a.Status == status {
+			list = append(list, *a)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].RedemptionID < list[j].RedemptionID })
+	return list
+}
+
+// prune drops payouts and decided approvals older than the daily window.
+// Callers hold l.mu.
+func (l *RiskLimiter) prune() {
+	cutoff := time.Now().Add(-24 * time.Hour)
+	i := 0
+	for i < len(l.outflows) && l.outflows[i].Time.Before(cutoff) {
+		i++
+	}
+	l.outflows = l.outflows[i:]
+
+	for id, a := range l.approvals {
+		if a.DecidedAt != nil && a.DecidedAt.Before(cutoff) {
+			delete(l.approvals, id)
+		}
+	}
+}
+
+// journal logs a queue decision and appends it to the decision log. Callers
+// hold l.mu, which also serializes writes to the log.
+func (l *RiskLimiter) journal(decision ApprovalDecision) {
+	decision.Time = time.Now().UTC()
+
+	event := log.Info()
+	if decision.Status == ApprovalPending {
+		event = log.Warn()
+	}
+	event.
+		Uint64("redemption_id", decision.RedemptionID).
+		Str("status", decision.Status).
+		Str("operator", decision.Operator).
+		Str("destination", decision.Destination).
+		Str("drops", decision.Drops).
+		Strs("reasons", decision.Reasons).
+		Msg("Approval queue decision")
+
+	line, err := json.Marshal(decision)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(l.decisionLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Warn().Err(err).Str("path", l.decisionLog).Msg("Failed to open approval decision log")
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Warn().Err(err).Str("path", l.decisionLog).Msg("Failed to write approval decision")
+	}
+}
+
+// save writes the state atomically. Callers hold l.mu. Failures are logged
+// rather than returned so bookkeeping never blocks settlement.
+func (l *RiskLimiter) save() {
+	state := limitsState{Outflows: l.outflows, Approvals: make([]Approval, 0, len(l.approvals))}
+	for _, a := range l.approvals {
+		state.Approvals = append(state.Approvals, *a)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode limits state")
+		return
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Error().Err(err).Msg("Failed to write limits state")
+		return
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		log.Error().Err(err).Msg("Failed to write limits state")
+	}
+}
+
+// approvedForPayout checks the risk limits before an XRPL payment. A payout
+// over a limit waits in the approval queue; a rejected one is never paid, so
+// its escrow times out into the failure path.
+func (a *Agent) approvedForPayout(event EscrowCreatedEvent) bool {
+	id := event.RedemptionID.Uint64()
+	approval := a.limits.Check(id, event.XRPLAddress, event.Amount)
+	if approval == nil {
+		return true
+	}
+
+	switch approval.Status {
+	case ApprovalApproved:
+		log.Info().
+			Uint64("redemption_id", id).
+			Str("operator", approval.Operator).
+			Msg("Payout over risk limit was approved, paying")
+		return true
+	case ApprovalRejected:
+		log.Warn().
+			Uint64("redemption_id", id).
+			Str("operator", approval.Operator).
+			Msg("Payout was rejected by an operator, not paying")
+		return false
+	default:
+		if r, _ := a.workflows.Get(WorkflowRedemption, id); r.State != StateAwaitingApproval {
+			a.workflows.Record(WorkflowRedemption, id, StateAwaitingApproval, strings.Join(approval.Reasons, "; "))
+			a.notifier.Notify(EventApprovalNeeded, redemptionSubject(id),
+				"Payout over a risk limit is waiting for operator approval: "+strings.Join(approval.Reasons, "; "),
+				map[string]string{"destination": approval.Destination, "amount_drops": approval.Drops})
+		}
+		log.Warn().
+			Uint64("redemption_id", id).
+			Str("destination", approval.Destination).
+			Str("drops", approval.Drops).
+			Strs("reasons", approval.Reasons).
+			Msg("Payout over risk limit, waiting for operator approval")
+		return false
+	}
+}
+
+// DecideApproval records an operator's decision on a queued redemption, then
+// queues its next stage: an approved payout is sent, a rejected one is proven
+// unpaid through FDC so FLIPCore fails it into the Firelight backstop. The
+// decision stands even when the stage cannot be queued.
+func (a *Agent) DecideApproval(ctx context.Context, id uint64, approve bool, operator, note string) (Approval, error) {
+	approval, err := a.limits.Decide(id, approve, operator, note)
+	if err != nil {
+		return Approval{}, err
+	}
+	a.workflows.Note(WorkflowRedemption, id, approval.Status, note, operator)
+	stage := RetryPayment
+	if !approve {
+		a.workflows.Record(WorkflowRedemption, id, StateRejected, "payout rejected, proving non-payment")
+		stage = RetryDispute
+	}
+
+	op, err := a.PrepareRetry(ctx, WorkflowRedemption, id, stage)
+	if err != nil {
+		return approval, fmt.Errorf("decision recorded, %s stage not queued: %w", stage, err)
+	}
+	if err := a.enqueue(ctx, op); err != nil {
+		return approval, fmt.Errorf("decision recorded, agent is busy: %w", err)
+	}
+	return approval, nil
+}
+
+// rejected reports whether an operator rejected the redemption's payout. It
+// reads the workflow timeline, which outlives the approval queue's retention.
+func (a *Agent) rejected(id uint64) bool {
+	r, _ := a.workflows.Get(WorkflowRedemption, id)
+	for _, entry := range r.Timeline {
+		if entry.Event == StateRejected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestRiskLimiter(t *testing.T, limits LimitsConfig) *RiskLimiter {
+	t.Helper()
+	dir := t.TempDir()
+	limits.StateFile = filepath.Join(dir, "limits_state.json")
+	limits.DecisionLog = filepath.Join(dir, "approvals.jsonl")
+	config := &Config{Limits: limits}
+	l, err := NewRiskLimiter(config, NewLiveSettings(config))
+	if err != nil {
+		t.Fatalf("NewRiskLimiter: %v", err)
+	}
+	return l
+}
+
+func TestRiskLimiterCheckReservesHeadroom(t *testing.T) {
+	l := newTestRiskLimiter(t, LimitsConfig{MaxPerDestinationDaily: 150})
+
+	// The first payout is not sent yet, but the second cannot use its headroom
+	if a := l.Check(1, "rUser", big.NewInt(100)); a != nil {
+		t.Fatalf("first payout queued: %+v", a)
+	}
+	if a := l.Check(2, "rUser", big.NewInt(100)); a == nil || a.Status != ApprovalPending {
+		t.Fatalf("second payout = %+v, want pending", a)
+	}
+
+	// A failed payout gives its headroom back
+	l.Release(1)
+	if a := l.Check(3, "rUser", big.NewInt(100)); a != nil {
+		t.Fatalf("payout after release queued: %+v", a)
+	}
+	l.RecordPayout(3, "rUser", big.NewInt(100))
+	if len(l.outflows) != 1 || l.outflows[0].Pending {
+		t.Fatalf("outflows = %+v, want one confirmed payout", l.outflows)
+	}
+}
+
+func TestRiskLimiterRecheckDoesNotDoubleCount(t *testing.T) {
+	l := newTestRiskLimiter(t, LimitsConfig{MaxPerDestinationDaily: 150})
+
+	for i := 0; i < 2; i++ {
+		if a := l.Check(1, "rUser", big.NewInt(100)); a != nil {
+			t.Fatalf("check %d queued: %+v", i, a)
+		}
+	}
+	if len(l.outflows) != 1 {
+		t.Fatalf("outflows = %d, want 1 reservation", len(l.outflows))
+	}
+}
+
+func TestRiskLimiterExpiresDecidedApprovals(t *testing.T) {
+	l := newTestRiskLimiter(t, LimitsConfig{MaxPerRedemption: 50})
+
+	l.Check(1, "rUser", big.NewInt(100))
+	l.Check(2, "rUser", big.NewInt(100))
+	if _, err := l.Decide(1, false, "alice", "suspicious"); err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+
+	stale := time.Now().Add(-25 * time.Hour)
+	l.approvals[1].DecidedAt = &stale
+	l.mu.Lock()
+	l.prune()
+	l.save()
+	l.mu.Unlock()
+
+	if got := l.Approvals(""); len(got) != 1 || got[0].RedemptionID != 2 {
+		t.Fatalf("approvals = %+v, want only the pending redemption 2", got)
+	}
+
+	reloaded, err := NewRiskLimiter(&Config{Limits: LimitsConfig{StateFile: l.path}}, l.settings)
+	if err != nil {
+		t.Fatalf("NewRiskLimiter: %v", err)
+	}
+	if got := reloaded.Approvals(""); len(got) != 1 {
+		t.Fatalf("reloaded approvals = %+v, want 1", got)
+	}
+}
+
+func TestAdmitRefusesRejectedPayout(t *testing.T) {
+	a := newScreeningTestAgent(t, nil, false)
+	a.workflows.Record(WorkflowRedemption, 4, StateRejected, "payout rejected, proving non-payment")
+	// Later entries, and the approval expiring from the queue, do not undo it
+	a.workflows.Note(WorkflowRedemption, 4, "dispute_failed", "FDC round not finalized", "")
+
+	if a.admit(WorkflowRedemption, 4, RetryPayment) {
+		t.Fatal("payment admitted for a rejected payout")
+	}
+	if !a.admit(WorkflowRedemption, 4, RetryDispute) {
+		t.Fatal("dispute refused for a rejected payout")
+	}
+	if a.rejected(5) {
+		t.Fatal("unknown redemption reported as rejected")
+	}
+}
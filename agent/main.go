@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,16 +13,45 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-var (
-	configPath = flag.String("config", "config.yaml", "Path to configuration file")
-)
+var configPath = flag.String("config", "config.yaml", "Path to configuration file")
 
 func main() {
+	flag.Usage = usage
 	flag.Parse()
 
 	// Setup logger
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339})
+
+	// No subcommand keeps the original behavior of running the daemon
+	name, args := "run", flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(args); err != nil {
+		log.Fatal().Err(err).Str("command", name).Msg("Command failed")
+	}
+}
+
+// runAgent runs the agent daemon until SIGINT or SIGTERM
+func runAgent(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	keeper := fs.Bool("keeper", false, "Run only the timeout keeper")
+	dryRun := fs.Bool("dry-run", false, "Record XRPL payments and Flare transactions instead of sending them")
+	fs.Parse(args)
+
+	// Through the environment so SIGHUP reloads keep dry-run mode
+	if *dryRun {
+		os.Setenv(envName("shadow.enabled"), "true")
+	}
+
 	log.Info().Msg("Starting FLIP Agent Service")
 
 	// Load configuration
@@ -30,6 +60,15 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
 
+	// Own the state files so one-shot commands cannot write them behind our back
+	if !*keeper {
+		unlock, err := lockStateDir(config)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to lock agent state, is another agent running?")
+		}
+		defer unlock()
+	}
+
 	// Initialize agent
 	agent, err := NewAgent(config)
 	if err != nil {
@@ -43,27 +82,80 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start agent in goroutine
-	agentErrChan := make(chan error, 1)
+	// Start agent in goroutine; agentDone receives once it has stopped
+	agentDone := make(chan error, 1)
 	go func() {
-		if err := agent.Run(ctx); err != nil {
-			agentErrChan <- err
+		run := agent.Run
+		if *keeper {
+			run = agent.RunKeeper
 		}
+		agentDone <- run(ctx)
 	}()
 
+	// Serve metrics and health checks
+	if config.Metrics.ListenAddr != "" {
+		health := NewHealthServer(agent, config)
+		go func() {
+			if err := health.Run(ctx); err != nil {
+				log.Error().Err(err).Msg("Metrics and health server stopped")
+			}
+		}()
+	}
+
+	// Serve the operator admin API; it drives the event loop, which keeper
+	// mode does not run
+	if config.Admin.ListenAddr != "" && !*keeper {
+		admin := NewAdminServer(agent, config)
+		go func() {
+			if err := admin.Run(ctx); err != nil {
+				log.Error().Err(err).Msg("Admin API stopped")
+			}
+		}()
+	}
+
+	// Reload configuration on SIGHUP
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+
 	// Wait for shutdown signal or error
-	select {
-	case sig := <-sigChan:
-		log.Info().Str("signal", sig.String()).Msg("Received shutdown signal")
-		cancel()
-	case err := <-agentErrChan:
-		log.Error().Err(err).Msg("Agent error")
-		cancel()
+wait:
+	for {
+		select {
+		case <-reloadChan:
+			log.Info().Str("config", *configPath).Msg("Received SIGHUP, reloading configuration")
+			reloaded, err := LoadConfig(*configPath)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to reload configuration, keeping current settings")
+				continue
+			}
+			if err := agent.Reload(ctx, reloaded); err != nil {
+				log.Error().Err(err).Msg("Failed to apply reloaded configuration")
+				continue
+			}
+			log.Info().Msg("Configuration reloaded")
+		case sig := <-sigChan:
+			log.Info().Str("signal", sig.String()).Msg("Received shutdown signal")
+			cancel()
+			break wait
+		case err := <-agentDone:
+			cancel()
+			if err != nil {
+				return fmt.Errorf("agent stopped: %w", err)
+			}
+			log.Info().Msg("Agent stopped")
+			return nil
+		}
 	}
 
-	// Graceful shutdown
+	// Graceful shutdown: the agent drains in-flight work within
+	// agent.shutdown_grace_period, but never abandons an XRPL payment before
+	// its hash is recorded, so wait for it rather than a fixed time
 	log.Info().Msg("Shutting down agent...")
-	time.Sleep(2 * time.Second)
+	select {
+	case <-agentDone:
+	case sig := <-sigChan:
+		log.Warn().Str("signal", sig.String()).Msg("Second signal, exiting without waiting for in-flight work")
+	}
 	log.Info().Msg("Agent stopped")
+	return nil
 }
-
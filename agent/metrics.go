@@ -0,0 +1,162 @@
+package main
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Pipeline stages timed by observeStage
+const (
+	StagePayment         = "xrpl_payment"
+	StageFDCPrepare      = "fdc_prepare"
+	StageFDCRequest      = "fdc_request"
+	StageFDCRound        = "fdc_round"
+	StageFDCProof        = "fdc_proof"
+	StageFDCSubmit       = "fdc_submit"
+	StageProvisional     = "provisional_settle"
+	StageMintingProvider = "minting_provisional"
+)
+
+// metricsRegistry holds every agent metric; it is served on /metrics
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	eventsSeen = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "flip_agent_events_total",
+		Help: "FLIPCore events received, by event type.",
+	}, []string{"type"})
+
+	blockLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "flip_agent_block_lag",
+		Help: "Blocks between chain head and the event cursor at the last poll.",
+	}, []string{"cursor"})
+
+	lastPoll = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "flip_agent_last_poll_timestamp_seconds",
+		Help: "Unix time of the last successful poll, by event cursor.",
+	}, []string{"cursor"})
+
+	xrplBalance = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "flip_agent_xrpl_balance_drops",
+		Help: "Agent XRPL wallet balance in drops.",
+	})
+
+	paymentsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "flip_agent_xrpl_payments_total",
+		Help: "XRPL payments by result (sent or failed).",
+	}, []string{"result"})
+
+	stageInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "flip_agent_stage_in_flight",
+		Help: "Jobs currently in each pipeline stage.",
+	}, []string{"stage"})
+
+	stageTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "flip_agent_stage_total",
+		Help: "Completed pipeline stages by stage and result (ok or error).",
+	}, []string{"stage", "result"})
+
+	stageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "flip_agent_stage_duration_seconds",
+		Help:    "Pipeline stage latency.",
+		Buckets: []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1200},
+	}, []string{"stage"})
+
+	gasSpent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "flip_agent_gas_spent_wei_total",
+		Help: "Flare gas fees paid for mined transactions, by method.",
+	}, []string{"method"})
+
+	fdcFeesSpent = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "flip_agent_fdc_fees_wei_total",
+		Help: "FDC attestation request fees paid.",
+	})
+
+	breakerTripped = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "flip_agent_circuit_breaker_tripped",
+		Help: "1 while a circuit breaker is tripped and XRPL payouts are halted.",
+	}, []string{"breaker"})
+
+	breakerTrips = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "flip_agent_circuit_breaker_trips_total",
+		Help: "Circuit breaker trips, by breaker.",
+	}, []string{"breaker"})
+
+	screeningTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "flip_agent_screening_total",
+		Help: "Compliance screening checks by result (clear, hit or error).",
+	}, []string{"result"})
+
+	notificationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "flip_agent_notifications_total",
+		Help: "Notifications by sink and result (sent, failed or dropped).",
+	}, []string{"sink", "result"})
+
+	reconcileFindings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "flip_agent_reconcile_findings",
+		Help: "Findings of the last payment reconciliation run by kind.",
+	}, []string{"kind"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		eventsSeen,
+		blockLag,
+		lastPoll,
+		xrplBalance,
+		paymentsTotal,
+		stageInFlight,
+		stageTotal,
+		stageDuration,
+		gasSpent,
+		fdcFeesSpent,
+		breakerTripped,
+		breakerTrips,
+		screeningTotal,
+		notificationsTotal,
+		reconcileFindings,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// observeStage marks a job as entering stage. Call the returned function with
+// the stage's error when it finishes:
+//
+//	done := observeStage(StageFDCRound)
+//	err := wait()
+//	done(err)
+func observeStage(stage string) func(error) {
+	start := time.Now()
+	stageInFlight.WithLabelValues(stage).Inc()
+	return func(err error) {
+		stageInFlight.WithLabelValues(stage).Dec()
+		stageDuration.WithLabelValues(stage).Observe(time.Since(start).Seconds())
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		stageTotal.WithLabelValues(stage, result).Inc()
+	}
+}
+
+// recordGas adds the fee paid for a mined transaction
+func recordGas(method string, tx *types.Transaction, receipt *types.Receipt) {
+	price := receipt.EffectiveGasPrice
+	if price == nil {
+		price = tx.GasPrice()
+	}
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), price)
+	gasSpent.WithLabelValues(method).Add(weiFloat(fee))
+}
+
+// weiFloat converts wei to a float for Prometheus; precision loss beyond
+// ~15 significant digits is acceptable for fee totals
+func weiFloat(wei *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(wei).Float64()
+	return f
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// claimDepositTx records that a minting uses an XRPL deposit. Returns the
+// minting that claimed the hash first, which differs from mintingID when the
+// deposit is being reused.
+func (a *Agent) claimDepositTx(txHash string, mintingID uint64) uint64 {
+	key := strings.ToUpper(strings.TrimPrefix(txHash, "0x"))
+	if owner, ok := a.mintingTxHashes[key]; ok {
+		return owner
+	}
+	a.mintingTxHashes[key] = mintingID
+	return mintingID
+}
+
+// verifyMintingDeposit checks the user's XRPL deposit directly before an LP
+// advances FXRP against it. Returns the reason the deposit cannot be trusted,
+// or "" if it can.
+func (a *Agent) verifyMintingDeposit(ctx context.Context, req MintingAttestationRequest) (string, error) {
+	mintingID := req.MintingID.Uint64()
+	if owner := a.claimDepositTx(req.XrplTxHash, mintingID); owner != mintingID {
+		return fmt.Sprintf("deposit already used by minting %d", owner), nil
+	}
+
+	expected := a.fdcSubmitter.defaultDepositAddress
+	reservation, err := a.fdcSubmitter.readCollateralReservation(ctx, req.Asset, req.CollateralReservationID)
+	if err != nil {
+		log.Warn().Err(err).Uint64("minting_id", mintingID).Msg("Failed to read collateral reservation")
+	} else {
+		expected = reservation.PaymentAddress
+	}
+	if expected == "" {
+		return "no expected deposit address for the agent vault", nil
+	}
+
+	tx, err := a.paymentProc.xrplClient.GetTransaction(ctx, req.XrplTxHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch XRPL deposit %s: %w", req.XrplTxHash, err)
+	}
+
+	switch {
+	case !tx.Validated:
+		return "deposit is not in a validated ledger", nil
+	case tx.TransactionType != "Payment":
+		return fmt.Sprintf("deposit is a %s transaction", tx.TransactionType), nil
+	case tx.Result != "tesSUCCESS":
+		return fmt.Sprintf("deposit result is %s", tx.Result), nil
+	case tx.Destination != expected:
+		return fmt.Sprintf("deposit paid %s, expected %s", tx.Destination, expected), nil
+	case tx.DeliveredAmount == nil:
+		return "deposit did not deliver XRP", nil
+	case tx.DeliveredAmount.Cmp(req.XrpAmount) < 0:
+		return fmt.Sprintf("deposit delivered %s drops, minting requires %s", tx.DeliveredAmount, req.XrpAmount), nil
+	}
+
+	return "", nil
+}
+
+// queueMintingForFDC moves a pending minting to QueuedForFDC so no LP is
+// matched against it
+func (a *Agent) queueMintingForFDC(ctx context.Context, mintingID *big.Int) error {
+	tx, err := a.fdcSubmitter.transactFLIPCore(ctx, "queueMintingForFDC", 100000, mintingID)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("tx_hash", tx.Hash().Hex()).
+		Uint64("minting_id", mintingID.Uint64()).
+		Msg("Minting queued for FDC")
+
+	return nil
+}
+
+// settleMintingProvisional verifies the deposit and scores the minting, then
+// either advances FXRP via finalizeMintingProvisional or queues it for FDC
+func (a *Agent) settleMintingProvisional(ctx context.Context, req MintingAttestationRequest) (bool, error) {
+	reason, err := a.verifyMintingDeposit(ctx, req)
+	if err != nil {
+		return false, err
+	}
+
+	if reason != "" {
+		log.Warn().
+			Uint64("minting_id", req.MintingID.Uint64()).
+			Str("xrpl_tx", req.XrplTxHash).
+			Str("reason", reason).
+			Msg("Minting deposit failed direct XRPL verification, queueing for FDC")
+		if err := a.queueMintingForFDC(ctx, req.MintingID); err != nil {
+			return false, fmt.Errorf("failed to queue minting for FDC: %w", err)
+		}
+		a.workflows.Record(WorkflowMinting, req.MintingID.Uint64(), StateQueuedFDC, reason)
+		return false, nil
+	}
+
+	decision, err := a.router.Evaluate(ctx, req.Asset, req.FxrpAmount)
+	if err != nil {
+		return false, fmt.Errorf("failed to score minting: %w", err)
+	}
+	decision.log("minting", req.MintingID.Uint64())
+	a.audit.RecordFor(AuditScoreInputs, WorkflowMinting, req.MintingID.Uint64(), decision)
+
+	if !decision.Result.CanProvisionalSettle {
+		if err := a.queueMintingForFDC(ctx, req.MintingID); err != nil {
+			return false, fmt.Errorf("failed to queue minting for FDC: %w", err)
+		}
+		a.workflows.Record(WorkflowMinting, req.MintingID.Uint64(), StateQueuedFDC, decision.summary())
+		return false, nil
+	}
+
+	done := observeStage(StageMintingProvider)
+	err = a.callFinalizeMintingProvisional(ctx, req.MintingID, decision.Params.PriceVolatility)
+	done(err)
+	if err != nil {
+		return false, fmt.Errorf("failed to call finalizeMintingProvisional: %w", err)
+	}
+	a.workflows.Record(WorkflowMinting, req.MintingID.Uint64(), StateProvisional, decision.summary())
+	return true, nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog/log"
+)
+
+// Multicall3 is deployed at the same address on Flare, Songbird and their
+// testnets
+const (
+	defaultMulticallAddress = "0xcA11bde05977b3631167028862bE2a173976CA11"
+	defaultMulticallBatch   = 200
+)
+
+const multicall3ABI = `[{
+	"inputs": [{"components": [
+		{"name": "target", "type": "address"},
+		{"name": "allowFailure", "type": "bool"},
+		{"name": "callData", "type": "bytes"}
+	], "name": "calls", "type": "tuple[]"}],
+	"name": "aggregate3",
+	"outputs": [{"components": [
+		{"name": "success", "type": "bool"},
+		{"name": "returnData", "type": "bytes"}
+	], "name": "returnData", "type": "tuple[]"}],
+	"stateMutability": "payable",
+	"type": "function"
+}]`
+
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// BatchCall is one view call in a batch
+type BatchCall struct {
+	Target common.Address
+	ABI    *abi.ABI
+	Method string
+	Args   []interface{}
+}
+
+// BatchResult holds the decoded outputs of a BatchCall, or the reason it failed
+type BatchResult struct {
+	Values  []interface{}
+	Err     error
+	outputs abi.Arguments
+}
+
+// Decode copies the outputs into out, which points to the type the generated
+// binding's getter returns. Multi-value outputs are matched to struct fields
+// by name.
+func (r BatchResult) Decode(out interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	return r.outputs.Copy(out, r.Values)
+}
+
+// Multicaller batches view calls through Multicall3, falling back to one
+// eth_call per read where Multicall3 is not deployed
+type Multicaller struct {
+	client    *ethclient.Client
+	address   common.Address
+	parsed    abi.ABI
+	batchSize int
+
+	mu        sync.Mutex // Guards available; recovery, reconcile and the keeper share a Multicaller
+	available *bool      // Resolved on first use
+}
+
+// NewMulticaller creates a Multicaller from the config
+func NewMulticaller(client *ethclient.Client, config *Config) (*Multicaller, error) {
+	parsed, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Multicall3 ABI: %w", err)
+	}
+
+	address := config.Flare.MulticallAddress
+	if address == "" {
+		address = defaultMulticallAddress
+	}
+	batchSize := config.Agent.RecoveryBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultMulticallBatch
+	}
+
+	return &Multicaller{
+		client:    client,
+		address:   common.HexToAddress(address),
+		parsed:    parsed,
+		batchSize: batchSize,
+	}, nil
+}
+
+// Call runs every call and returns results in the same order. A failing call
+// only fails its own result; the returned error is for transport failures.
+func (m *Multicaller) Call(ctx context.Context, calls []BatchCall) ([]BatchResult, error) {
+	packed := make([]multicall3Call, len(calls))
+	for i, call := range calls {
+		data, err := call.ABI.Pack(call.Method, call.Args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack %s: %w", call.Method, err)
+		}
+		packed[i] = multicall3Call{Target: call.Target, AllowFailure: true, CallData: data}
+	}
+
+	if !m.isAvailable(ctx) {
+		return m.callSerial(ctx, calls, packed)
+	}
+
+	results := make([]BatchResult, 0, len(calls))
+	for start := 0; start < len(calls); start += m.batchSize {
+		end := start + m.batchSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+
+		raw, err := m.aggregate(ctx, packed[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for i, r := range raw {
+			results = append(results, decodeBatchResult(calls[start+i], r.Success, r.ReturnData))
+		}
+	}
+	return results, nil
+}
+
+func (m *Multicaller) aggregate(ctx context.Context, calls []multicall3Call) ([]multicall3Result, error) {
+	data, err := m.parsed.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack aggregate3: %w", err)
+	}
+
+	output, err := m.client.CallContract(ctx, ethereum.CallMsg{To: &m.address, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call aggregate3: %w", err)
+	}
+
+	unpacked, err := m.parsed.Unpack("aggregate3", output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode aggregate3: %w", err)
+	}
+
+	results := *abi.ConvertType(unpacked[0], new([]multicall3Result)).(*[]multicall3Result)
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(results), len(calls))
+	}
+	return results, nil
+}
+
+func (m *Multicaller) callSerial(ctx context.Context, calls []BatchCall, packed []multicall3Call) ([]BatchResult, error) {
+	results := make([]BatchResult, len(calls))
+	for i, call := range packed {
+		target := call.Target
+		output, err := m.client.CallContract(ctx, ethereum.CallMsg{To: &target, Data: call.CallData}, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			results[i] = BatchResult{Err: err}
+			continue
+		}
+		results[i] = decodeBatchResult(calls[i], true, output)
+	}
+	return results, nil
+}
+
+// isAvailable checks once whether Multicall3 has code on this chain
+func (m *Multicaller) isAvailable(ctx context.Context) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.available != nil {
+		return *m.available
+	}
+
+	code, err := m.client.CodeAt(ctx, m.address, nil)
+	if err != nil {
+		return false // Retry the check next time
+	}
+	available := len(code) > 0
+	m.available = &available
+	if !available {
+		log.Warn().
+			Str("multicall_address", m.address.Hex()).
+			Msg("Multicall3 not deployed, falling back to individual calls")
+	}
+	return available
+}
+
+func decodeBatchResult(call BatchCall, success bool, data []byte) BatchResult {
+	if !success {
+		return BatchResult{Err: fmt.Errorf("%s reverted", call.Method)}
+	}
+	values, err := call.ABI.Unpack(call.Method, data)
+	if err != nil {
+		return BatchResult{Err: fmt.Errorf("failed to decode %s: %w", call.Method, err)}
+	}
+	return BatchResult{Values: values, outputs: call.ABI.Methods[call.Method].Outputs}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultNetwork is used when config.yaml does not name a network profile
+const DefaultNetwork = "coston2"
+
+// NetworkProfile bundles the chain-specific parameters for a Flare network
+// and the XRPL network its FDC verifiers attest against
+type NetworkProfile struct {
+	Name    string
+	ChainID int64
+	RPCURL  string
+
+	// FDC contracts and Data Availability layer
+	FdcHubAddress       string
+	FdcFeeConfigAddress string
+	DALayerURL          string
+	VerifierURL         string
+
+	// Voting round epoch parameters
+	FirstVotingRoundStartTs    uint64
+	VotingEpochDurationSeconds uint64
+
+	// XRPL network attested by the FDC verifiers on this chain
+	XRPLSourceID string
+	XRPLWS       string
+	XRPLRPC      string
+}
+
+// networkProfiles holds the built-in profiles. FDC contract addresses and
+// epoch parameters are fallbacks for when the on-chain lookup fails.
+var networkProfiles = map[string]NetworkProfile{
+	"coston2": {
+		Name:                       "coston2",
+		ChainID:                    114,
+		RPCURL:                     "https://coston2-api.flare.network/ext/C/rpc",
+		FdcHubAddress:              "0x48aC463d7975828989331F4De43341627b9c5f1D",
+		FdcFeeConfigAddress:        "0x191a1282Ac700edE65c5B0AaF313BAcC3eA7fC7e",
+		DALayerURL:                 "https://ctn2-data-availability.flare.network",
+		VerifierURL:                "https://fdc-verifiers-testnet.flare.network",
+		FirstVotingRoundStartTs:    1658430000,
+		VotingEpochDurationSeconds: 90,
+		XRPLSourceID:               "testXRP",
+		XRPLWS:                     "wss://s.altnet.rippletest.net:51233",
+		XRPLRPC:                    "https://s.altnet.rippletest.net:51234",
+	},
+	// No FeeConfig fallback: Validate requires fdc.fee_config_address
+	"coston": {
+		Name:                       "coston",
+		ChainID:                    16,
+		RPCURL:                     "https://coston-api.flare.network/ext/C/rpc",
+		FdcHubAddress:              "0x1c78A073E3BD2aCa4cc327d55FB0cD4f0549B55b",
+		DALayerURL:                 "https://ctn-data-availability.flare.network",
+		VerifierURL:                "https://fdc-verifiers-testnet.flare.network",
+		FirstVotingRoundStartTs:    1658429955,
+		VotingEpochDurationSeconds: 90,
+		XRPLSourceID:               "testXRP",
+		XRPLWS:                     "wss://s.altnet.rippletest.net:51233",
+		XRPLRPC:                    "https://s.altnet.rippletest.net:51234",
+	},
+	// No FdcHub or FeeConfig fallback: Validate requires fdc.fdc_hub_address
+	// and fdc.fee_config_address on this network
+	"songbird": {
+		Name:                       "songbird",
+		ChainID:                    19,
+		RPCURL:                     "https://songbird-api.flare.network/ext/C/rpc",
+		DALayerURL:                 "https://sgb-data-availability.flare.network",
+		VerifierURL:                "https://fdc-verifiers-mainnet.flare.network",
+		FirstVotingRoundStartTs:    1658429955,
+		VotingEpochDurationSeconds: 90,
+		XRPLSourceID:               "XRP",
+		XRPLWS:                     "wss://xrplcluster.com",
+		XRPLRPC:                    "https://xrplcluster.com",
+	},
+	// No FdcHub or FeeConfig fallback: Validate requires fdc.fdc_hub_address
+	// and fdc.fee_config_address on this network
+	"flare": {
+		Name:                       "flare",
+		ChainID:                    14,
+		RPCURL:                     "https://flare-api.flare.network/ext/C/rpc",
+		DALayerURL:                 "https://flr-data-availability.flare.network",
+		VerifierURL:                "https://fdc-verifiers-mainnet.flare.network",
+		FirstVotingRoundStartTs:    1658430000,
+		VotingEpochDurationSeconds: 90,
+		XRPLSourceID:               "XRP",
+		XRPLWS:                     "wss://xrplcluster.com",
+		XRPLRPC:                    "https://xrplcluster.com",
+	},
+}
+
+// LookupNetworkProfile returns the built-in profile with the given name
+func LookupNetworkProfile(name string) (NetworkProfile, error) {
+	profile, ok := networkProfiles[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		names := make([]string, 0, len(networkProfiles))
+		for n := range networkProfiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return NetworkProfile{}, fmt.Errorf("unknown network %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return profile, nil
+}
+
+// applyNetworkProfile fills every endpoint and FDC parameter left empty in the
+// config from the selected profile. Values set explicitly in config.yaml win.
+// FDC contract addresses and voting epoch parameters are not copied: they are
+// read from chain, with the profile values used only as a fallback.
+func (c *Config) applyNetworkProfile() error {
+	if c.Network == "" {
+		c.Network = DefaultNetwork
+	}
+
+	profile, err := LookupNetworkProfile(c.Network)
+	if err != nil {
+		return err
+	}
+	c.Network = profile.Name
+	c.Profile = profile
+
+	if c.Flare.ChainID == 0 {
+		c.Flare.ChainID = profile.ChainID
+	}
+	if c.Flare.RPCURL == "" {
+		c.Flare.RPCURL = profile.RPCURL
+	}
+	if c.Flare.ContractRegistryAddress == "" {
+		c.Flare.ContractRegistryAddress = FlareContractRegistryAddress
+	}
+	if c.Flare.RegistryRefreshInterval == 0 {
+		c.Flare.RegistryRefreshInterval = 600
+	}
+
+	if c.FDC.DALayerURL == "" {
+		c.FDC.DALayerURL = profile.DALayerURL
+	}
+	if c.FDC.VerifierURL == "" {
+		c.FDC.VerifierURL = profile.VerifierURL
+	}
+	if c.FDC.SourceID == "" {
+		c.FDC.SourceID = profile.XRPLSourceID
+	}
+
+	if c.XRPL.WSURL == "" {
+		c.XRPL.WSURL = profile.XRPLWS
+	}
+	if c.XRPL.RPCURL == "" {
+		c.XRPL.RPCURL = profile.XRPLRPC
+	}
+
+	if c.Flare.ChainID != profile.ChainID {
+		log.Warn().
+			Int64("chain_id", c.Flare.ChainID).
+			Int64("profile_chain_id", profile.ChainID).
+			Str("network", profile.Name).
+			Msg("flare.chain_id differs from the network profile")
+	}
+
+	return nil
+}
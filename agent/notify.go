@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Notification defaults used when the config leaves them unset
+const (
+	defaultNotifyDedupWindow  = time.Hour
+	defaultNotifyMaxAttempts  = 5
+	defaultNotifyRetryBackoff = 2 * time.Second
+	defaultNotifyBalanceCheck = time.Minute
+	maxNotifyRetryBackoff     = 5 * time.Minute
+	notifyQueueSize           = 256 // Per sink; further notifications are dropped
+	notifyHTTPTimeout         = 10 * time.Second
+)
+
+// Notification sink types
+const (
+	SinkWebhook = "webhook" // JSON body, optionally HMAC signed
+	SinkSlack   = "slack"   // Slack-compatible incoming webhook
+	SinkFile    = "file"    // JSON lines file, or stdout for "-"
+)
+
+// Events the agent notifies about
+const (
+	EventSettled        = "redemption_settled"
+	EventPaymentFailed  = "payment_failed"
+	EventRecordFailed   = "payment_record_failed" // Paid on XRPL, hash not recorded on FLIPCore
+	EventProofMismatch  = "fdc_proof_mismatch"
+	EventBreakerTripped = "breaker_tripped"
+	EventLowBalance     = "low_balance"
+	EventApprovalNeeded = "approval_needed"
+)
+
+// notifyEvents maps each event to its severity
+var notifyEvents = map[string]string{
+	EventSettled:        "info",
+	EventPaymentFailed:  "critical",
+	EventRecordFailed:   "critical",
+	EventProofMismatch:  "critical",
+	EventBreakerTripped: "critical",
+	EventLowBalance:     "warning",
+	EventApprovalNeeded: "warning",
+}
+
+// Notification is one structured event pushed to the sinks
+type Notification struct {
+	Event    string            `json:"event"`
+	Severity string            `json:"severity"`
+	Subject  string            `json:"subject"` // The redemption, breaker or account the event is about
+	Message  string            `json:"message"`
+	Details  map[string]string `json:"details,omitempty"`
+	Time     time.Time         `json:"time"`
+	DryRun   bool              `json:"dry_run,omitempty"`
+}
+
+// Sink delivers notifications to one destination
+type Sink interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// notifySink is a sink with its event filter and delivery queue
+type notifySink struct {
+	name   string
+	sink   Sink
+	events map[string]bool // nil sends every event
+	queue  chan Notification
+}
+
+// Notifier fans notifications out to the configured sinks. An identical
+// event for the same subject is sent once per dedup window. Each sink
+// delivers from its own queue and retries with exponential backoff, so a
+// slow or failing sink neither blocks the agent nor the other sinks.
+type Notifier struct {
+	sinks       []*notifySink
+	dedupWindow time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	dryRun      bool
+	xrpl        *XRPLClient
+	lowBalance  uint64 // Drops; 0 disables the balance watch
+
+	mu   sync.Mutex
+	sent map[string]time.Time // Dedup key -> when it was last queued
+}
+
+// NewNotifier creates a notifier for the configured sinks. xrpl may be nil
+// when the low balance watch is not needed.
+func NewNotifier(config *Config, xrpl *XRPLClient) (*Notifier, error) {
+	cfg := config.Notifications
+
+	n := &Notifier{
+		dedupWindow: time.Duration(cfg.DedupWindow) * time.Second,
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     time.Duration(cfg.RetryBackoff) * time.Second,
+		dryRun:      config.Shadow.Enabled,
+		xrpl:        xrpl,
+		lowBalance:  cfg.LowBalance,
+		sent:        make(map[string]time.Time),
+	}
+	if n.dedupWindow <= 0 {
+		n.dedupWindow = defaultNotifyDedupWindow
+	}
+	if n.maxAttempts <= 0 {
+		n.maxAttempts = defaultNotifyMaxAttempts
+	}
+	if n.backoff <= 0 {
+		n.backoff = defaultNotifyRetryBackoff
+	}
+
+	for i, sc := range cfg.Sinks {
+		var sink Sink
+		switch sc.Type {
+		case SinkWebhook:
+			sink = &WebhookSink{url: sc.URL, secret: sc.Secret, httpClient: &http.Client{Timeout: notifyHTTPTimeout}}
+		case SinkSlack:
+			sink = &SlackSink{url: sc.URL, httpClient: &http.Client{Timeout: notifyHTTPTimeout}}
+		case SinkFile:
+			sink = &FileSink{path: sc.Path}
+		default:
+			return nil, fmt.Errorf("notification sink %d has unknown type %q", i, sc.Type)
+		}
+
+		name := sc.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", sc.Type, i)
+		}
+		var events map[string]bool
+		if len(sc.Events) > 0 {
+			events = make(map[string]bool)
+			for _, e := range sc.Events {
+				events[e] = true
+			}
+		}
+		n.sinks = append(n.sinks, &notifySink{
+			name:   name,
+			sink:   sink,
+			events: events,
+			queue:  make(chan Notification, notifyQueueSize),
+		})
+	}
+	return n, nil
+}
+
+// Notify queues an event for every sink that accepts it. It never blocks:
+// a notification for a full queue is dropped and logged.
+func (n *Notifier) Notify(event, subject, message string, details map[string]string) {
+	if len(n.sinks) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	key := event + "|" + subject
+	n.mu.Lock()
+	for k, at := range n.sent {
+		if now.Sub(at) >= n.dedupWindow {
+			delete(n.sent, k)
+		}
+	}
+	if _, ok := n.sent[key]; ok {
+		n.mu.Unlock()
+		log.Debug().Str("event", event).Str("subject", subject).Msg("Duplicate notification suppressed")
+		return
+	}
+	n.sent[key] = now
+	n.mu.Unlock()
+
+	notification := Notification{
+		Event:    event,
+		Severity: notifyEvents[event],
+		Subject:  subject,
+		Message:  message,
+		Details:  details,
+		Time:     now,
+		DryRun:   n.dryRun,
+	}
+	for _, s := range n.sinks {
+		if s.events != nil && !s.events[event] {
+			continue
+		}
+		select {
+		case s.queue <- notification:
+		default:
+			notificationsTotal.WithLabelValues(s.name, "dropped").Inc()
+			log.Warn().
+				Str("sink", s.name).
+				Str("event", event).
+				Str("subject", subject).
+				Msg("Notification queue full, dropping notification")
+		}
+	}
+}
+
+// Run delivers queued notifications and watches the XRPL balance until ctx
+// is cancelled
+func (n *Notifier) Run(ctx context.Context) {
+	for _, s := range n.sinks {
+		go n.deliver(ctx, s)
+	}
+	if n.lowBalance == 0 || n.xrpl == nil {
+		<-ctx.Done()
+		return
+	}
+
+	ticker := time.NewTicker(defaultNotifyBalanceCheck)
+	defer ticker.Stop()
+	for {
+		n.checkBalance(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkBalance notifies when the XRPL wallet is below the low balance mark
+func (n *Notifier) checkBalance(ctx context.Context) {
+	balance, err := n.xrpl.GetBalance(ctx)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to get XRPL balance for low balance check")
+		return
+	}
+	drops, ok := new(big.Int).SetString(balance, 10)
+	if !ok || drops.Cmp(new(big.Int).SetUint64(n.lowBalance)) >= 0 {
+		return
+	}
+	n.Notify(EventLowBalance, n.xrpl.wallet.Address,
+		fmt.Sprintf("XRPL wallet holds %s, below the %s low balance mark", formatDrops(drops), formatDrops(new(big.Int).SetUint64(n.lowBalance))),
+		map[string]string{"balance_drops": balance, "low_balance_drops": fmt.Sprint(n.lowBalance)})
+}
+
+// deliver sends one sink's notifications in order, retrying each with
+// exponential backoff before giving up on it
+func (n *Notifier) deliver(ctx context.Context, s *notifySink) {
+	for {
+		var notification Notification
+		select {
+		case <-ctx.Done():
+			return
+		case notification = <-s.queue:
+		}
+
+		backoff := n.backoff
+		for attempt := 1; ; attempt++ {
+			err := s.sink.Send(ctx, notification)
+			if err == nil {
+				notificationsTotal.WithLabelValues(s.name, "sent").Inc()
+				break
+			}
+			if attempt >= n.maxAttempts || ctx.Err() != nil {
+				notificationsTotal.WithLabelValues(s.name, "failed").Inc()
+				log.Error().
+					Err(err).
+					Str("sink", s.name).
+					Str("event", notification.Event).
+					Str("subject", notification.Subject).
+					Int("attempts", attempt).
+					Msg("Failed to deliver notification, giving up")
+				break
+			}
+			log.Warn().
+				Err(err).
+				Str("sink", s.name).
+				Str("event", notification.Event).
+				Int("attempt", attempt).
+				Dur("retry_in", backoff).
+				Msg("Failed to deliver notification, retrying")
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxNotifyRetryBackoff)
+		}
+	}
+}
+
+// WebhookSink POSTs each notification as JSON. With a secret, the body is
+// signed with HMAC-SHA256 and the hex digest sent as "sha256=<digest>" in
+// the X-Flip-Signature header.
+type WebhookSink struct {
+	url        string
+	secret     string
+	httpClient *http.Client
+}
+
+func (w *WebhookSink) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"X-Flip-Event": n.Event}
+	if w.secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.secret))
+		mac.Write(body)
+		headers["X-Flip-Signature"] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return postNotification(ctx, w.httpClient, w.url, body, headers)
+}
+
+// SlackSink posts a formatted message to a Slack-compatible incoming webhook
+type SlackSink struct {
+	url        string
+	httpClient *http.Client
+}
+
+func (s *SlackSink) Send(ctx context.Context, n Notification) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "*[%s] %s* `%s`", n.Severity, n.Event, n.Subject)
+	if n.DryRun {
+		text.WriteString(" (dry run)")
+	}
+	fmt.Fprintf(&text, "\n%s", n.Message)
+
+	keys := make([]string, 0, len(n.Details))
+	for k := range n.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&text, "\n• %s: `%s`", k, n.Details[k])
+	}
+
+	body, err := json.Marshal(map[string]string{"text": text.String()})
+	if err != nil {
+		return err
+	}
+	return postNotification(ctx, s.httpClient, s.url, body, nil)
+}
+
+// postNotification POSTs a JSON body and accepts any 2xx response
+func postNotification(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &httpStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return nil
+}
+
+// FileSink appends each notification as a JSON line to a file, or writes it
+// to stdout when the path is "-"
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (f *FileSink) Send(ctx context.Context, n Notification) error {
+	line, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.path == "-" {
+		_, err := os.Stdout.Write(line)
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer file.Close()
+	_, err = file.Write(line)
+	return err
+}
+
+// redemptionSubject names a redemption in notifications
+func redemptionSubject(id uint64) string {
+	return fmt.Sprintf("redemption %d", id)
+}
+
+// notifySettled reports a redemption whose payment FDC proved on FLIPCore
+func (a *Agent) notifySettled(id uint64, xrplTxHash string, roundID uint64) {
+	a.notifier.Notify(EventSettled, redemptionSubject(id), "Redemption settled with an FDC payment proof", map[string]string{
+		"xrpl_tx_hash": xrplTxHash,
+		"fdc_round":    fmt.Sprint(roundID),
+	})
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/flip-protocol/agent/bindings"
+	"github.com/rs/zerolog/log"
+)
+
+// admit reports whether the agent may run stage for an ID. IDs on operator
+// hold are left alone and rejected payouts are never paid; in a paused
+// workflow, or for a payment while a circuit breaker is tripped, the stage is
+// recorded so resuming or resetting runs it.
+func (a *Agent) admit(kind string, id uint64, stage string) bool {
+	if hold := a.workflows.Hold(kind, id); hold != "" {
+		log.Warn().
+			Str("workflow", kind).
+			Uint64("id", id).
+			Str("hold", hold).
+			Msg("ID is on operator hold, not processing")
+		return false
+	}
+	if kind == WorkflowRedemption && stage == RetryPayment && a.rejected(id) {
+		log.Warn().
+			Uint64("redemption_id", id).
+			Msg("Payout was rejected by an operator, not paying")
+		return false
+	}
+	if a.workflows.Paused(kind) {
+		a.workflows.Defer(kind, id, stage, "workflow paused")
+		log.Info().
+			Str("workflow", kind).
+			Uint64("id", id).
+			Str("stage", stage).
+			Msg("Workflow paused, deferring until resumed")
+		return false
+	}
+	if halted := a.payoutsHalted(kind, stage); halted != "" {
+		a.workflows.Defer(kind, id, stage, "circuit breaker "+halted+" tripped")
+		log.Warn().
+			Str("workflow", kind).
+			Uint64("id", id).
+			Str("breakers", halted).
+			Msg("Payouts halted by circuit breaker, deferring until reset")
+		return false
+	}
+	return true
+}
+
+// payoutsHalted returns the tripped circuit breakers when stage is an XRPL
+// payout, or ""
+func (a *Agent) payoutsHalted(kind, stage string) string {
+	if kind != WorkflowRedemption || stage != RetryPayment {
+		return ""
+	}
+	return a.breakers.Halted()
+}
+
+// interrupt records that a stage was not finished because the agent is
+// shutting down, so the next start resumes it
+func (a *Agent) interrupt(kind string, id uint64, stage, detail string) {
+	a.workflows.Interrupt(kind, id, stage, detail)
+	log.Warn().
+		Str("workflow", kind).
+		Uint64("id", id).
+		Str("stage", stage).
+		Str("detail", detail).
+		Msg("Shutting down, stage will resume on the next start")
+}
+
+// resumeInterrupted re-runs the stages a previous shutdown left unfinished.
+// It runs before the recovery scan, which picks up anything that fails here.
+func (a *Agent) resumeInterrupted(ctx context.Context) {
+	for _, r := range a.workflows.List("", StateInterrupted, "") {
+		if a.stopping.Load() {
+			return
+		}
+		if a.workflows.Hold(r.Kind, r.ID) != "" {
+			continue // Resumed on a later start once released
+		}
+		if !a.admit(r.Kind, r.ID, r.DeferredStage) {
+			continue
+		}
+
+		op, err := a.PrepareRetry(ctx, r.Kind, r.ID, r.DeferredStage)
+		if err != nil {
+			// The chain moved on, e.g. the escrow timed out
+			a.workflows.Record(r.Kind, r.ID, StateResumeSkipped, err.Error())
+			continue
+		}
+		log.Info().
+			Str("workflow", r.Kind).
+			Uint64("id", r.ID).
+			Str("stage", r.DeferredStage).
+			Msg("Resuming stage interrupted by shutdown")
+		a.workflows.Note(r.Kind, r.ID, "resumed", r.DeferredStage, "")
+		op(ctx)
+	}
+}
+
+// enqueue runs op on the agent's event loop, between events, so operator
+// actions never race the handlers
+func (a *Agent) enqueue(ctx context.Context, op func(context.Context)) error {
+	select {
+	case a.adminOps <- op:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PrepareRetry checks that stage can run for an ID in its current on-chain
+// state and returns the action that runs it
+func (a *Agent) PrepareRetry(ctx context.Context, kind string, id uint64, stage string) (func(context.Context), error) {
+	if hold := a.workflows.Hold(kind, id); hold != "" {
+		return nil, fmt.Errorf("%s %d is %s, release it first", kind, id, hold)
+	}
+	if a.workflows.Paused(kind) {
+		return nil, fmt.Errorf("%s workflow is paused, resume it first", kind)
+	}
+	if halted := a.payoutsHalted(kind, stage); halted != "" {
+		return nil, fmt.Errorf("payouts are halted by circuit breaker %s, reset it first", halted)
+	}
+
+	switch kind {
+	case WorkflowRedemption:
+		return a.prepareRedemptionRetry(ctx, id, stage)
+	case WorkflowMinting:
+		return a.prepareMintingRetry(ctx, id, stage)
+	default:
+		return nil, fmt.Errorf("unknown workflow %q", kind)
+	}
+}
+
+func (a *Agent) prepareRedemptionRetry(ctx context.Context, id uint64, stage string) (func(context.Context), error) {
+	r, timedOut, err := a.readRedemption(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	status := statusName(redemptionStatusNames, r.status)
+
+	switch stage {
+	case RetryProvisional:
+		if r.status != 0 {
+			return nil, fmt.Errorf("redemption %d is %s, provisional needs Pending", id, status)
+		}
+		event := RedemptionRequestedEvent{
+			RedemptionID: r.id,
+			User:         r.user,
+			Asset:        r.asset,
+			Amount:       r.amount,
+			XRPLAddress:  r.xrplAddress,
+			Timestamp:    r.requestedAt,
+		}
+		return func(ctx context.Context) {
+			delete(a.processedRedemptions, id)
+			if err := a.handleRedemptionRequested(ctx, event); err != nil {
+				log.Error().Err(err).Uint64("redemption_id", id).Msg("Retried provisional stage failed")
+			}
+		}, nil
+	case RetryPayment:
+		if r.status != 2 {
+			return nil, fmt.Errorf("redemption %d is %s, payment needs EscrowCreated", id, status)
+		}
+		if r.xrplTxHash != "" {
+			return nil, fmt.Errorf("redemption %d already has XRPL payment %s recorded, retry fdc instead", id, r.xrplTxHash)
+		}
+		if a.rejected(id) {
+			return nil, fmt.Errorf("redemption %d payout was rejected, retry dispute instead", id)
+		}
+		return func(ctx context.Context) { a.recoverPendingEscrow(ctx, r, timedOut) }, nil
+	case RetryDispute:
+		if r.status != 2 || r.xrplTxHash != "" {
+			return nil, fmt.Errorf("redemption %d is %s, dispute needs an unpaid EscrowCreated", id, status)
+		}
+		if !a.rejected(id) {
+			return nil, fmt.Errorf("redemption %d payout was not rejected", id)
+		}
+		return func(ctx context.Context) { a.disputeRejected(ctx, r) }, nil
+	case RetryFDC:
+		if r.status != 2 {
+			return nil, fmt.Errorf("redemption %d is %s, fdc needs EscrowCreated", id, status)
+		}
+		if r.xrplTxHash == "" {
+			return nil, fmt.Errorf("redemption %d has no recorded XRPL payment", id)
+		}
+		return func(ctx context.Context) { a.recoverFDCSubmission(ctx, r) }, nil
+	default:
+		return nil, fmt.Errorf("unknown stage %q, expected %s, %s, %s or %s", stage, RetryProvisional, RetryPayment, RetryFDC, RetryDispute)
+	}
+}
+
+func (a *Agent) prepareMintingRetry(ctx context.Context, id uint64, stage string) (func(context.Context), error) {
+	m, err := a.readMinting(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch stage {
+	case RetryProvisional:
+		if m.status != 0 {
+			return nil, fmt.Errorf("minting %d has status %d, provisional needs Pending", id, m.status)
+		}
+		return func(ctx context.Context) {
+			delete(a.processedMintings, id)
+			a.recoverMinting(ctx, m)
+		}, nil
+	case RetryFDC:
+		if m.status != 1 && m.status != 2 {
+			return nil, fmt.Errorf("minting %d has status %d, fdc needs ProvisionalSettled or QueuedForFDC", id, m.status)
+		}
+		return func(ctx context.Context) { a.recoverMinting(ctx, m) }, nil
+	default:
+		return nil, fmt.Errorf("unknown stage %q for mintings, expected %s or %s", stage, RetryProvisional, RetryFDC)
+	}
+}
+
+// NextStage returns the stage an ID needs in its current on-chain state, or
+// "" when the agent has nothing left to do for it
+func (a *Agent) NextStage(ctx context.Context, kind string, id uint64) (string, error) {
+	switch kind {
+	case WorkflowRedemption:
+		r, _, err := a.readRedemption(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case r.status == 0:
+			return RetryProvisional, nil
+		case r.status == 2 && r.xrplTxHash == "" && a.rejected(id):
+			return RetryDispute, nil
+		case r.status == 2 && r.xrplTxHash == "":
+			return RetryPayment, nil
+		case r.status == 2:
+			return RetryFDC, nil
+		}
+	case WorkflowMinting:
+		m, err := a.readMinting(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		switch m.status {
+		case 0:
+			return RetryProvisional, nil
+		case 1, 2:
+			return RetryFDC, nil
+		}
+	default:
+		return "", fmt.Errorf("unknown workflow %q", kind)
+	}
+	return "", nil
+}
+
+// Resume unpauses a workflow and re-runs every stage deferred while it was
+// paused, including those deferred before a restart. Returns the resumed IDs.
+func (a *Agent) Resume(ctx context.Context, kind string) ([]uint64, error) {
+	a.workflows.SetPaused(kind, false)
+	return a.runDeferred(ctx, kind)
+}
+
+// ResetBreaker resets a tripped circuit breaker. Once none is left tripped,
+// payouts deferred while halted are run. Returns the resumed IDs.
+func (a *Agent) ResetBreaker(ctx context.Context, name, operator string) ([]uint64, error) {
+	if err := a.breakers.Reset(ctx, name, operator); err != nil {
+		return nil, err
+	}
+	if a.breakers.Halted() != "" || a.workflows.Paused(WorkflowRedemption) {
+		return nil, nil
+	}
+	return a.runDeferred(ctx, WorkflowRedemption)
+}
+
+// runDeferred queues every deferred stage of a workflow that may run now.
+// Payouts stay deferred while a circuit breaker is tripped.
+func (a *Agent) runDeferred(ctx context.Context, kind string) ([]uint64, error) {
+	var ids []uint64
+	var ops []func(context.Context)
+	for _, r := range a.workflows.List(kind, StateDeferred, "") {
+		if a.payoutsHalted(kind, r.DeferredStage) != "" {
+			continue
+		}
+		op, err := a.PrepareRetry(ctx, kind, r.ID, r.DeferredStage)
+		if err != nil {
+			// The chain moved on while paused, e.g. the escrow timed out
+			a.workflows.Record(kind, r.ID, StateResumeSkipped, err.Error())
+			continue
+		}
+		ids = append(ids, r.ID)
+		ops = append(ops, op)
+	}
+
+	return ids, a.enqueue(ctx, func(ctx context.Context) {
+		for _, op := range ops {
+			op(ctx)
+		}
+	})
+}
+
+// readRedemption reads one redemption, its recorded XRPL payment and whether
+// its escrow can time out
+func (a *Agent) readRedemption(ctx context.Context, id uint64) (recoveredRedemption, bool, error) {
+	flipCoreABI, err := bindings.ABI(ContractFLIPCore)
+	if err != nil {
+		return recoveredRedemption{}, false, err
+	}
+	escrowABI, err := bindings.ABI(ContractEscrowVault)
+	if err != nil {
+		return recoveredRedemption{}, false, err
+	}
+	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
+	escrowVaultAddr := common.HexToAddress(a.config.Flare.EscrowVaultAddress)
+	redemptionID := new(big.Int).SetUint64(id)
+
+	results, err := a.multicall.Call(ctx, []BatchCall{
+		{Target: flipCoreAddr, ABI: flipCoreABI, Method: "redemptions", Args: []interface{}{redemptionID}},
+		{Target: flipCoreAddr, ABI: flipCoreABI, Method: "redemptionXrplTxHash", Args: []interface{}{redemptionID}},
+		{Target: escrowVaultAddr, ABI: escrowABI, Method: "canTimeout", Args: []interface{}{redemptionID}},
+	})
+	if err != nil {
+		return recoveredRedemption{}, false, err
+	}
+	r, err := decodeRecoveredRedemption(id, results[0], results[1])
+	if err != nil {
+		return recoveredRedemption{}, false, fmt.Errorf("failed to get redemption %d: %w", id, err)
+	}
+	if r.user == (common.Address{}) {
+		return recoveredRedemption{}, false, fmt.Errorf("redemption %d does not exist", id)
+	}
+	var timedOut bool
+	results[2].Decode(&timedOut) // A failed check leaves the escrow open
+	return r, timedOut, nil
+}
+
+// readMinting reads one minting request
+func (a *Agent) readMinting(ctx context.Context, id uint64) (recoveredMinting, error) {
+	flipCoreABI, err := bindings.ABI(ContractFLIPCore)
+	if err != nil {
+		return recoveredMinting{}, err
+	}
+	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
+
+	results, err := a.multicall.Call(ctx, []BatchCall{
+		{Target: flipCoreAddr, ABI: flipCoreABI, Method: "mintingRequests", Args: []interface{}{new(big.Int).SetUint64(id)}},
+	})
+	if err != nil {
+		return recoveredMinting{}, err
+	}
+	m, err := decodeRecoveredMinting(id, results[0])
+	if err != nil {
+		return recoveredMinting{}, fmt.Errorf("failed to get minting %d: %w", id, err)
+	}
+	if m.user == (common.Address{}) {
+		return recoveredMinting{}, fmt.Errorf("minting %d does not exist", id)
+	}
+	return m, nil
+}
@@ -13,10 +13,11 @@ import (
 type PaymentProcessor struct {
 	xrplClient *XRPLClient
 	config     *Config
+	settings   *LiveSettings
 }
 
 // NewPaymentProcessor creates a new payment processor
-func NewPaymentProcessor(config *Config) (*PaymentProcessor, error) {
+func NewPaymentProcessor(config *Config, settings *LiveSettings) (*PaymentProcessor, error) {
 	xrplClient, err := NewXRPLClient(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create XRPL client: %w", err)
@@ -25,6 +26,7 @@ func NewPaymentProcessor(config *Config) (*PaymentProcessor, error) {
 	return &PaymentProcessor{
 		xrplClient: xrplClient,
 		config:     config,
+		settings:   settings,
 	}, nil
 }
 
@@ -34,6 +36,23 @@ func (pp *PaymentProcessor) SendPayment(
 	destination string,
 	amount *big.Int,
 	paymentReference string,
+) (string, error) {
+	done := observeStage(StagePayment)
+	txHash, err := pp.sendPayment(ctx, destination, amount, paymentReference)
+	done(err)
+	if err != nil {
+		paymentsTotal.WithLabelValues("failed").Inc()
+		return "", err
+	}
+	paymentsTotal.WithLabelValues("sent").Inc()
+	return txHash, nil
+}
+
+func (pp *PaymentProcessor) sendPayment(
+	ctx context.Context,
+	destination string,
+	amount *big.Int,
+	paymentReference string,
 ) (string, error) {
 	// Convert amount from wei/drops to XRP drops string
 	// XRP uses 6 decimals, so amount is already in drops (smallest unit)
@@ -46,7 +65,7 @@ func (pp *PaymentProcessor) SendPayment(
 	}
 
 	balanceBig, _ := new(big.Int).SetString(balance, 10)
-	minBalance := big.NewInt(int64(pp.config.Agent.MinXRPBalance))
+	minBalance := new(big.Int).SetUint64(pp.settings.MinXRPBalance())
 	requiredBalance := new(big.Int).Add(amount, minBalance)
 
 	if balanceBig.Cmp(requiredBalance) < 0 {
@@ -54,15 +73,20 @@ func (pp *PaymentProcessor) SendPayment(
 	}
 
 	// Send payment with retries
+	maxRetries, retryDelay := pp.settings.PaymentRetries()
 	var txHash string
 	var lastErr error
 
-	for i := 0; i < pp.config.Agent.MaxPaymentRetries; i++ {
+	for i := 0; i < maxRetries; i++ {
 		if i > 0 {
 			log.Warn().
 				Int("attempt", i+1).
 				Msg("Retrying XRP payment")
-			time.Sleep(time.Duration(pp.config.Agent.PaymentRetryDelay) * time.Second)
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(retryDelay):
+			}
 		}
 
 		txHash, lastErr = pp.xrplClient.SendPayment(ctx, destination, amountDrops, paymentReference)
@@ -80,6 +104,6 @@ func (pp *PaymentProcessor) SendPayment(
 			Msg("Payment attempt failed")
 	}
 
-	return "", fmt.Errorf("failed to send payment after %d attempts: %w", pp.config.Agent.MaxPaymentRetries, lastErr)
+	return "", fmt.Errorf("failed to send payment after %d attempts: %w", maxRetries, lastErr)
 }
 
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/flip-protocol/agent/bindings"
+	"github.com/rs/zerolog/log"
+)
+
+// Reconciliation defaults used when the config leaves them unset
+const (
+	defaultReconcileInterval  = time.Hour
+	defaultReconcileLedgers   = 30000 // About a day of XRPL ledgers
+	defaultReconcileBlocks    = 5000  // A few hours of Flare blocks
+	defaultReconcileReportDir = "reconcile"
+	reconcileBatchSize        = 100
+	// Payments younger than this may still be on their way to FLIPCore
+	reconcileRecordGrace = 10 * time.Minute
+)
+
+// Reconciliation finding kinds
+const (
+	FindingUnrecorded     = "unrecorded_payment"    // Paid on XRPL, no hash on FLIPCore
+	FindingRecordedBad    = "recorded_hash_invalid" // FLIPCore hash missing from or failed on XRPL
+	FindingDuplicate      = "duplicate_payment"     // More than one successful payment for a reference
+	FindingAmountMismatch = "amount_mismatch"       // Delivered drops differ from the redemption amount
+)
+
+// ReconcileFinding is one disagreement between XRPL and FLIPCore, with what
+// an operator should do about it
+type ReconcileFinding struct {
+	Kind         string   `json:"kind"`
+	RedemptionID *uint64  `json:"redemption_id,omitempty"`
+	Reference    string   `json:"reference,omitempty"`
+	XRPLTxHashes []string `json:"xrpl_tx_hashes,omitempty"`
+	RecordedHash string   `json:"recorded_hash,omitempty"`
+	Detail       string   `json:"detail"`
+	Action       string   `json:"action"`
+}
+
+// ReconcileReport is the result of one reconciliation run
+type ReconcileReport struct {
+	GeneratedAt     time.Time          `json:"generated_at"`
+	Wallets         []string           `json:"wallets"`
+	FromLedger      uint64             `json:"from_ledger"`
+	FromBlock       uint64             `json:"from_block"`
+	ToBlock         uint64             `json:"to_block"`
+	PaymentsChecked int                `json:"payments_checked"`
+	RecordsChecked  int                `json:"records_checked"`
+	Findings        []ReconcileFinding `json:"findings"`
+}
+
+// Reconciler compares the agent wallets' XRPL payment history with the
+// payment hashes recorded on FLIPCore
+type Reconciler struct {
+	client      *ethclient.Client
+	flip        *FlipContracts
+	multicall   *Multicaller
+	xrpl        *XRPLClient
+	flipCoreABI *abi.ABI
+	wallets     []string
+	interval    time.Duration
+	ledgers     uint64
+	blocks      uint64
+	reportDir   string
+}
+
+// NewReconciler creates a reconciler for the agent wallet and any extra
+// wallets in reconcile.wallets
+func NewReconciler(client *ethclient.Client, flip *FlipContracts, multicall *Multicaller, xrpl *XRPLClient, config *Config) (*Reconciler, error) {
+	flipCoreABI, err := bindings.ABI(ContractFLIPCore)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reconciler{
+		client:      client,
+		flip:        flip,
+		multicall:   multicall,
+		xrpl:        xrpl,
+		flipCoreABI: flipCoreABI,
+		interval:    time.Duration(config.Reconcile.Interval) * time.Second,
+		ledgers:     uint64(config.Reconcile.LedgerLookback),
+		blocks:      uint64(config.Reconcile.BlockLookback),
+		reportDir:   config.Reconcile.ReportDir,
+	}
+	if r.interval <= 0 {
+		r.interval = defaultReconcileInterval
+	}
+	if r.ledgers == 0 {
+		r.ledgers = defaultReconcileLedgers
+	}
+	if r.blocks == 0 {
+		r.blocks = defaultReconcileBlocks
+	}
+	if r.reportDir == "" {
+		r.reportDir = defaultReconcileReportDir
+	}
+
+	r.wallets = append(r.wallets, xrpl.wallet.Address)
+	for _, w := range config.Reconcile.Wallets {
+		if w != xrpl.wallet.Address {
+			r.wallets = append(r.wallets, w)
+		}
+	}
+	return r, nil
+}
+
+// Run reconciles every interval and writes a report for each run
+func (r *Reconciler) Run(ctx context.Context) {
+	log.Info().
+		Dur("interval", r.interval).
+		Strs("wallets", r.wallets).
+		Str("report_dir", r.reportDir).
+		Msg("Payment reconciliation started")
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := r.Reconcile(ctx, r.ledgers, r.blocks)
+		if err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Msg("Payment reconciliation failed")
+		}
+		if err == nil {
+			if _, err := r.WriteReport(report); err != nil {
+				log.Warn().Err(err).Msg("Failed to write reconciliation report")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile checks XRPL payments from the last ledgers ledgers against
+// FLIPCore, along with every XrplPaymentRecorded event from the last blocks
+// blocks
+func (r *Reconciler) Reconcile(ctx context.Context, ledgers, blocks uint64) (*ReconcileReport, error) {
+	validated, err := r.xrpl.GetLedger(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get validated ledger: %w", err)
+	}
+	head, err := r.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	report := &ReconcileReport{
+		GeneratedAt: time.Now().UTC(),
+		Wallets:     r.wallets,
+		FromLedger:  validated.Index - min(ledgers, validated.Index),
+		FromBlock:   head - min(blocks, head),
+		ToBlock:     head,
+		Findings:    []ReconcileFinding{},
+	}
+
+	// Outgoing payments, by the redemption their reference names
+	paid := make(map[uint64][]XRPLPayment)
+	byHash := make(map[string]XRPLPayment)
+	for _, wallet := range r.wallets {
+		payments, err := r.xrpl.AccountPayments(ctx, wallet, report.FromLedger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payments of %s: %w", wallet, err)
+		}
+		for _, p := range payments {
+			report.PaymentsChecked++
+			byHash[strings.ToUpper(p.Hash)] = p
+			if p.Result != "tesSUCCESS" {
+				continue // Failed attempts moved no funds
+			}
+			if id := redemptionFromReference(p.Reference); id != nil {
+				paid[*id] = append(paid[*id], p)
+			}
+		}
+	}
+
+	recorded, err := r.recordedPayments(ctx, report.FromBlock, head)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[uint64]bool)
+	for id := range paid {
+		ids[id] = true
+	}
+	for id := range recorded {
+		ids[id] = true
+	}
+	sorted := make([]uint64, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for start := 0; start < len(sorted); start += reconcileBatchSize {
+		batch := sorted[start:min(start+reconcileBatchSize, len(sorted))]
+		redemptions, err := r.readRedemptions(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range batch {
+			report.RecordsChecked++
+			findings, err := r.check(ctx, id, redemptions[i], paid[id], byHash)
+			if err != nil {
+				return nil, err
+			}
+			report.Findings = append(report.Findings, findings...)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, f := range report.Findings {
+		counts[f.Kind]++
+		event := log.Warn().Str("kind", f.Kind).Strs("xrpl_tx_hashes", f.XRPLTxHashes).Str("recorded_hash", f.RecordedHash)
+		if f.RedemptionID != nil {
+			event = event.Uint64("redemption_id", *f.RedemptionID)
+		}
+		event.Msg(f.Detail)
+	}
+	for _, kind := range []string{FindingUnrecorded, FindingRecordedBad, FindingDuplicate, FindingAmountMismatch} {
+		reconcileFindings.WithLabelValues(kind).Set(float64(counts[kind]))
+	}
+	log.Info().
+		Int("payments", report.PaymentsChecked).
+		Int("redemptions", report.RecordsChecked).
+		Int("findings", len(report.Findings)).
+		Msg("Payment reconciliation complete")
+	return report, nil
+}
+
+// check compares one redemption's XRPL payments with its recorded hash
+func (r *Reconciler) check(ctx context.Context, id uint64, redemption recoveredRedemption, payments []XRPLPayment, byHash map[string]XRPLPayment) ([]ReconcileFinding, error) {
+	var findings []ReconcileFinding
+	finding := func(kind, detail, action string, hashes ...string) {
+		findings = append(findings, ReconcileFinding{
+			Kind:         kind,
+			RedemptionID: &id,
+			Reference:    generatePaymentReference(new(big.Int).SetUint64(id)),
+			XRPLTxHashes: hashes,
+			RecordedHash: redemption.xrplTxHash,
+			Detail:       detail,
+			Action:       action,
+		})
+	}
+
+	// The recorded payment counts alongside the ones in the window, so a
+	// second payment is caught even when the first is older than the window
+	successful := payments
+	if recorded := redemption.xrplTxHash; recorded != "" {
+		p, ok := byHash[strings.ToUpper(recorded)]
+		if !ok {
+			tx, err := r.xrpl.GetTransaction(ctx, recorded)
+			switch {
+			case err != nil && ctx.Err() != nil:
+				return nil, ctx.Err()
+			case err != nil:
+				finding(FindingRecordedBad,
+					fmt.Sprintf("Recorded XRPL payment %s was not found: %v", recorded, err),
+					"Check whether the user was paid; if not, pay and record the correct hash before the escrow times out")
+			default:
+				p = XRPLPayment{Hash: tx.Hash, Account: tx.Account, Destination: tx.Destination, Result: tx.Result, DeliveredAmount: tx.DeliveredAmount}
+				if !tx.Validated {
+					p.Result = "not validated"
+				}
+				ok = true
+			}
+		}
+		if ok && p.Result != "tesSUCCESS" {
+			finding(FindingRecordedBad,
+				fmt.Sprintf("Recorded XRPL payment %s did not succeed: %s", recorded, p.Result),
+				"The user was not paid by this transaction; pay and record the correct hash before the escrow times out")
+		}
+		if ok && p.Result == "tesSUCCESS" && !containsPayment(successful, p.Hash) {
+			successful = append(successful, p)
+		}
+	}
+
+	if len(successful) > 1 {
+		hashes := make([]string, len(successful))
+		for i, p := range successful {
+			hashes[i] = p.Hash
+		}
+		finding(FindingDuplicate,
+			fmt.Sprintf("Redemption %d was paid %d times", id, len(successful)),
+			"Recover the overpayment from the destination and check why the payment was repeated", hashes...)
+	}
+
+	if redemption.xrplTxHash == "" && len(payments) > 0 {
+		oldest := payments[len(payments)-1]
+		if time.Since(oldest.Time) >= reconcileRecordGrace {
+			finding(FindingUnrecorded,
+				fmt.Sprintf("XRPL payment %s for redemption %d is not recorded on FLIPCore", oldest.Hash, id),
+				"Record the hash with recordXrplPayment and run `agent retry-fdc`, or the redemption will be disputed as unpaid", oldest.Hash)
+		}
+	}
+
+	for _, p := range successful {
+		if p.DeliveredAmount == nil || p.DeliveredAmount.Cmp(redemption.amount) != 0 {
+			delivered := "a non-XRP amount"
+			if p.DeliveredAmount != nil {
+				delivered = formatDrops(p.DeliveredAmount)
+			}
+			finding(FindingAmountMismatch,
+				fmt.Sprintf("XRPL payment %s delivered %s, redemption %d is for %s", p.Hash, delivered, id, formatDrops(redemption.amount)),
+				"Settle the difference with the user, or recover the excess", p.Hash)
+		}
+	}
+	return findings, nil
+}
+
+// recordedPayments returns the hashes FLIPCore recorded in blocks [from, to]
+func (r *Reconciler) recordedPayments(ctx context.Context, from, to uint64) (map[uint64]string, error) {
+	const maxBlockRange uint64 = 29
+
+	flipCore, err := bindings.NewFLIPCoreFilterer(r.flip.Address(ContractFLIPCore), r.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind FLIPCore: %w", err)
+	}
+
+	recorded := make(map[uint64]string)
+	for start := from; start <= to; start += maxBlockRange + 1 {
+		end := min(start+maxBlockRange, to)
+		events, err := flipCore.FilterXrplPaymentRecorded(&bind.FilterOpts{Start: start, End: &end, Context: ctx}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter logs %d-%d: %w", start, end, err)
+		}
+		for events.Next() {
+			recorded[events.Event.RedemptionId.Uint64()] = events.Event.XrplTxHash
+		}
+		err = events.Error()
+		events.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode logs %d-%d: %w", start, end, err)
+		}
+	}
+	return recorded, nil
+}
+
+// readRedemptions reads the redemption and its recorded hash for each ID,
+// batched through Multicall3 and decoded into the FLIPCore binding's types
+func (r *Reconciler) readRedemptions(ctx context.Context, ids []uint64) ([]recoveredRedemption, error) {
+	flipCore := r.flip.Address(ContractFLIPCore)
+	var calls []BatchCall
+	for _, id := range ids {
+		arg := new(big.Int).SetUint64(id)
+		calls = append(calls,
+			BatchCall{Target: flipCore, ABI: r.flipCoreABI, Method: "redemptions", Args: []interface{}{arg}},
+			BatchCall{Target: flipCore, ABI: r.flipCoreABI, Method: "redemptionXrplTxHash", Args: []interface{}{arg}},
+		)
+	}
+	results, err := r.multicall.Call(ctx, calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redemptions: %w", err)
+	}
+
+	redemptions := make([]recoveredRedemption, len(ids))
+	for i, id := range ids {
+		redemption, err := decodeRecoveredRedemption(id, results[2*i], results[2*i+1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redemption %d: %w", id, err)
+		}
+		redemptions[i] = redemption
+	}
+	return redemptions, nil
+}
+
+// WriteReport saves a report as reconcile-<time>.json in the report dir and
+// returns its path
+func (r *Reconciler) WriteReport(report *ReconcileReport) (string, error) {
+	if err := os.MkdirAll(r.reportDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create report dir: %w", err)
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(r.reportDir, "reconcile-"+report.GeneratedAt.Format("20060102T150405Z")+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp, path)
+}
+
+func containsPayment(payments []XRPLPayment, hash string) bool {
+	for _, p := range payments {
+		if strings.EqualFold(p.Hash, hash) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/flip-protocol/agent/bindings"
+)
+
+func TestReconcilerRecordedPaymentsDecodesEvents(t *testing.T) {
+	flipCore := common.HexToAddress("0x00000000000000000000000000000000000F11C0")
+	parsed, err := bindings.ABI(ContractFLIPCore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := parsed.Events["XrplPaymentRecorded"]
+	data, err := event.Inputs.NonIndexed().Pack("ABCDEF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := map[string]interface{}{
+		"address":          flipCore,
+		"topics":           []common.Hash{event.ID, common.BigToHash(big.NewInt(42))},
+		"data":             fmt.Sprintf("0x%x", data),
+		"blockNumber":      "0x10",
+		"transactionHash":  common.Hash{1},
+		"transactionIndex": "0x0",
+		"blockHash":        common.Hash{2},
+		"logIndex":         "0x0",
+		"removed":          false,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_getLogs" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": []interface{}{entry}})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Reconciler{
+		client: client,
+		flip:   &FlipContracts{addresses: map[string]common.Address{ContractFLIPCore: flipCore}},
+	}
+
+	recorded, err := r.recordedPayments(context.Background(), 10, 20)
+	if err != nil {
+		t.Fatalf("recordedPayments: %v", err)
+	}
+	if len(recorded) != 1 || recorded[42] != "ABCDEF" {
+		t.Fatalf("recorded = %v, want 42 -> ABCDEF", recorded)
+	}
+}
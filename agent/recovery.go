@@ -0,0 +1,472 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/flip-protocol/agent/bindings"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultRecoveryStateFile = "recovery_state.json"
+
+// RecoveryState is persisted between runs so startup recovery only scans IDs
+// that can still need action
+type RecoveryState struct {
+	// Lowest IDs that were not in a terminal state at the end of the last pass
+	RedemptionLowWater uint64 `json:"redemption_low_water"`
+	MintingLowWater    uint64 `json:"minting_low_water"`
+
+	// XRPL deposits claimed by mintings below the low-water mark, kept so
+	// deposit reuse is still detected without rescanning them
+	Deposits map[string]uint64 `json:"deposits"`
+}
+
+// loadRecoveryState reads the state file, returning an empty state if there
+// is none yet
+func (a *Agent) loadRecoveryState() (*RecoveryState, error) {
+	state := &RecoveryState{Deposits: make(map[string]uint64)}
+
+	data, err := os.ReadFile(a.recoveryStatePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recovery state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to decode recovery state: %w", err)
+	}
+	if state.Deposits == nil {
+		state.Deposits = make(map[string]uint64)
+	}
+	return state, nil
+}
+
+// saveRecoveryState writes the state file atomically
+func (a *Agent) saveRecoveryState(state *RecoveryState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := a.recoveryStatePath()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write recovery state: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func (a *Agent) recoveryStatePath() string {
+	if a.config.Agent.RecoveryStateFile != "" {
+		return a.config.Agent.RecoveryStateFile
+	}
+	return defaultRecoveryStateFile
+}
+
+// recoveredRedemption is a redemption read during the recovery scan
+type recoveredRedemption struct {
+	id          *big.Int
+	user        common.Address
+	asset       common.Address
+	amount      *big.Int
+	requestedAt *big.Int
+	status      uint8
+	xrplAddress string
+	xrplTxHash  string
+}
+
+// recoverPreviousRuns resumes work left over from previous runs in a single
+// pass: redemptions with a recorded XRPL payment are finalized through FDC,
+// unpaid escrows are paid or disputed, and pending mintings are settled. Reads
+// are batched through Multicall3 and start from the persisted low-water marks.
+func (a *Agent) recoverPreviousRuns(ctx context.Context) error {
+	state, err := a.loadRecoveryState()
+	if err != nil {
+		return err
+	}
+
+	flipCoreABI, err := bindings.ABI(ContractFLIPCore)
+	if err != nil {
+		return err
+	}
+	escrowABI, err := bindings.ABI(ContractEscrowVault)
+	if err != nil {
+		return err
+	}
+	flipCoreAddr := common.HexToAddress(a.config.Flare.FLIPCoreAddress)
+	escrowVaultAddr := common.HexToAddress(a.config.Flare.EscrowVaultAddress)
+
+	counts, err := a.multicall.Call(ctx, []BatchCall{
+		{Target: flipCoreAddr, ABI: flipCoreABI, Method: "nextRedemptionId"},
+		{Target: flipCoreAddr, ABI: flipCoreABI, Method: "nextMintingId"},
+	})
+	if err != nil {
+		return err
+	}
+	nextRedemption, nextMinting, err := decodeNextIDs(counts)
+	if err != nil {
+		return err
+	}
+
+	// A redeployed FLIPCore starts from zero again
+	if state.RedemptionLowWater > nextRedemption || state.MintingLowWater > nextMinting {
+		log.Warn().Msg("Recovery low-water mark is ahead of FLIPCore, rescanning from 0")
+		state = &RecoveryState{Deposits: make(map[string]uint64)}
+	}
+	for hash, id := range state.Deposits {
+		a.mintingTxHashes[hash] = id
+	}
+
+	log.Info().
+		Uint64("redemptions_from", state.RedemptionLowWater).
+		Uint64("next_redemption_id", nextRedemption).
+		Uint64("mintings_from", state.MintingLowWater).
+		Uint64("next_minting_id", nextMinting).
+		Msg("Recovering work from previous runs")
+
+	redemptions, redemptionLowWater, err := a.scanRedemptions(ctx, flipCoreABI, flipCoreAddr, state.RedemptionLowWater, nextRedemption)
+	if err != nil {
+		return err
+	}
+	mintings, mintingLowWater, err := a.scanMintings(ctx, flipCoreABI, flipCoreAddr, state.MintingLowWater, nextMinting)
+	if err != nil {
+		return err
+	}
+
+	// Only unpaid escrows need a timeout check
+	var unpaid []recoveredRedemption
+	var timeoutCalls []BatchCall
+	for _, r := range redemptions {
+		if r.status == 2 && r.xrplTxHash == "" {
+			unpaid = append(unpaid, r)
+			timeoutCalls = append(timeoutCalls, BatchCall{Target: escrowVaultAddr, ABI: escrowABI, Method: "canTimeout", Args: []interface{}{r.id}})
+		}
+	}
+	timeouts, err := a.multicall.Call(ctx, timeoutCalls)
+	if err != nil {
+		return err
+	}
+
+	// Payments already sent only need FDC finalization. Work not started
+	// before a shutdown stays below the low-water mark for the next start.
+	for _, r := range redemptions {
+		if a.stopping.Load() {
+			break
+		}
+		if r.status == 2 && r.xrplTxHash != "" {
+			a.recoverFDCSubmission(ctx, r)
+		}
+	}
+
+	for i, r := range unpaid {
+		if a.stopping.Load() {
+			break
+		}
+		var timedOut bool
+		if err := timeouts[i].Decode(&timedOut); err != nil {
+			log.Warn().Err(err).Uint64("redemption_id", r.id.Uint64()).Msg("Failed to check escrow timeout")
+			continue
+		}
+		a.recoverPendingEscrow(ctx, r, timedOut)
+	}
+
+	for _, req := range mintings {
+		if a.stopping.Load() {
+			break
+		}
+		a.recoverMinting(ctx, req)
+	}
+
+	state.RedemptionLowWater = redemptionLowWater
+	state.MintingLowWater = mintingLowWater
+	state.Deposits = make(map[string]uint64, len(a.mintingTxHashes))
+	for hash, id := range a.mintingTxHashes {
+		state.Deposits[hash] = id
+	}
+	if err := a.saveRecoveryState(state); err != nil {
+		return err
+	}
+
+	log.Info().
+		Uint64("redemption_low_water", redemptionLowWater).
+		Uint64("minting_low_water", mintingLowWater).
+		Msg("Recovery pass complete")
+	return nil
+}
+
+// scanRedemptions reads redemptions [from, to) and returns the escrowed ones
+// with the new low-water mark
+func (a *Agent) scanRedemptions(ctx context.Context, parsed *abi.ABI, flipCore common.Address, from, to uint64) ([]recoveredRedemption, uint64, error) {
+	var calls []BatchCall
+	for i := from; i < to; i++ {
+		id := new(big.Int).SetUint64(i)
+		calls = append(calls,
+			BatchCall{Target: flipCore, ABI: parsed, Method: "redemptions", Args: []interface{}{id}},
+			BatchCall{Target: flipCore, ABI: parsed, Method: "redemptionXrplTxHash", Args: []interface{}{id}},
+		)
+	}
+	results, err := a.multicall.Call(ctx, calls)
+	if err != nil {
+		return nil, from, err
+	}
+
+	lowWater := to
+	var escrowed []recoveredRedemption
+	for i := from; i < to; i++ {
+		r, err := decodeRecoveredRedemption(i, results[2*(i-from)], results[2*(i-from)+1])
+		if err != nil {
+			log.Warn().Err(err).Uint64("redemption_id", i).Msg("Failed to get redemption")
+			lowWater = min(lowWater, i)
+			continue
+		}
+		if r.status < 4 { // Not Finalized, Failed or Timeout
+			lowWater = min(lowWater, i)
+		}
+		if r.status != 2 { // Only EscrowCreated needs the agent
+			continue
+		}
+		escrowed = append(escrowed, r)
+	}
+
+	return escrowed, lowWater, nil
+}
+
+// decodeNextIDs decodes nextRedemptionId and nextMintingId results
+func decodeNextIDs(counts []BatchResult) (nextRedemption, nextMinting uint64, err error) {
+	var redemptions, mintings *big.Int
+	if err := counts[0].Decode(&redemptions); err != nil {
+		return 0, 0, err
+	}
+	if err := counts[1].Decode(&mintings); err != nil {
+		return 0, 0, err
+	}
+	return redemptions.Uint64(), mintings.Uint64(), nil
+}
+
+// decodeRecoveredRedemption decodes the redemptions and redemptionXrplTxHash
+// results for one ID. A failed hash read leaves the hash empty.
+func decodeRecoveredRedemption(id uint64, redemption, txHash BatchResult) (recoveredRedemption, error) {
+	var record bindings.FLIPCoreRedemption
+	if err := redemption.Decode(&record); err != nil {
+		return recoveredRedemption{}, err
+	}
+	r := recoveredRedemption{
+		id:          new(big.Int).SetUint64(id),
+		user:        record.User,
+		asset:       record.Asset,
+		amount:      record.Amount,
+		requestedAt: record.RequestedAt,
+		status:      record.Status,
+		xrplAddress: strings.Trim(record.XrplAddress, "\x00"),
+	}
+	if err := txHash.Decode(&r.xrplTxHash); err != nil {
+		r.xrplTxHash = ""
+	}
+	return r, nil
+}
+
+// recoveredMinting is a minting read during the recovery scan
+type recoveredMinting struct {
+	req    MintingAttestationRequest
+	user   common.Address
+	status uint8
+}
+
+// scanMintings reads mintings [from, to), indexes their deposits and returns
+// the non-terminal ones with the new low-water mark
+func (a *Agent) scanMintings(ctx context.Context, parsed *abi.ABI, flipCore common.Address, from, to uint64) ([]recoveredMinting, uint64, error) {
+	var calls []BatchCall
+	for i := from; i < to; i++ {
+		calls = append(calls, BatchCall{Target: flipCore, ABI: parsed, Method: "mintingRequests", Args: []interface{}{new(big.Int).SetUint64(i)}})
+	}
+	results, err := a.multicall.Call(ctx, calls)
+	if err != nil {
+		return nil, from, err
+	}
+
+	lowWater := to
+	var open []recoveredMinting
+	for i := from; i < to; i++ {
+		m, err := decodeRecoveredMinting(i, results[i-from])
+		if err != nil {
+			log.Warn().Err(err).Uint64("minting_id", i).Msg("Failed to get minting request")
+			lowWater = min(lowWater, i)
+			continue
+		}
+
+		// Index every deposit so later mintings cannot reuse it
+		a.claimDepositTx(m.req.XrplTxHash, i)
+
+		if m.status < 3 { // Not Finalized, Failed or Timeout
+			lowWater = min(lowWater, i)
+			open = append(open, m)
+		}
+	}
+
+	return open, lowWater, nil
+}
+
+// decodeRecoveredMinting decodes a mintingRequests result
+func decodeRecoveredMinting(id uint64, result BatchResult) (recoveredMinting, error) {
+	var record bindings.FLIPCoreMintingRequest
+	if err := result.Decode(&record); err != nil {
+		return recoveredMinting{}, err
+	}
+	return recoveredMinting{
+		req: MintingAttestationRequest{
+			MintingID:               new(big.Int).SetUint64(id),
+			Asset:                   record.Asset,
+			CollateralReservationID: record.CollateralReservationId,
+			XrplTxHash:              record.XrplTxHash,
+			XrpAmount:               record.XrpAmount,
+			FxrpAmount:              record.FxrpAmount,
+		},
+		user:   record.User,
+		status: record.Status,
+	}, nil
+}
+
+// disputeRejected proves a rejected payout unpaid so FLIPCore fails the
+// redemption into the Firelight backstop instead of letting its escrow time out
+func (a *Agent) disputeRejected(ctx context.Context, r recoveredRedemption) {
+	if !a.admit(WorkflowRedemption, r.id.Uint64(), RetryDispute) {
+		return
+	}
+	a.resolveUnpaid(ctx, r)
+}
+
+// resolveUnpaid reports an unpaid redemption to FLIPCore with an FDC
+// nonexistence proof
+func (a *Agent) resolveUnpaid(ctx context.Context, r recoveredRedemption) {
+	id := r.id.Uint64()
+	if err := a.disputeNonPayment(ctx, r.id, r.xrplAddress, r.amount, r.requestedAt); err != nil {
+		log.Error().Err(err).Uint64("redemption_id", id).Msg("Failed to resolve unpaid escrow with nonexistence proof")
+		a.workflows.Note(WorkflowRedemption, id, "dispute_failed", err.Error(), "")
+		return
+	}
+	a.workflows.Record(WorkflowRedemption, id, StateDisputed, "non-payment proven")
+}
+
+// recoverFDCSubmission retries FDC finalization for a redemption whose XRPL
+// payment was recorded but never finalized
+func (a *Agent) recoverFDCSubmission(ctx context.Context, r recoveredRedemption) {
+	id := r.id.Uint64()
+	if !a.admit(WorkflowRedemption, id, RetryFDC) {
+		return
+	}
+	log.Info().
+		Uint64("redemption_id", id).
+		Str("xrpl_tx_hash", r.xrplTxHash).
+		Msg("Found redemption needing FDC finalization, retrying...")
+
+	proof, err := a.fdcSubmitter.GetFDCProof(ctx, r.xrplTxHash)
+	if err != nil {
+		log.Warn().Err(err).Uint64("redemption_id", id).Msg("Failed to get FDC proof for recovery")
+		a.workflows.Note(WorkflowRedemption, id, "fdc_proof_failed", err.Error(), "")
+		return
+	}
+
+	if err := a.fdcSubmitter.SubmitProof(ctx, r.id, proof); err != nil {
+		log.Warn().Err(err).Uint64("redemption_id", id).Msg("Failed to submit FDC proof in recovery")
+		a.workflows.Note(WorkflowRedemption, id, "fdc_submit_failed", err.Error(), "")
+	} else {
+		log.Info().Uint64("redemption_id", id).Msg("Successfully recovered FDC submission")
+		a.workflows.Record(WorkflowRedemption, id, StateFinalized, fmt.Sprintf("FDC round %d", proof.RoundID))
+		a.notifySettled(id, r.xrplTxHash, proof.RoundID)
+	}
+}
+
+// recoverPendingEscrow pays an unpaid escrow, or disputes it once the window
+// has passed
+func (a *Agent) recoverPendingEscrow(ctx context.Context, r recoveredRedemption, timedOut bool) {
+	id := r.id.Uint64()
+	if a.rejected(id) {
+		a.disputeRejected(ctx, r)
+		return
+	}
+	if !a.admit(WorkflowRedemption, id, RetryPayment) {
+		return
+	}
+
+	// Paying after the escrow window would pay the user twice once the
+	// escrow times out; prove the non-payment instead. A payment this agent
+	// already sent is recorded by handleEscrowCreated instead.
+	if _, paid := a.sentPayment(id); timedOut && !paid {
+		a.resolveUnpaid(ctx, r)
+		return
+	}
+
+	log.Info().
+		Uint64("redemption_id", id).
+		Str("user", r.user.Hex()).
+		Str("xrpl_address", r.xrplAddress).
+		Str("amount", r.amount.String()).
+		Msg("Found pending escrow, processing...")
+
+	event := EscrowCreatedEvent{
+		RedemptionID:     r.id,
+		User:             r.user,
+		Amount:           r.amount,
+		XRPLAddress:      r.xrplAddress,
+		PaymentReference: generatePaymentReference(r.id),
+	}
+
+	if err := a.handleEscrowCreated(ctx, event); err != nil {
+		log.Error().Err(err).Uint64("redemption_id", id).Msg("Failed to process pending escrow")
+	}
+}
+
+// recoverMinting settles a pending minting, screening its user first as
+// handleMintingRequested does, or finishes its FDC path
+func (a *Agent) recoverMinting(ctx context.Context, m recoveredMinting) {
+	req, status := m.req, m.status
+	id := req.MintingID.Uint64()
+	stage := RetryFDC
+	if status == 0 {
+		stage = RetryProvisional
+	}
+	if !a.admit(WorkflowMinting, id, stage) {
+		return
+	}
+
+	switch status {
+	case 0: // Pending
+		if ok, err := a.screen(ctx, WorkflowMinting, id, "", m.user); !ok {
+			if err != nil {
+				log.Error().Err(err).Uint64("minting_id", id).Msg("Failed to screen pending minting")
+			}
+			return
+		}
+
+		hasLiquidity, err := a.checkLPLiquidity(ctx, req.Asset, req.FxrpAmount)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to check LP liquidity")
+		}
+		if !hasLiquidity {
+			log.Warn().
+				Uint64("minting_id", id).
+				Str("asset", req.Asset.Hex()).
+				Msg("Skipping minting - no LP liquidity available. Register LP via LiquidityProviderRegistry.registerERC20Position()")
+			return
+		}
+
+		log.Info().Uint64("minting_id", id).Msg("LP liquidity available, processing minting...")
+		if _, err := a.settleMintingProvisional(ctx, req); err != nil {
+			log.Error().Err(err).Uint64("minting_id", id).Msg("Failed to process pending minting")
+			a.workflows.Note(WorkflowMinting, id, "provisional_failed", err.Error(), "")
+		} else {
+			a.processedMintings[id] = true
+		}
+	case 1, 2: // ProvisionalSettled or QueuedForFDC - waiting on the FDC outcome
+		a.finalizeMintingWithFDC(ctx, req)
+	}
+}
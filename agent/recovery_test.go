@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/flip-protocol/agent/bindings"
+)
+
+// packedResult encodes values as method's return data and decodes it the
+// way Multicaller does
+func packedResult(t *testing.T, contract, method string, values ...interface{}) BatchResult {
+	t.Helper()
+	parsed, err := bindings.ABI(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := parsed.Methods[method].Outputs.Pack(values...)
+	if err != nil {
+		t.Fatalf("pack %s: %v", method, err)
+	}
+	return decodeBatchResult(BatchCall{ABI: parsed, Method: method}, true, data)
+}
+
+var errTestRevert = errors.New("reverted")
+
+func TestDecodeRecoveredRedemption(t *testing.T) {
+	user := common.HexToAddress(testEVMAddress)
+	redemption := packedResult(t, ContractFLIPCore, "redemptions",
+		user, common.Address{}, big.NewInt(1000), big.NewInt(1700000000), big.NewInt(0), big.NewInt(0),
+		uint8(2), big.NewInt(0), true, testXRPLAddress+"\x00\x00")
+	txHash := packedResult(t, ContractFLIPCore, "redemptionXrplTxHash", "ABCDEF")
+
+	r, err := decodeRecoveredRedemption(7, redemption, txHash)
+	if err != nil {
+		t.Fatalf("decodeRecoveredRedemption: %v", err)
+	}
+	if r.id.Uint64() != 7 || r.user != user || r.amount.Int64() != 1000 || r.status != 2 {
+		t.Fatalf("redemption = %+v", r)
+	}
+	if r.xrplAddress != testXRPLAddress || r.xrplTxHash != "ABCDEF" {
+		t.Fatalf("xrpl address %q, hash %q", r.xrplAddress, r.xrplTxHash)
+	}
+
+	// A failed hash read leaves the redemption unpaid rather than failing it
+	r, err = decodeRecoveredRedemption(7, redemption, BatchResult{Err: errTestRevert})
+	if err != nil || r.xrplTxHash != "" {
+		t.Fatalf("decode with failed hash = %+v, %v", r, err)
+	}
+	if _, err := decodeRecoveredRedemption(7, BatchResult{Err: errTestRevert}, txHash); err == nil {
+		t.Fatal("failed redemption read decoded")
+	}
+}
+
+func TestDecodeRecoveredMinting(t *testing.T) {
+	user := common.HexToAddress(testEVMAddress)
+	result := packedResult(t, ContractFLIPCore, "mintingRequests",
+		user, common.Address{}, big.NewInt(11), "DEPOSIT", big.NewInt(5000000), big.NewInt(5000000),
+		big.NewInt(1700000000), big.NewInt(0), big.NewInt(0), uint8(1), big.NewInt(0), common.Address{}, big.NewInt(0), true)
+
+	m, err := decodeRecoveredMinting(3, result)
+	if err != nil {
+		t.Fatalf("decodeRecoveredMinting: %v", err)
+	}
+	if m.user != user || m.status != 1 || m.req.XrplTxHash != "DEPOSIT" || m.req.CollateralReservationID.Int64() != 11 {
+		t.Fatalf("minting = %+v", m)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/flip-protocol/scoring"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultSuccessRateRefresh bounds how stale the success rate may get
+const defaultSuccessRateRefresh = 5 * time.Minute
+
+const routingABI = `[
+	{"inputs":[{"name":"_redemptionId","type":"uint256"}],"name":"getRedemptionStatus","outputs":[{"name":"status","type":"uint8"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"name":"_operator","type":"address"}],"name":"getOperatorStats","outputs":[{"name":"operatorStake","type":"uint256"},{"name":"routingErrors","type":"uint256"},{"name":"haircutErrors","type":"uint256"},{"name":"errorRate","type":"uint256"},{"name":"rewards","type":"uint256"}],"stateMutability":"view","type":"function"}
+]`
+
+// Router computes the scoring inputs for each request and decides between the
+// provisional fast lane and the FDC queue
+type Router struct {
+	scorer     *scoring.DeterministicScorer
+	volatility *VolatilityTracker
+	client     *ethclient.Client
+	parsed     abi.ABI
+	flip       *FlipContracts // OperatorRegistry can change on reload
+	multicall  *Multicaller
+	workflows  *WorkflowStore
+	operator   common.Address
+	refresh    time.Duration
+
+	mu            sync.Mutex
+	successRate   *big.Int
+	successRateAt time.Time
+	lowWater      uint64          // Every redemption below this is settled and counted
+	settled       map[uint64]bool // Counted redemptions at or above lowWater
+	finalized     int
+	total         int
+}
+
+// unroutedStates are the states of redemptions the agent saw but did not
+// route or pay, which do not count towards its success rate
+var unroutedStates = map[string]bool{
+	StateRequested:        true,
+	StateDeferred:         true,
+	StateAwaitingApproval: true,
+	StateRejected:         true,
+	StateResumeSkipped:    true,
+}
+
+// RoutingDecision records the inputs and outcome of scoring a request
+type RoutingDecision struct {
+	Params scoring.ScoringParams
+	Result scoring.ScoreResult
+	Feed   string
+}
+
+// NewRouter creates a router for the configured operator
+func NewRouter(client *ethclient.Client, contracts *ContractResolver, flip *FlipContracts, multicall *Multicaller, workflows *WorkflowStore, config *Config) (*Router, error) {
+	parsed, err := abi.JSON(strings.NewReader(routingABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse routing ABI: %w", err)
+	}
+
+	volatility, err := NewVolatilityTracker(client, contracts, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var operator common.Address
+	if config.Flare.PrivateKey != "" {
+		privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(config.Flare.PrivateKey, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		operator = crypto.PubkeyToAddress(privateKey.PublicKey)
+	}
+
+	refresh := time.Duration(config.Scoring.SuccessRateRefresh) * time.Second
+	if refresh <= 0 {
+		refresh = defaultSuccessRateRefresh
+	}
+
+	return &Router{
+		scorer:     scoring.NewDeterministicScorer(),
+		volatility: volatility,
+		client:     client,
+		parsed:     parsed,
+		flip:       flip,
+		multicall:  multicall,
+		workflows:  workflows,
+		operator:   operator,
+		refresh:    refresh,
+		settled:    make(map[uint64]bool),
+	}, nil
+}
+
+// Run keeps the FTSO volatility window filled until ctx is cancelled
+func (r *Router) Run(ctx context.Context) {
+	r.volatility.Run(ctx)
+}
+
+// Evaluate scores a request for asset and amount with live inputs
+func (r *Router) Evaluate(ctx context.Context, asset common.Address, amount *big.Int) (*RoutingDecision, error) {
+	volatility, feed, live := r.volatility.Volatility(asset)
+	if !live {
+		log.Warn().
+			Str("feed", feed).
+			Str("volatility", volatility.String()).
+			Msg("Not enough FTSO samples, using conservative default volatility")
+	}
+
+	successRate := r.SuccessRate(ctx)
+
+	stake, err := r.Stake(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := scoring.ScoringParams{
+		PriceVolatility:  volatility,
+		Amount:           amount,
+		AgentSuccessRate: successRate,
+		AgentStake:       stake,
+		HourOfDay:        time.Now().UTC().Hour(), // FLIPCore uses block.timestamp, i.e. UTC
+	}
+
+	return &RoutingDecision{
+		Params: params,
+		Result: r.scorer.CalculateScore(params),
+		Feed:   feed,
+	}, nil
+}
+
+// SuccessRate returns the share of the redemptions this agent routed or paid
+// that finalized on FLIPCore, cached for the refresh interval. When the
+// refresh fails the previous rate is kept so routing carries on.
+func (r *Router) SuccessRate(ctx context.Context) *big.Int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.successRate != nil && time.Since(r.successRateAt) < r.refresh {
+		return r.successRate
+	}
+
+	if err := r.refreshSettled(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to refresh agent success rate, using the previous value")
+	}
+	r.successRate = scoring.GetAgentSuccessRate(r.finalized, r.total)
+	r.successRateAt = time.Now()
+
+	log.Debug().
+		Int("finalized", r.finalized).
+		Int("settled", r.total).
+		Uint64("low_water", r.lowWater).
+		Str("success_rate", r.successRate.String()).
+		Msg("Agent success rate refreshed")
+
+	return r.successRate
+}
+
+// refreshSettled reads the status of the agent's redemptions that have not
+// settled yet in one batch and counts the ones that have since. Settled
+// statuses are final, so each redemption is read until it settles and never
+// again. Callers hold mu.
+func (r *Router) refreshSettled(ctx context.Context) error {
+	flipCore := r.flip.Address(ContractFLIPCore)
+
+	var open []uint64
+	var calls []BatchCall
+	for _, record := range r.workflows.List(WorkflowRedemption, "", "") {
+		if record.ID < r.lowWater || r.settled[record.ID] || unroutedStates[record.State] {
+			continue
+		}
+		open = append(open, record.ID)
+		calls = append(calls, BatchCall{
+			Target: flipCore,
+			ABI:    &r.parsed,
+			Method: "getRedemptionStatus",
+			Args:   []interface{}{new(big.Int).SetUint64(record.ID)},
+		})
+	}
+
+	if len(calls) > 0 {
+		results, err := r.multicall.Call(ctx, calls)
+		if err != nil {
+			return fmt.Errorf("failed to read redemption statuses: %w", err)
+		}
+		for i, result := range results {
+			if result.Err != nil {
+				continue // Read again on the next refresh
+			}
+			switch result.Values[0].(uint8) {
+			case 4: // Finalized
+				r.finalized++
+			case 5, 6: // Failed, Timeout
+			default:
+				continue
+			}
+			r.total++
+			r.settled[open[i]] = true
+		}
+	}
+
+	// Advance past the settled prefix. Redemptions that may still be routed
+	// (e.g. deferred ones) hold the mark back.
+	lowWater := r.lowWater
+	for _, record := range r.workflows.List(WorkflowRedemption, "", "") {
+		if record.ID < r.lowWater {
+			continue
+		}
+		if !r.settled[record.ID] && record.State != StateRejected && record.State != StateResumeSkipped {
+			break // List is ordered by ID
+		}
+		lowWater = record.ID + 1
+	}
+	r.lowWater = lowWater
+	for id := range r.settled {
+		if id < r.lowWater {
+			delete(r.settled, id)
+		}
+	}
+	return nil
+}
+
+// Stake reads the operator's stake from OperatorRegistry.getOperatorStats
+func (r *Router) Stake(ctx context.Context) (*big.Int, error) {
+	if r.operator == (common.Address{}) {
+		return big.NewInt(0), nil
+	}
+
+	registry := bind.NewBoundContract(r.flip.Address(ContractOperatorRegistry), r.parsed, r.client, r.client, r.client)
+	var result []interface{}
+	if err := registry.Call(&bind.CallOpts{Context: ctx}, &result, "getOperatorStats", r.operator); err != nil {
+		return nil, fmt.Errorf("failed to get operator stats: %w", err)
+	}
+	return result[0].(*big.Int), nil
+}
+
+// log logs the scoring inputs and outcome for a request
+func (d *RoutingDecision) log(kind string, id uint64) {
+	log.Info().
+		Str("kind", kind).
+		Uint64("id", id).
+		Str("feed", d.Feed).
+		Str("volatility", d.Params.PriceVolatility.String()).
+		Str("success_rate", d.Params.AgentSuccessRate.String()).
+		Str("stake", d.Params.AgentStake.String()).
+		Str("amount", d.Params.Amount.String()).
+		Str("score", d.Result.Score.String()).
+		Str("confidence_lower", d.Result.ConfidenceLower.String()).
+		Bool("provisional", d.Result.CanProvisionalSettle).
+		Msg("Routing decision")
+}
+
+// summary renders the decision for workflow timelines
+func (d *RoutingDecision) summary() string {
+	return fmt.Sprintf("score %s (lower %s), volatility %s, success rate %s, stake %s",
+		d.Result.Score, d.Result.ConfidenceLower, d.Params.PriceVolatility, d.Params.AgentSuccessRate, d.Params.AgentStake)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultScreeningTimeout bounds one request to the screening service
+const defaultScreeningTimeout = 10 * time.Second
+
+// ScreeningSubject is the pair of addresses screened before a payout or
+// minting fast lane. XRPLAddress is empty for mintings.
+type ScreeningSubject struct {
+	Workflow    string `json:"workflow"`
+	ID          uint64 `json:"id"`
+	XRPLAddress string `json:"xrpl_address,omitempty"`
+	EVMAddress  string `json:"evm_address"`
+}
+
+// ScreeningHit explains why a subject failed screening
+type ScreeningHit struct {
+	Screener string
+	Address  string
+	Reason   string
+}
+
+func (h *ScreeningHit) String() string {
+	s := fmt.Sprintf("%s: %s", h.Screener, h.Address)
+	if h.Reason != "" {
+		s += " (" + h.Reason + ")"
+	}
+	return s
+}
+
+// Screener checks addresses against a compliance source. Screen returns nil
+// when the subject is clear.
+type Screener interface {
+	Screen(ctx context.Context, subject ScreeningSubject) (*ScreeningHit, error)
+}
+
+// NewScreener builds the screeners enabled in the config, or returns nil
+// when screening is off
+func NewScreener(config *Config) (Screener, error) {
+	var chain screenerChain
+	if config.Screening.DenylistFile != "" {
+		denylist, err := NewDenylistScreener(config.Screening.DenylistFile)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, denylist)
+	}
+	if config.Screening.URL != "" {
+		chain = append(chain, NewHTTPScreener(config))
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// screenerChain runs screeners in order and stops at the first hit
+type screenerChain []Screener
+
+func (c screenerChain) Screen(ctx context.Context, subject ScreeningSubject) (*ScreeningHit, error) {
+	for _, s := range c {
+		hit, err := s.Screen(ctx, subject)
+		if err != nil || hit != nil {
+			return hit, err
+		}
+	}
+	return nil, nil
+}
+
+// DenylistScreener matches addresses against a local file of one address per
+// line ('#' starts a comment). The file is re-read whenever it changes.
+// EVM addresses match case-insensitively, XRPL addresses exactly.
+type DenylistScreener struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	entries map[string]string // Normalised address -> comment
+}
+
+// NewDenylistScreener loads the denylist at path
+func NewDenylistScreener(path string) (*DenylistScreener, error) {
+	d := &DenylistScreener{path: path}
+	if err := d.refresh(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *DenylistScreener) Screen(ctx context.Context, subject ScreeningSubject) (*ScreeningHit, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// A denylist that cannot be read must not let everyone through
+	if err := d.refresh(); err != nil {
+		return nil, err
+	}
+	for _, address := range []string{subject.XRPLAddress, subject.EVMAddress} {
+		if address == "" {
+			continue
+		}
+		if comment, ok := d.entries[denylistKey(address)]; ok {
+			return &ScreeningHit{Screener: "denylist", Address: address, Reason: comment}, nil
+		}
+	}
+	return nil, nil
+}
+
+// refresh reloads the file when its size or modification time changed.
+// Callers hold mu, except NewDenylistScreener.
+func (d *DenylistScreener) refresh() error {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat denylist: %w", err)
+	}
+	if d.entries != nil && info.ModTime().Equal(d.modTime) && info.Size() == d.size {
+		return nil
+	}
+
+	f, err := os.Open(d.path)
+	if err != nil {
+		return fmt.Errorf("failed to open denylist: %w", err)
+	}
+	defer f.Close()
+
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, comment, _ := strings.Cut(scanner.Text(), "#")
+		address := strings.TrimSpace(line)
+		if address == "" {
+			continue
+		}
+		entries[denylistKey(address)] = strings.TrimSpace(comment)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read denylist: %w", err)
+	}
+
+	d.entries = entries
+	d.modTime = info.ModTime()
+	d.size = info.Size()
+	log.Info().
+		Str("path", d.path).
+		Int("entries", len(entries)).
+		Msg("Loaded screening denylist")
+	return nil
+}
+
+// denylistKey normalises an address for lookup
+func denylistKey(address string) string {
+	if common.IsHexAddress(address) {
+		return strings.ToLower(common.HexToAddress(address).Hex())
+	}
+	return address
+}
+
+// HTTPScreener asks a screening service about each subject. The subject is
+// POSTed as JSON and the service answers 200 with {"hit": bool, "reason": "..."}.
+type HTTPScreener struct {
+	url        string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewHTTPScreener creates a client for the configured screening service
+func NewHTTPScreener(config *Config) *HTTPScreener {
+	timeout := time.Duration(config.Screening.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultScreeningTimeout
+	}
+	return &HTTPScreener{
+		url:        config.Screening.URL,
+		apiKey:     config.Screening.APIKey,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (h *HTTPScreener) Screen(ctx context.Context, subject ScreeningSubject) (*ScreeningHit, error) {
+	body, err := json.Marshal(subject)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("screening request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read screening response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	var result struct {
+		Hit     *bool  `json:"hit"`
+		Address string `json:"address"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse screening response: %w", err)
+	}
+	if result.Hit == nil {
+		return nil, errors.New("screening response has no hit field")
+	}
+	if !*result.Hit {
+		return nil, nil
+	}
+	if result.Address == "" {
+		result.Address = subject.EVMAddress
+		if subject.XRPLAddress != "" {
+			result.Address = subject.XRPLAddress + " / " + subject.EVMAddress
+		}
+	}
+	return &ScreeningHit{Screener: "http", Address: result.Address, Reason: result.Reason}, nil
+}
+
+// screen runs an ID's addresses through compliance screening. A hit puts the
+// ID on a screened hold and returns false. When the screener fails the ID is
+// not processed either, unless screening.fail_open is set.
+func (a *Agent) screen(ctx context.Context, kind string, id uint64, xrplAddress string, user common.Address) (bool, error) {
+	if a.screener == nil {
+		return true, nil
+	}
+
+	hit, err := a.screener.Screen(ctx, ScreeningSubject{
+		Workflow:    kind,
+		ID:          id,
+		XRPLAddress: xrplAddress,
+		EVMAddress:  user.Hex(),
+	})
+	if err != nil {
+		screeningTotal.WithLabelValues("error").Inc()
+		if a.config.Screening.FailOpen {
+			log.Warn().Err(err).Str("workflow", kind).Uint64("id", id).Msg("Screening failed, proceeding (fail_open)")
+			a.workflows.Note(kind, id, "screening_failed", err.Error()+", proceeding", "")
+			return true, nil
+		}
+		a.workflows.Note(kind, id, "screening_failed", err.Error(), "")
+		return false, fmt.Errorf("failed to screen addresses: %w", err)
+	}
+	if hit == nil {
+		screeningTotal.WithLabelValues("clear").Inc()
+		return true, nil
+	}
+
+	screeningTotal.WithLabelValues("hit").Inc()
+	a.workflows.SetHold(kind, id, HoldScreened, hit.String(), "screening")
+	log.Warn().
+		Str("workflow", kind).
+		Uint64("id", id).
+		Str("screener", hit.Screener).
+		Str("address", hit.Address).
+		Str("reason", hit.Reason).
+		Msg("Screening hit, holding ID for review")
+	return false, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	testEVMAddress  = "0x52908400098527886E0F7030069857D2E4169EE7"
+	testXRPLAddress = "rPT1Sjq2YGrBMTttX4GZHjKu9dyfzbpAYe"
+)
+
+func writeDenylist(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDenylistScreenerReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	start := time.Now().Add(-time.Hour)
+	writeDenylist(t, path, "# empty to start\n", start)
+
+	d, err := NewDenylistScreener(path)
+	if err != nil {
+		t.Fatalf("NewDenylistScreener: %v", err)
+	}
+	subject := ScreeningSubject{XRPLAddress: testXRPLAddress, EVMAddress: testEVMAddress}
+	if hit, err := d.Screen(context.Background(), subject); err != nil || hit != nil {
+		t.Fatalf("Screen = %v, %v; want clear", hit, err)
+	}
+
+	writeDenylist(t, path, testXRPLAddress+" # sanctioned\n", start.Add(time.Minute))
+	hit, err := d.Screen(context.Background(), subject)
+	if err != nil {
+		t.Fatalf("Screen: %v", err)
+	}
+	if hit == nil || hit.Address != testXRPLAddress || hit.Reason != "sanctioned" {
+		t.Fatalf("hit = %+v, want %s (sanctioned)", hit, testXRPLAddress)
+	}
+
+	// A denylist that disappears fails closed
+	os.Remove(path)
+	if _, err := d.Screen(context.Background(), subject); err == nil {
+		t.Fatal("Screen succeeded without a denylist")
+	}
+}
+
+func TestDenylistScreenerMatchesEVMCaseInsensitively(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	writeDenylist(t, path, "0x52908400098527886e0f7030069857d2e4169ee7\nrLowerCase\n", time.Now())
+
+	d, err := NewDenylistScreener(path)
+	if err != nil {
+		t.Fatalf("NewDenylistScreener: %v", err)
+	}
+	if hit, _ := d.Screen(context.Background(), ScreeningSubject{EVMAddress: testEVMAddress}); hit == nil {
+		t.Fatal("checksummed EVM address did not match its lower-case entry")
+	}
+
+	// XRPL addresses are case-sensitive base58
+	if hit, _ := d.Screen(context.Background(), ScreeningSubject{XRPLAddress: "rlowercase"}); hit != nil {
+		t.Fatalf("XRPL address matched case-insensitively: %+v", hit)
+	}
+}
+
+func TestHTTPScreenerResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantHit bool
+		wantErr bool
+	}{
+		{"hit", http.StatusOK, `{"hit":true,"reason":"OFAC"}`, true, false},
+		{"clear", http.StatusOK, `{"hit":false}`, false, false},
+		{"missing hit", http.StatusOK, `{"reason":"unknown"}`, false, true},
+		{"server error", http.StatusInternalServerError, "boom", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeDAServer(t, tt.status, tt.body)
+			h := NewHTTPScreener(&Config{Screening: ScreeningConfig{URL: server.URL}})
+
+			hit, err := h.Screen(context.Background(), ScreeningSubject{XRPLAddress: testXRPLAddress, EVMAddress: testEVMAddress})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (hit != nil) != tt.wantHit {
+				t.Fatalf("hit = %+v, wantHit %v", hit, tt.wantHit)
+			}
+			if hit != nil && (hit.Reason != "OFAC" || hit.Address != testXRPLAddress+" / "+testEVMAddress) {
+				t.Fatalf("hit = %+v", hit)
+			}
+		})
+	}
+}
+
+// stubScreener returns a fixed result
+type stubScreener struct {
+	hit *ScreeningHit
+	err error
+}
+
+func (s stubScreener) Screen(context.Context, ScreeningSubject) (*ScreeningHit, error) {
+	return s.hit, s.err
+}
+
+func newScreeningTestAgent(t *testing.T, screener Screener, failOpen bool) *Agent {
+	t.Helper()
+	config := &Config{}
+	config.Agent.WorkflowStateFile = filepath.Join(t.TempDir(), "workflow_state.json")
+	config.Screening.FailOpen = failOpen
+	workflows, err := NewWorkflowStore(config)
+	if err != nil {
+		t.Fatalf("NewWorkflowStore: %v", err)
+	}
+	return &Agent{config: config, screener: screener, workflows: workflows}
+}
+
+func TestAgentScreen(t *testing.T) {
+	user := common.HexToAddress(testEVMAddress)
+	down := errors.New("screening service down")
+
+	t.Run("hit holds the ID", func(t *testing.T) {
+		a := newScreeningTestAgent(t, stubScreener{hit: &ScreeningHit{Screener: "denylist", Address: testXRPLAddress}}, true)
+		ok, err := a.screen(context.Background(), WorkflowRedemption, 7, testXRPLAddress, user)
+		if ok || err != nil {
+			t.Fatalf("screen = %v, %v; want false, nil", ok, err)
+		}
+		if hold := a.workflows.Hold(WorkflowRedemption, 7); hold != HoldScreened {
+			t.Fatalf("hold = %q, want %q", hold, HoldScreened)
+		}
+	})
+
+	t.Run("error fails closed", func(t *testing.T) {
+		a := newScreeningTestAgent(t, stubScreener{err: down}, false)
+		ok, err := a.screen(context.Background(), WorkflowMinting, 3, "", user)
+		if ok || !errors.Is(err, down) {
+			t.Fatalf("screen = %v, %v; want false, %v", ok, err, down)
+		}
+		if hold := a.workflows.Hold(WorkflowMinting, 3); hold != "" {
+			t.Fatalf("error placed hold %q", hold)
+		}
+	})
+
+	t.Run("error with fail_open proceeds", func(t *testing.T) {
+		a := newScreeningTestAgent(t, stubScreener{err: down}, true)
+		ok, err := a.screen(context.Background(), WorkflowMinting, 3, "", user)
+		if !ok || err != nil {
+			t.Fatalf("screen = %v, %v; want true, nil", ok, err)
+		}
+		r, _ := a.workflows.Get(WorkflowMinting, 3)
+		if len(r.Timeline) != 1 || r.Timeline[0].Event != "screening_failed" {
+			t.Fatalf("timeline = %+v, want a screening_failed note", r.Timeline)
+		}
+	})
+
+	t.Run("no screener proceeds", func(t *testing.T) {
+		a := newScreeningTestAgent(t, nil, false)
+		if ok, err := a.screen(context.Background(), WorkflowRedemption, 1, testXRPLAddress, user); !ok || err != nil {
+			t.Fatalf("screen = %v, %v; want true, nil", ok, err)
+		}
+	})
+}
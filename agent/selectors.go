@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/flip-protocol/agent/bindings"
+	"github.com/rs/zerolog/log"
+)
+
+// selectorChecks lists the deployed contracts the agent depends on. Each is
+// checked against the full ABI its generated bindings were built from.
+var selectorChecks = []string{
+	ContractFLIPCore,
+	ContractEscrowVault,
+	ContractLPRegistry,
+	ContractOperatorRegistry,
+	ContractSettlementReceipt,
+	ContractBlazeVault,
+}
+
+// verifySelectors fails if deployed bytecode no longer dispatches a method the
+// agent would call, so ABI drift stops the agent instead of reverting later
+func (a *Agent) verifySelectors(ctx context.Context) error {
+	if a.config.Agent.SkipSelectorCheck {
+		log.Warn().Msg("Selector drift check disabled")
+		return nil
+	}
+
+	for _, contract := range selectorChecks {
+		address := a.flip.Address(contract)
+		if address == (common.Address{}) {
+			continue
+		}
+
+		parsed, err := bindings.ABI(contract)
+		if err != nil {
+			return fmt.Errorf("failed to load %s ABI: %w", contract, err)
+		}
+		if err := bindings.CheckDeployed(ctx, a.flareClient, contract, address, parsed); err != nil {
+			return err
+		}
+		log.Info().
+			Str("contract", contract).
+			Str("address", address.Hex()).
+			Int("selectors", len(parsed.Methods)).
+			Msg("Selector drift check passed")
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LiveSettings holds the config fields that are re-applied on SIGHUP.
+// Components read them on every use instead of copying them at startup, so
+// a reload takes effect without interrupting in-flight work.
+type LiveSettings struct {
+	mu                sync.RWMutex
+	pollInterval      time.Duration
+	maxPaymentRetries int
+	paymentRetryDelay time.Duration
+	minXRPBalance     uint64
+	riskLimits        RiskLimits
+}
+
+// RiskLimits caps XRPL payouts, in drops. 0 disables a limit.
+type RiskLimits struct {
+	MaxPerRedemption       uint64
+	MaxPerDestinationDaily uint64
+	MaxHourlyOutflow       uint64
+}
+
+// NewLiveSettings creates settings from a validated config
+func NewLiveSettings(config *Config) *LiveSettings {
+	s := &LiveSettings{}
+	s.Apply(config)
+	return s
+}
+
+// Apply copies the hot-reloadable fields from config and sets the log level
+func (s *LiveSettings) Apply(config *Config) {
+	s.mu.Lock()
+	s.pollInterval = time.Duration(config.Agent.PollingInterval) * time.Second
+	s.maxPaymentRetries = config.Agent.MaxPaymentRetries
+	s.paymentRetryDelay = time.Duration(config.Agent.PaymentRetryDelay) * time.Second
+	s.minXRPBalance = config.Agent.MinXRPBalance
+	s.riskLimits = RiskLimits{
+		MaxPerRedemption:       config.Limits.MaxPerRedemption,
+		MaxPerDestinationDaily: config.Limits.MaxPerDestinationDaily,
+		MaxHourlyOutflow:       config.Limits.MaxHourlyOutflow,
+	}
+	s.mu.Unlock()
+
+	if level, err := zerolog.ParseLevel(config.Agent.LogLevel); err == nil {
+		zerolog.SetGlobalLevel(level)
+	}
+}
+
+// PollInterval is how often the event monitor polls for new blocks
+func (s *LiveSettings) PollInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pollInterval
+}
+
+// PaymentRetries returns the XRPL payment attempt count and delay between them
+func (s *LiveSettings) PaymentRetries() (int, time.Duration) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maxPaymentRetries, s.paymentRetryDelay
+}
+
+// MinXRPBalance is the XRPL balance (drops) kept in reserve after payments
+func (s *LiveSettings) MinXRPBalance() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.minXRPBalance
+}
+
+// RiskLimits returns the payout limits checked before each XRPL payment
+func (s *LiveSettings) RiskLimits() RiskLimits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.riskLimits
+}
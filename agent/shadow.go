@@ -0,0 +1,592 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog/log"
+)
+
+// Shadow defaults used when the config leaves them unset
+const (
+	defaultShadowStateDir = "shadow"
+	defaultShadowJournal  = "journal.jsonl"
+)
+
+// Chains a shadow entry was built for
+const (
+	ShadowChainFlare = "flare"
+	ShadowChainXRPL  = "xrpl"
+)
+
+// shadowMethods maps each transaction the agent sends to the workflow its
+// first argument identifies, so journal entries can be matched to IDs
+var shadowMethods = map[string]string{
+	"finalizeProvisional(uint256,uint256,uint256,uint256)": WorkflowRedemption,
+	"queueForFDC(uint256)":                                 WorkflowRedemption,
+	"recordXrplPayment(uint256,string)":                    WorkflowRedemption,
+	"handleFDCAttestation(uint256,uint256,bool)":           WorkflowRedemption,
+	"checkTimeout(uint256)":                                WorkflowRedemption,
+	"triggerFirelight(uint256)":                            WorkflowRedemption,
+	"finalizeMintingProvisional(uint256,uint256)":          WorkflowMinting,
+	"queueMintingForFDC(uint256)":                          WorkflowMinting,
+	"handleMintingFDCAttestation(uint256,uint256,bool)":    WorkflowMinting,
+	"checkMintingTimeout(uint256)":                         WorkflowMinting,
+	"requestAttestation(bytes)":                            "",
+}
+
+// shadowSelectors is shadowMethods keyed by 4-byte selector
+var shadowSelectors = func() map[string]string {
+	selectors := make(map[string]string)
+	for signature := range shadowMethods {
+		selectors[string(crypto.Keccak256([]byte(signature))[:4])] = signature
+	}
+	return selectors
+}()
+
+// ShadowEntry is one transaction the agent built and signed in dry-run mode
+// but did not send
+type ShadowEntry struct {
+	Time      time.Time       `json:"time"`
+	Chain     string          `json:"chain"`
+	Workflow  string          `json:"workflow,omitempty"`
+	ID        *uint64         `json:"id,omitempty"`
+	Method    string          `json:"method"` // FLIPCore method name, "transfer" or "Payment"
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Value     string          `json:"value,omitempty"` // Wei on Flare, drops on XRPL
+	Reference string          `json:"reference,omitempty"`
+	Hash      string          `json:"hash"` // Hash the transaction would have had
+	Raw       string          `json:"raw"`  // Signed transaction: RLP on Flare, tx_blob on XRPL
+	Tx        json.RawMessage `json:"tx"`
+	Simulated string          `json:"simulated,omitempty"` // "ok", or why the transaction would have failed
+}
+
+// ShadowRecorder appends would-have-sent transactions to the shadow journal
+// and remembers them, so waiting on one succeeds without the network
+type ShadowRecorder struct {
+	path string
+
+	mu       sync.Mutex
+	receipts map[common.Hash]*types.Receipt
+	xrpl     map[string]bool
+}
+
+var (
+	shadowMu        sync.Mutex
+	shadowRecorders = make(map[string]*ShadowRecorder)
+)
+
+// applyShadow moves the agent's state files into shadow.state_dir so a shadow
+// agent can run next to production without sharing its state
+func (c *Config) applyShadow() {
+	if !c.Shadow.Enabled {
+		return
+	}
+	if c.Shadow.StateDir == "" {
+		c.Shadow.StateDir = defaultShadowStateDir
+	}
+	if c.Shadow.Journal == "" {
+		c.Shadow.Journal = filepath.Join(c.Shadow.StateDir, defaultShadowJournal)
+	}
+
+	shadowPath := func(path, fallback string) string {
+		if path == "" {
+			path = fallback
+		}
+		return filepath.Join(c.Shadow.StateDir, filepath.Base(path))
+	}
+	c.Agent.RecoveryStateFile = shadowPath(c.Agent.RecoveryStateFile, defaultRecoveryStateFile)
+	c.Agent.WorkflowStateFile = shadowPath(c.Agent.WorkflowStateFile, defaultWorkflowStateFile)
+	c.Keeper.OutcomeLog = shadowPath(c.Keeper.OutcomeLog, defaultKeeperOutcomeLog)
+	c.Firelight.ReportDir = shadowPath(c.Firelight.ReportDir, defaultFirelightReportDir)
+	c.Breakers.StateFile = shadowPath(c.Breakers.StateFile, defaultBreakerStateFile)
+	c.Limits.StateFile = shadowPath(c.Limits.StateFile, defaultLimitsStateFile)
+	c.Limits.DecisionLog = shadowPath(c.Limits.DecisionLog, defaultLimitsDecisionLog)
+	c.Audit.Journal = shadowPath(c.Audit.Journal, defaultAuditJournal)
+	c.Reconcile.ReportDir = shadowPath(c.Reconcile.ReportDir, defaultReconcileReportDir)
+}
+
+// openShadow returns the recorder for config.Shadow.Journal, or nil when
+// dry-run mode is off. Every client in the process shares one recorder.
+func openShadow(config *Config) (*ShadowRecorder, error) {
+	if !config.Shadow.Enabled {
+		return nil, nil
+	}
+
+	shadowMu.Lock()
+	defer shadowMu.Unlock()
+
+	if s, ok := shadowRecorders[config.Shadow.Journal]; ok {
+		return s, nil
+	}
+	if err := os.MkdirAll(config.Shadow.StateDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create shadow state dir: %w", err)
+	}
+	if dir := filepath.Dir(config.Shadow.Journal); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create shadow journal dir: %w", err)
+		}
+	}
+
+	s := &ShadowRecorder{
+		path:     config.Shadow.Journal,
+		receipts: make(map[common.Hash]*types.Receipt),
+		xrpl:     make(map[string]bool),
+	}
+	shadowRecorders[config.Shadow.Journal] = s
+	log.Warn().
+		Str("journal", s.path).
+		Str("state_dir", config.Shadow.StateDir).
+		Msg("Dry-run mode: XRPL payments and Flare transactions will be recorded, not sent")
+	return s, nil
+}
+
+// dialFlare connects to the Flare RPC. In dry-run mode transactions sent
+// through the client are simulated and recorded instead of broadcast.
+func dialFlare(config *Config) (*ethclient.Client, error) {
+	shadow, err := openShadow(config)
+	if err != nil {
+		return nil, err
+	}
+	if shadow == nil {
+		return ethclient.Dial(config.Flare.RPCURL)
+	}
+
+	// Simulations go through an ordinary client
+	sim, err := ethclient.Dial(config.Flare.RPCURL)
+	if err != nil {
+		return nil, err
+	}
+	transport := &shadowTransport{
+		base:     http.DefaultTransport,
+		recorder: shadow,
+		sim:      sim,
+		chainID:  big.NewInt(config.Flare.ChainID),
+	}
+	client, err := rpc.DialOptions(context.Background(), config.Flare.RPCURL, rpc.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(client), nil
+}
+
+// Sent reports whether an XRPL hash belongs to a payment recorded in dry-run
+// mode. Safe to call on a nil recorder.
+func (s *ShadowRecorder) Sent(xrplTxHash string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.xrpl[strings.ToUpper(xrplTxHash)]
+}
+
+// RecordXRPLPayment journals a signed XRPL payment that was not submitted
+func (s *ShadowRecorder) RecordXRPLPayment(from, destination, amountDrops, memo, hash, blob string, tx json.RawMessage) {
+	entry := ShadowEntry{
+		Chain:     ShadowChainXRPL,
+		Method:    "Payment",
+		From:      from,
+		To:        destination,
+		Value:     amountDrops,
+		Reference: memo,
+		Hash:      hash,
+		Raw:       blob,
+		Tx:        tx,
+	}
+	if entry.ID = redemptionFromReference(memo); entry.ID != nil {
+		entry.Workflow = WorkflowRedemption
+	}
+
+	s.mu.Lock()
+	s.xrpl[strings.ToUpper(hash)] = true
+	s.mu.Unlock()
+	s.record(entry)
+}
+
+// recordFlareTx simulates a signed transaction, journals it and keeps a
+// receipt reflecting the simulation for bind.WaitMined
+func (s *ShadowRecorder) recordFlareTx(ctx context.Context, sim *ethclient.Client, chainID *big.Int, tx *types.Transaction) error {
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		return fmt.Errorf("failed to recover sender: %w", err)
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	txJSON, err := tx.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	entry := ShadowEntry{
+		Chain: ShadowChainFlare,
+		From:  from.Hex(),
+		Value: tx.Value().String(),
+		Hash:  tx.Hash().Hex(),
+		Raw:   hexutil.Encode(raw),
+		Tx:    txJSON,
+	}
+	if tx.To() != nil {
+		entry.To = tx.To().Hex()
+	}
+	entry.Method, entry.Workflow, entry.ID = decodeFlareCall(tx.Data())
+
+	// Gas estimation runs the call against the latest state
+	status := types.ReceiptStatusSuccessful
+	gasUsed, err := sim.EstimateGas(ctx, ethereum.CallMsg{
+		From:     from,
+		To:       tx.To(),
+		GasPrice: tx.GasPrice(),
+		Value:    tx.Value(),
+		Data:     tx.Data(),
+	})
+	switch {
+	case err != nil:
+		status = types.ReceiptStatusFailed
+		gasUsed = tx.Gas()
+		entry.Simulated = err.Error()
+	case gasUsed > tx.Gas():
+		status = types.ReceiptStatusFailed
+		entry.Simulated = fmt.Sprintf("out of gas: needs %d, limit %d", gasUsed, tx.Gas())
+		gasUsed = tx.Gas()
+	default:
+		entry.Simulated = "ok"
+	}
+
+	head, err := sim.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	s.mu.Lock()
+	s.receipts[tx.Hash()] = &types.Receipt{
+		Type:              tx.Type(),
+		Status:            status,
+		CumulativeGasUsed: gasUsed,
+		Logs:              []*types.Log{},
+		TxHash:            tx.Hash(),
+		GasUsed:           gasUsed,
+		EffectiveGasPrice: tx.GasPrice(),
+		BlockNumber:       new(big.Int).SetUint64(head),
+	}
+	s.mu.Unlock()
+	s.record(entry)
+	return nil
+}
+
+func (s *ShadowRecorder) receipt(hash common.Hash) (*types.Receipt, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.receipts[hash]
+	return r, ok
+}
+
+// record appends an entry to the journal. Failures are logged so a shadow
+// run keeps going.
+func (s *ShadowRecorder) record(entry ShadowEntry) {
+	entry.Time = time.Now().UTC()
+
+	event := log.Warn().
+		Str("chain", entry.Chain).
+		Str("method", entry.Method).
+		Str("to", entry.To).
+		Str("hash", entry.Hash)
+	if entry.ID != nil {
+		event = event.Str("workflow", entry.Workflow).Uint64("id", *entry.ID)
+	}
+	if entry.Simulated != "" {
+		event = event.Str("simulated", entry.Simulated)
+	}
+	event.Msg("Dry-run: recorded transaction instead of sending it")
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Warn().Err(err).Str("path", s.path).Msg("Failed to open shadow journal")
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Warn().Err(err).Str("path", s.path).Msg("Failed to write shadow journal")
+	}
+}
+
+// ReadShadowJournal loads every entry from a shadow journal
+func ReadShadowJournal(path string) ([]ShadowEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shadow journal: %w", err)
+	}
+
+	var entries []ShadowEntry
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry ShadowEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("shadow journal line %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// shadowTransport intercepts eth_sendRawTransaction, and receipt lookups for
+// the transactions it intercepted; every other request goes to the node
+type shadowTransport struct {
+	base     http.RoundTripper
+	recorder *ShadowRecorder
+	sim      *ethclient.Client
+	chainID  *big.Int
+}
+
+// jsonrpcMessage is a single JSON-RPC request or response
+type jsonrpcMessage struct {
+	Version string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Params  []json.RawMessage `json:"params,omitempty"`
+	Result  interface{}       `json:"result"`
+	Error   *jsonrpcError     `json:"error,omitempty"`
+}
+
+type jsonrpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (t *shadowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Batches are only used for reads
+	var msg jsonrpcMessage
+	if len(body) == 0 || body[0] != '{' || json.Unmarshal(body, &msg) != nil || len(msg.Params) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	switch msg.Method {
+	case "eth_sendRawTransaction":
+		return t.sendRawTransaction(req, msg)
+	case "eth_getTransactionReceipt":
+		var hash common.Hash
+		if json.Unmarshal(msg.Params[0], &hash) != nil {
+			break
+		}
+		if receipt, ok := t.recorder.receipt(hash); ok {
+			return jsonrpcResponse(req, msg.ID, receipt, nil), nil
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (t *shadowTransport) sendRawTransaction(req *http.Request, msg jsonrpcMessage) (*http.Response, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(msg.Params[0], &raw); err != nil {
+		return jsonrpcResponse(req, msg.ID, nil, &jsonrpcError{Code: -32602, Message: err.Error()}), nil
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return jsonrpcResponse(req, msg.ID, nil, &jsonrpcError{Code: -32602, Message: err.Error()}), nil
+	}
+	if err := t.recorder.recordFlareTx(req.Context(), t.sim, t.chainID, tx); err != nil {
+		return jsonrpcResponse(req, msg.ID, nil, &jsonrpcError{Code: -32000, Message: "dry-run: " + err.Error()}), nil
+	}
+	return jsonrpcResponse(req, msg.ID, tx.Hash(), nil), nil
+}
+
+func jsonrpcResponse(req *http.Request, id json.RawMessage, result interface{}, rpcErr *jsonrpcError) *http.Response {
+	body, err := json.Marshal(jsonrpcMessage{Version: "2.0", ID: id, Result: result, Error: rpcErr})
+	if err != nil {
+		body = []byte(`{"jsonrpc":"2.0","error":{"code":-32603,"message":"dry-run: failed to encode response"}}`)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// ShadowDiff compares what a shadow agent would have done for one ID with
+// what production did on chain
+type ShadowDiff struct {
+	Workflow    string   `json:"workflow"`
+	ID          uint64   `json:"id"`
+	Shadow      []string `json:"shadow"`     // Transactions the shadow agent would have sent, in order
+	Production  string   `json:"production"` // On-chain state left by production
+	Differences []string `json:"differences,omitempty"`
+	Notes       []string `json:"notes,omitempty"`
+}
+
+// ShadowReport compares journal entries with the on-chain state of the same
+// IDs. Entries without an ID, e.g. attestation requests, are not compared.
+func (a *Agent) ShadowReport(ctx context.Context, entries []ShadowEntry) ([]ShadowDiff, error) {
+	var order []string
+	byID := make(map[string][]ShadowEntry)
+	for _, e := range entries {
+		if e.ID == nil {
+			continue
+		}
+		key := workflowKey(e.Workflow, *e.ID)
+		if _, ok := byID[key]; !ok {
+			order = append(order, key)
+		}
+		byID[key] = append(byID[key], e)
+	}
+
+	var diffs []ShadowDiff
+	for _, key := range order {
+		group := byID[key]
+		diff := ShadowDiff{Workflow: group[0].Workflow, ID: *group[0].ID}
+		for _, e := range group {
+			diff.Shadow = append(diff.Shadow, e.Method)
+			if e.Simulated != "" && e.Simulated != "ok" {
+				diff.Notes = append(diff.Notes, fmt.Sprintf("%s would have failed: %s", e.Method, e.Simulated))
+			}
+		}
+
+		var err error
+		switch diff.Workflow {
+		case WorkflowRedemption:
+			err = a.diffRedemption(ctx, &diff, group)
+		case WorkflowMinting:
+			err = a.diffMinting(ctx, &diff, group)
+		}
+		if err != nil {
+			diff.Production = "unknown"
+			diff.Differences = append(diff.Differences, "could not read production state: "+err.Error())
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// shadowLane reports which lane the shadow agent chose: "provisional",
+// "fdc" or "" if it sent neither call
+func shadowLane(group []ShadowEntry, provisional, fdc string) string {
+	for _, e := range group {
+		switch e.Method {
+		case provisional:
+			return "provisional"
+		case fdc:
+			return "fdc"
+		}
+	}
+	return ""
+}
+
+func (a *Agent) diffRedemption(ctx context.Context, diff *ShadowDiff, group []ShadowEntry) error {
+	inspection, err := a.inspectRedemption(ctx, diff.ID)
+	if err != nil {
+		return err
+	}
+	diff.Production = inspection.Status
+	if inspection.XRPLTxHash != "" {
+		diff.Production += ", paid " + inspection.XRPLTxHash
+	}
+
+	// The escrow only exists if production took the provisional lane
+	production := ""
+	switch {
+	case inspection.EscrowStatus != "None":
+		production = "provisional"
+	case inspection.Status != "Pending":
+		production = "fdc"
+	}
+	shadow := shadowLane(group, "finalizeProvisional", "queueForFDC")
+	if shadow != "" && production != "" && shadow != production {
+		diff.Differences = append(diff.Differences, fmt.Sprintf("lane: shadow %s, production %s", shadow, production))
+	}
+
+	var payment *ShadowEntry
+	for i := range group {
+		if group[i].Chain == ShadowChainXRPL {
+			payment = &group[i]
+		}
+	}
+	switch {
+	case payment == nil && inspection.XRPLTxHash != "":
+		diff.Differences = append(diff.Differences, "production paid, shadow did not")
+	case payment == nil:
+	case inspection.XRPLTxHash == "":
+		diff.Differences = append(diff.Differences, fmt.Sprintf("shadow would have paid %s drops to %s, production has not paid", payment.Value, payment.To))
+	case inspection.XRPLError != "":
+		diff.Notes = append(diff.Notes, "could not fetch production payment: "+inspection.XRPLError)
+	default:
+		if inspection.XRPLDestination != payment.To {
+			diff.Differences = append(diff.Differences, fmt.Sprintf("destination: shadow %s, production %s", payment.To, inspection.XRPLDestination))
+		}
+		if inspection.XRPLDelivered != payment.Value {
+			diff.Differences = append(diff.Differences, fmt.Sprintf("amount: shadow %s drops, production delivered %s", payment.Value, inspection.XRPLDelivered))
+		}
+	}
+	return nil
+}
+
+func (a *Agent) diffMinting(ctx context.Context, diff *ShadowDiff, group []ShadowEntry) error {
+	m, err := a.readMinting(ctx, diff.ID)
+	if err != nil {
+		return err
+	}
+	diff.Production = "status " + strconv.Itoa(int(m.status))
+
+	production := ""
+	switch m.status {
+	case 1:
+		production = "provisional"
+	case 2:
+		production = "fdc"
+	}
+	shadow := shadowLane(group, "finalizeMintingProvisional", "queueMintingForFDC")
+	if shadow != "" && production != "" && shadow != production {
+		diff.Differences = append(diff.Differences, fmt.Sprintf("lane: shadow %s, production %s", shadow, production))
+	}
+	return nil
+}
+
+// errShadowJournalEmpty is returned when there is nothing to compare
+var errShadowJournalEmpty = errors.New("shadow journal has no entries for a redemption or minting")
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// stateLockFile sits next to the workflow state and is held by whichever
+// process owns the agent's state files
+const stateLockFile = "agent.lock"
+
+// errStateLocked means another process, normally the daemon, owns the state
+var errStateLocked = errors.New("agent state is locked by another process")
+
+// lockStateDir takes an exclusive lock on the directory holding the agent's
+// state files. The daemon holds it while running, and one-shot commands that
+// run workflow stages in-process take it so they never write the workflow,
+// limits or breaker state alongside a running daemon. The returned function
+// releases the lock.
+func lockStateDir(config *Config) (func(), error) {
+	path := config.Agent.WorkflowStateFile
+	if path == "" {
+		path = defaultWorkflowStateFile
+	}
+	lockPath := filepath.Join(filepath.Dir(path), stateLockFile)
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("%w (%s)", errStateLocked, lockPath)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
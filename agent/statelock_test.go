@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockStateDirIsExclusive(t *testing.T) {
+	config := &Config{}
+	config.Agent.WorkflowStateFile = filepath.Join(t.TempDir(), "workflow_state.json")
+
+	unlock, err := lockStateDir(config)
+	if err != nil {
+		t.Fatalf("lockStateDir: %v", err)
+	}
+	if _, err := lockStateDir(config); !errors.Is(err, errStateLocked) {
+		t.Fatalf("second lock err = %v, want errStateLocked", err)
+	}
+
+	unlock()
+	unlock, err = lockStateDir(config)
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	unlock()
+}
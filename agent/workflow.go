@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultWorkflowStateFile = "workflow_state.json"
+	maxTimelineEntries       = 200
+)
+
+// Workflows the agent drives
+const (
+	WorkflowRedemption = "redemption"
+	WorkflowMinting    = "minting"
+)
+
+// Stages an operator can force-retry. Each maps onto the handler or recovery
+// path that owns it, so a retry does exactly what the agent would have done.
+const (
+	RetryProvisional = "provisional" // Score and settle (or queue for FDC) a pending request
+	RetryPayment     = "payment"     // Pay an unpaid escrow, or dispute it after the window
+	RetryFDC         = "fdc"         // Prove a recorded payment or minting deposit through FDC
+	RetryDispute     = "dispute"     // Prove a rejected payout unpaid through FDC
+)
+
+// Operator holds that stop the agent acting on an ID
+const (
+	HoldSkipped     = "skipped"     // Operator will handle it outside the agent
+	HoldQuarantined = "quarantined" // Suspicious, kept for investigation
+	HoldScreened    = "screened"    // Compliance screening hit, released only by an operator
+)
+
+// Workflow states recorded in the timeline
+const (
+	StateRequested        = "requested"
+	StateDeferred         = "deferred"    // Seen while the workflow was paused or payouts were halted
+	StateInterrupted      = "interrupted" // Left unfinished by a shutdown, resumed on the next start
+	StateQueuedFDC        = "queued_fdc"
+	StateEscrowCreated    = "escrow_created"
+	StateAwaitingApproval = "awaiting_approval" // Payout over a risk limit, in the approval queue
+	StateRejected         = "rejected"          // Payout rejected by an operator
+	StateProvisional      = "provisional_settled"
+	StatePaymentSent      = "payment_sent"
+	StatePaymentRecorded  = "payment_recorded"
+	StateDisputed         = "disputed"
+	StateFinalized        = "finalized"
+	StateFailed           = "failed"
+	StateResumeSkipped    = "resume_skipped" // Deferred stage no longer applied on resume
+)
+
+// WorkflowRecord is the agent's view of one redemption or minting
+type WorkflowRecord struct {
+	Kind          string          `json:"kind"`
+	ID            uint64          `json:"id"`
+	State         string          `json:"state"`
+	DeferredStage string          `json:"deferred_stage,omitempty"` // Stage to run on resume or restart
+	Hold          string          `json:"hold,omitempty"`
+	HoldReason    string          `json:"hold_reason,omitempty"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	Timeline      []TimelineEntry `json:"timeline,omitempty"`
+}
+
+// workflowState is the persisted form of WorkflowStore
+type workflowState struct {
+	Paused  map[string]bool  `json:"paused"`
+	Records []WorkflowRecord `json:"records"`
+}
+
+// WorkflowStore tracks the state and timeline of every ID the agent has
+// touched, operator holds and which workflows are paused. Every change is
+// written to disk so holds and pauses survive a restart.
+type WorkflowStore struct {
+	path string
+
+	mu      sync.RWMutex
+	paused  map[string]bool
+	records map[string]*WorkflowRecord
+}
+
+// NewWorkflowStore loads the store from config.Agent.WorkflowStateFile
+func NewWorkflowStore(config *Config) (*WorkflowStore, error) {
+	path := config.Agent.WorkflowStateFile
+	if path == "" {
+		path = defaultWorkflowStateFile
+	}
+
+	s := &WorkflowStore{
+		path:    path,
+		paused:  make(map[string]bool),
+		records: make(map[string]*WorkflowRecord),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow state: %w", err)
+	}
+
+	var state workflowState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode workflow state: %w", err)
+	}
+	for kind, paused := range state.Paused {
+		s.paused[kind] = paused
+	}
+	for i := range state.Records {
+		r := state.Records[i]
+		s.records[workflowKey(r.Kind, r.ID)] = &r
+	}
+
+	for kind, paused := range s.paused {
+		if paused {
+			log.Warn().Str("workflow", kind).Msg("Workflow is paused from a previous run")
+		}
+	}
+	return s, nil
+}
+
+func workflowKey(kind string, id uint64) string {
+	return fmt.Sprintf("%s/%d", kind, id)
+}
+
+// Record moves an ID to a new state
+func (s *WorkflowStore) Record(kind string, id uint64, state, detail string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.record(kind, id)
+	r.State = state
+	if state != StateDeferred && state != StateInterrupted {
+		r.DeferredStage = ""
+	}
+	s.appendEntry(r, TimelineEntry{Event: state, Detail: detail})
+	s.save()
+}
+
+// Note adds a timeline entry without changing state, e.g. a failed attempt
+// or an operator action
+func (s *WorkflowStore) Note(kind string, id uint64, event, detail, operator string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appendEntry(s.record(kind, id), TimelineEntry{Event: event, Detail: detail, Operator: operator})
+	s.save()
+}
+
+// Defer records that stage was not run, e.g. because the workflow is paused
+func (s *WorkflowStore) Defer(kind string, id uint64, stage, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.record(kind, id)
+	if r.State == StateDeferred && r.DeferredStage == stage {
+		return
+	}
+	r.State = StateDeferred
+	r.DeferredStage = stage
+	s.appendEntry(r, TimelineEntry{Event: StateDeferred, Detail: reason + " before " + stage})
+	s.save()
+}
+
+// Interrupt records that stage was cut short, or never started, because the
+// agent was shutting down
+func (s *WorkflowStore) Interrupt(kind string, id uint64, stage, detail string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.record(kind, id)
+	r.State = StateInterrupted
+	r.DeferredStage = stage
+	s.appendEntry(r, TimelineEntry{Event: StateInterrupted, Detail: stage + ": " + detail})
+	s.save()
+}
+
+// SetHold places or clears (hold == "") an operator hold on an ID
+func (s *WorkflowStore) SetHold(kind string, id uint64, hold, reason, operator string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.record(kind, id)
+	event := hold
+	if hold == "" {
+		event = "released"
+	}
+	r.Hold = hold
+	r.HoldReason = reason
+	s.appendEntry(r, TimelineEntry{Event: event, Detail: reason, Operator: operator})
+	s.save()
+}
+
+// Hold returns the operator hold on an ID, or ""
+func (s *WorkflowStore) Hold(kind string, id uint64) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if r, ok := s.records[workflowKey(kind, id)]; ok {
+		return r.Hold
+	}
+	return ""
+}
+
+// SetPaused pauses or resumes a workflow
+func (s *WorkflowStore) SetPaused(kind string, paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paused[kind] = paused
+	s.save()
+}
+
+// Paused reports whether a workflow is paused
+func (s *WorkflowStore) Paused(kind string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.paused[kind]
+}
+
+// Get returns a copy of one record
+func (s *WorkflowStore) Get(kind string, id uint64) (WorkflowRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.records[workflowKey(kind, id)]
+	if !ok {
+		return WorkflowRecord{}, false
+	}
+	copied := *r
+	copied.Timeline = append([]TimelineEntry(nil), r.Timeline...)
+	return copied, true
+}
+
+// List returns matching records, without timelines, ordered by kind and ID.
+// Empty filters match everything.
+func (s *WorkflowStore) List(kind, state, hold string) []WorkflowRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []WorkflowRecord
+	for _, r := range s.records {
+		if (kind != "" && r.Kind != kind) || (state != "" && r.State != state) || (hold != "" && r.Hold != hold) {
+			continue
+		}
+		copied := *r
+		copied.Timeline = nil
+		list = append(list, copied)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// record returns the record for an ID, creating it. Callers hold s.mu.
+func (s *WorkflowStore) record(kind string, id uint64) *WorkflowRecord {
+	key := workflowKey(kind, id)
+	r, ok := s.records[key]
+	if !ok {
+		r = &WorkflowRecord{Kind: kind, ID: id}
+		s.records[key] = r
+	}
+	return r
+}
+
+func (s *WorkflowStore) appendEntry(r *WorkflowRecord, entry TimelineEntry) {
+	entry.Time = time.Now().UTC()
+	r.UpdatedAt = entry.Time
+	r.Timeline = append(r.Timeline, entry)
+	if len(r.Timeline) > maxTimelineEntries {
+		r.Timeline = r.Timeline[len(r.Timeline)-maxTimelineEntries:]
+	}
+}
+
+// save writes the store atomically. Callers hold s.mu. Failures are logged
+// rather than returned so bookkeeping never blocks settlement.
+func (s *WorkflowStore) save() {
+	state := workflowState{Paused: s.paused, Records: make([]WorkflowRecord, 0, len(s.records))}
+	for _, r := range s.records {
+		state.Records = append(state.Records, *r)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode workflow state")
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Error().Err(err).Msg("Failed to write workflow state")
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		log.Error().Err(err).Msg("Failed to write workflow state")
+	}
+}
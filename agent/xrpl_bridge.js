@@ -2,8 +2,12 @@
 // XRPL payment bridge - called by Go agent to send XRP payments
 const xrpl = require('xrpl');
 
-async function sendPayment(seed, destination, amountDrops, memoData) {
-  const client = new xrpl.Client('wss://s.altnet.rippletest.net:51233');
+const DEFAULT_WS_URL = 'wss://s.altnet.rippletest.net:51233';
+
+// signPayment autofills and signs a payment without submitting it. The agent
+// journals the returned transaction and blob before it submits the blob.
+async function signPayment(seed, destination, amountDrops, memoData, wsUrl) {
+  const client = new xrpl.Client(wsUrl || DEFAULT_WS_URL);
   await client.connect();
 
   const wallet = xrpl.Wallet.fromSeed(seed);
@@ -17,16 +21,38 @@ async function sendPayment(seed, destination, amountDrops, memoData) {
   };
 
   if (memoData) {
+    // A 32-byte hex reference is sent as raw bytes so FDC recognizes it as
+    // the standard payment reference; anything else is sent as UTF-8 text
+    const isStandardReference = /^(0x)?[0-9a-fA-F]{64}$/.test(memoData);
+    const memoBytes = isStandardReference
+      ? Buffer.from(memoData.replace(/^0x/, ''), 'hex')
+      : Buffer.from(memoData, 'utf8');
     payment.Memos = [{
       Memo: {
-        MemoData: Buffer.from(memoData, 'utf8').toString('hex').toUpperCase()
+        MemoData: memoBytes.toString('hex').toUpperCase()
       }
     }];
   }
 
   const prepared = await client.autofill(payment);
   const signed = wallet.sign(prepared);
-  const result = await client.submitAndWait(signed.tx_blob);
+
+  await client.disconnect();
+
+  return {
+    success: true,
+    txHash: signed.hash,
+    txBlob: signed.tx_blob,
+    tx: prepared,
+  };
+}
+
+// submitPayment submits a signed blob and waits for it to be validated
+async function submitPayment(txBlob, wsUrl) {
+  const client = new xrpl.Client(wsUrl || DEFAULT_WS_URL);
+  await client.connect();
+
+  const result = await client.submitAndWait(txBlob);
 
   await client.disconnect();
 
@@ -40,11 +66,8 @@ async function sendPayment(seed, destination, amountDrops, memoData) {
   }
 }
 
-// CLI interface
-const args = process.argv.slice(2);
-if (args.length >= 3) {
-  const [seed, destination, amountDrops, memoData] = args;
-  sendPayment(seed, destination, amountDrops, memoData || '')
+function run(promise) {
+  promise
     .then(result => {
       console.log(JSON.stringify(result));
       process.exit(0);
@@ -53,8 +76,20 @@ if (args.length >= 3) {
       console.error(JSON.stringify({ success: false, error: err.message }));
       process.exit(1);
     });
+}
+
+// CLI interface
+const signOnly = process.argv.includes('--sign-only');
+const args = process.argv.slice(2).filter(arg => arg !== '--sign-only');
+if (args[0] === '--submit' && args.length >= 2) {
+  const [, txBlob, wsUrl] = args;
+  run(submitPayment(txBlob, wsUrl));
+} else if (signOnly && args.length >= 3) {
+  const [seed, destination, amountDrops, memoData, wsUrl] = args;
+  run(signPayment(seed, destination, amountDrops, memoData || '', wsUrl));
 } else {
-  console.error('Usage: xrpl_bridge.js <seed> <destination> <amountDrops> [memoData]');
+  console.error('Usage: xrpl_bridge.js <seed> <destination> <amountDrops> [memoData] [wsUrl] --sign-only');
+  console.error('       xrpl_bridge.js --submit <txBlob> [wsUrl]');
   process.exit(1);
 }
 
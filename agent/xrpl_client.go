@@ -7,9 +7,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -20,7 +22,10 @@ import (
 // XRPLClient handles XRPL connections and operations
 type XRPLClient struct {
 	rpcURL string
+	wsURL  string
 	wallet *XRPLWallet
+	shadow *ShadowRecorder // Set in dry-run mode
+	audit  *AuditJournal
 }
 
 // XRPLWallet represents an XRPL wallet
@@ -35,16 +40,28 @@ func NewXRPLClient(config *Config) (*XRPLClient, error) {
 	// In production, use proper XRPL key derivation
 	// For testnet, we can use the seed directly with xrpl.js or similar
 	// For MVP, we'll extract address via account_info call
-	
+
 	wallet := &XRPLWallet{
 		Seed: config.XRPL.WalletSeed,
 	}
 
 	// For MVP, address will be derived by Node.js bridge when needed
 	// In production, derive address from seed properly using XRPL key derivation
+	shadow, err := openShadow(config)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := openAudit(config)
+	if err != nil {
+		return nil, err
+	}
+
 	client := &XRPLClient{
-		rpcURL: config.XRPL.TestnetRPC,
+		rpcURL: config.XRPL.RPCURL,
+		wsURL:  config.XRPL.WSURL,
 		wallet: wallet,
+		shadow: shadow,
+		audit:  audit,
 	}
 
 	// Derive address using Node.js (temporary solution)
@@ -69,6 +86,33 @@ func NewXRPLClient(config *Config) (*XRPLClient, error) {
 	return client, nil
 }
 
+// paymentReferencePattern matches a 32-byte FDC payment reference in hex
+var paymentReferencePattern = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{64}$`)
+
+// encodeMemoData returns the MemoData xrpl_bridge.js puts on a payment: a
+// payment reference is sent as its 32 raw bytes, so FDC sees the standard
+// reference, and any other memo as UTF-8
+func encodeMemoData(memo string) string {
+	memoBytes := []byte(memo)
+	if paymentReferencePattern.MatchString(memo) {
+		memoBytes, _ = hex.DecodeString(strings.TrimPrefix(memo, "0x"))
+	}
+	return strings.ToUpper(hex.EncodeToString(memoBytes))
+}
+
+// decodeMemoData reverses encodeMemoData: 32 bytes are a payment reference,
+// returned as lower-case hex, and anything else is read as UTF-8
+func decodeMemoData(memoData string) string {
+	memo, err := hex.DecodeString(memoData)
+	if err != nil {
+		return ""
+	}
+	if len(memo) == 32 {
+		return hex.EncodeToString(memo)
+	}
+	return string(memo)
+}
+
 // GetBalance gets the XRP balance for the wallet
 func (c *XRPLClient) GetBalance(ctx context.Context) (string, error) {
 	reqBody := map[string]interface{}{
@@ -94,109 +138,82 @@ func (c *XRPLClient) GetBalance(ctx context.Context) (string, error) {
 		return "0", err
 	}
 
-	// Convert XRP to drops (1 XRP = 1,000,000 drops)
-	balanceFloat, err := strconv.ParseFloat(result.Result.AccountData.Balance, 64)
-	if err != nil {
-		return "0", fmt.Errorf("failed to parse balance: %w", err)
+	// account_info reports the balance in drops already
+	drops, ok := new(big.Int).SetString(result.Result.AccountData.Balance, 10)
+	if !ok {
+		return "0", fmt.Errorf("failed to parse balance %q", result.Result.AccountData.Balance)
 	}
 
-	drops := int64(balanceFloat * 1_000_000)
-	return strconv.FormatInt(drops, 10), nil
+	balance, _ := new(big.Float).SetInt(drops).Float64()
+	xrplBalance.Set(balance)
+	return drops.String(), nil
 }
 
-// SendPayment sends an XRP payment with a memo
-func (c *XRPLClient) SendPayment(ctx context.Context, destination string, amountDrops string, memoData string) (string, error) {
-	log.Info().
-		Str("destination", destination).
-		Str("amount", amountDrops).
-		Str("memo", memoData).
-		Msg("Sending XRP payment")
+// bridgeResult is what xrpl_bridge.js prints
+type bridgeResult struct {
+	Success bool            `json:"success"`
+	TxHash  string          `json:"txHash"`
+	Error   string          `json:"error"`
+	TxBlob  string          `json:"txBlob"` // --sign-only
+	Tx      json.RawMessage `json:"tx"`     // --sign-only: the autofilled transaction
+}
 
-	// Parse amount from drops string
-	amountInt, err := strconv.ParseInt(amountDrops, 10, 64)
+// runBridge runs xrpl_bridge.js with args and decodes its output
+func (c *XRPLClient) runBridge(ctx context.Context, args ...string) (*bridgeResult, error) {
+	// Get absolute path to bridge script
+	bridgePath, err := filepath.Abs("./xrpl_bridge.js")
 	if err != nil {
-		return "", fmt.Errorf("invalid amount: %w", err)
+		return nil, fmt.Errorf("failed to get bridge script path: %w", err)
 	}
 
-	// Convert drops to XRP amount string
-	amountXRP := float64(amountInt) / 1_000_000
-	amountStr := strconv.FormatFloat(amountXRP, 'f', 6, 64)
-
-	// Get account info for sequence number
-	accountInfoReq := map[string]interface{}{
-		"method": "account_info",
-		"params": []map[string]interface{}{
-			{
-				"account":      c.wallet.Address,
-				"strict":       true,
-				"ledger_index": "validated",
-			},
-		},
+	cmd := exec.CommandContext(ctx, "node", append([]string{bridgePath}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute XRPL bridge: %w, output: %s", err, string(output))
 	}
 
-	var accountInfo struct {
-		Result struct {
-			AccountData struct {
-				Sequence json.Number `json:"Sequence"`
-			} `json:"account_data"`
-		} `json:"result"`
+	var result bridgeResult
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse bridge output: %w, output: %s", err, string(output))
 	}
-
-	if err := c.callRPC(ctx, accountInfoReq, &accountInfo); err != nil {
-		return "", fmt.Errorf("failed to get account info: %w", err)
+	if !result.Success {
+		return nil, fmt.Errorf("XRPL payment failed: %s", result.Error)
 	}
+	return &result, nil
+}
 
-	// Build payment transaction
-	paymentTx := map[string]interface{}{
-		"TransactionType": "Payment",
-		"Account":         c.wallet.Address,
-		"Destination":     destination,
-		"Amount":          amountStr,
-		"Sequence":        accountInfo.Result.AccountData.Sequence,
-		"Fee":             "12", // Minimum fee in drops
-	}
+// SendPayment sends an XRP payment with a memo. The bridge autofills and
+// signs the payment first; the exact transaction and blob are journaled
+// before the blob is submitted.
+func (c *XRPLClient) SendPayment(ctx context.Context, destination string, amountDrops string, memoData string) (string, error) {
+	log.Info().
+		Str("destination", destination).
+		Str("amount", amountDrops).
+		Str("memo", memoData).
+		Msg("Sending XRP payment")
 
-	// Add memo if provided
-	if memoData != "" {
-		memoBytes := []byte(memoData)
-		paymentTx["Memos"] = []map[string]interface{}{
-			{
-				"Memo": map[string]interface{}{
-					"MemoData": hex.EncodeToString(memoBytes),
-				},
-			},
-		}
+	if _, err := strconv.ParseUint(amountDrops, 10, 64); err != nil {
+		return "", fmt.Errorf("invalid amount: %w", err)
 	}
 
-	// Use Node.js bridge script for XRPL payments
-	// Get absolute path to bridge script
-	bridgePath, err := filepath.Abs("./xrpl_bridge.js")
-	if err != nil {
-		return "", fmt.Errorf("failed to get bridge script path: %w", err)
-	}
-	
-	cmd := exec.CommandContext(ctx, "node", bridgePath, c.wallet.Seed, destination, amountDrops, memoData)
-	
-	output, err := cmd.CombinedOutput()
+	signed, err := c.runBridge(ctx, c.wallet.Seed, destination, amountDrops, memoData, c.wsURL, "--sign-only")
 	if err != nil {
-		return "", fmt.Errorf("failed to execute XRPL bridge: %w, output: %s", err, string(output))
+		return "", err
 	}
+	txHash := signed.TxHash
+	c.auditPayment(AuditXRPLBuilt, memoData, signed.Tx)
+	c.auditPayment(AuditXRPLSigned, memoData, map[string]interface{}{"hash": txHash, "tx_blob": signed.TxBlob})
 
-	var result struct {
-		Success bool   `json:"success"`
-		TxHash  string `json:"txHash"`
-		Error   string `json:"error"`
-	}
-
-	if err := json.Unmarshal(output, &result); err != nil {
-		return "", fmt.Errorf("failed to parse bridge output: %w, output: %s", err, string(output))
+	if c.shadow != nil {
+		c.shadow.RecordXRPLPayment(c.wallet.Address, destination, amountDrops, memoData, txHash, signed.TxBlob, signed.Tx)
+		return txHash, nil
 	}
 
-	if !result.Success {
-		return "", fmt.Errorf("XRPL payment failed: %s", result.Error)
+	if _, err := c.runBridge(ctx, "--submit", signed.TxBlob, c.wsURL); err != nil {
+		// The blob may still be validated before its LastLedgerSequence
+		return "", fmt.Errorf("failed to submit payment %s: %w", txHash, err)
 	}
-
-	txHash := result.TxHash
+	c.auditPayment(AuditXRPLSubmitted, memoData, map[string]interface{}{"hash": txHash, "destination": destination, "amount_drops": amountDrops})
 	log.Info().
 		Str("tx_hash", txHash).
 		Msg("XRP payment submitted successfully")
@@ -204,8 +221,21 @@ func (c *XRPLClient) SendPayment(ctx context.Context, destination string, amount
 	return txHash, nil
 }
 
+// auditPayment journals a payment step against the redemption its
+// reference identifies
+func (c *XRPLClient) auditPayment(entryType, reference string, data interface{}) {
+	if id := redemptionFromReference(reference); id != nil {
+		c.audit.RecordFor(entryType, WorkflowRedemption, *id, data)
+		return
+	}
+	c.audit.Record(entryType, data)
+}
+
 // WaitForFinalization waits for XRPL transaction finalization
 func (c *XRPLClient) WaitForFinalization(ctx context.Context, txHash string) error {
+	if c.shadow.Sent(txHash) {
+		return nil
+	}
 	maxAttempts := 10
 	for i := 0; i < maxAttempts; i++ {
 		req := map[string]interface{}{
@@ -230,11 +260,231 @@ func (c *XRPLClient) WaitForFinalization(ctx context.Context, txHash string) err
 				Msg("Transaction finalized")
 			return nil
 		}
-		time.Sleep(1 * time.Second)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(1 * time.Second):
+		}
 	}
 	return fmt.Errorf("transaction not finalized after %d attempts", maxAttempts)
 }
 
+// XRPLTransaction is the subset of an XRPL transaction needed to check a
+// payment
+type XRPLTransaction struct {
+	Hash            string
+	TransactionType string
+	Account         string
+	Destination     string
+	Validated       bool
+	Result          string   // Engine result, e.g. tesSUCCESS
+	DeliveredAmount *big.Int // Drops; nil if the payment delivered an issued currency
+	LedgerIndex     uint64
+}
+
+// GetTransaction fetches a transaction by hash
+func (c *XRPLClient) GetTransaction(ctx context.Context, txHash string) (*XRPLTransaction, error) {
+	req := map[string]interface{}{
+		"method": "tx",
+		"params": []map[string]interface{}{
+			{
+				"transaction": txHash,
+				"binary":      false,
+			},
+		},
+	}
+
+	var result struct {
+		Result struct {
+			Hash            string `json:"hash"`
+			TransactionType string `json:"TransactionType"`
+			Account         string `json:"Account"`
+			Destination     string `json:"Destination"`
+			Validated       bool   `json:"validated"`
+			LedgerIndex     uint64 `json:"ledger_index"`
+			Meta            struct {
+				TransactionResult string          `json:"TransactionResult"`
+				DeliveredAmount   json.RawMessage `json:"delivered_amount"`
+			} `json:"meta"`
+			Error string `json:"error"`
+		} `json:"result"`
+	}
+
+	if err := c.callRPC(ctx, req, &result); err != nil {
+		return nil, err
+	}
+	if result.Result.Error != "" {
+		return nil, fmt.Errorf("tx request failed: %s", result.Result.Error)
+	}
+
+	tx := &XRPLTransaction{
+		Hash:            result.Result.Hash,
+		TransactionType: result.Result.TransactionType,
+		Account:         result.Result.Account,
+		Destination:     result.Result.Destination,
+		Validated:       result.Result.Validated,
+		Result:          result.Result.Meta.TransactionResult,
+		LedgerIndex:     result.Result.LedgerIndex,
+	}
+
+	// XRP amounts are a string of drops, issued currencies an object
+	var drops string
+	if err := json.Unmarshal(result.Result.Meta.DeliveredAmount, &drops); err == nil {
+		amount, ok := new(big.Int).SetString(drops, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid delivered_amount %q", drops)
+		}
+		tx.DeliveredAmount = amount
+	}
+
+	return tx, nil
+}
+
+// XRPLPayment is an outgoing payment found in an account's history
+type XRPLPayment struct {
+	Hash            string    `json:"hash"`
+	Account         string    `json:"account"`
+	Destination     string    `json:"destination"`
+	Reference       string    `json:"reference,omitempty"` // First memo: a payment reference in hex, otherwise UTF-8
+	Result          string    `json:"result"`
+	DeliveredAmount *big.Int  `json:"delivered_drops,omitempty"` // nil for issued currencies
+	LedgerIndex     uint64    `json:"ledger_index"`
+	Time            time.Time `json:"time"`
+}
+
+// AccountPayments returns the validated payments sent by account in ledgers
+// minLedger and later, newest first
+func (c *XRPLClient) AccountPayments(ctx context.Context, account string, minLedger uint64) ([]XRPLPayment, error) {
+	var payments []XRPLPayment
+	var marker json.RawMessage
+	for {
+		params := map[string]interface{}{
+			"account":          account,
+			"ledger_index_min": minLedger,
+			"ledger_index_max": -1,
+			"limit":            200,
+		}
+		if marker != nil {
+			params["marker"] = marker
+		}
+		req := map[string]interface{}{
+			"method": "account_tx",
+			"params": []map[string]interface{}{params},
+		}
+
+		var result struct {
+			Result struct {
+				Transactions []struct {
+					Tx struct {
+						Hash            string `json:"hash"`
+						TransactionType string `json:"TransactionType"`
+						Account         string `json:"Account"`
+						Destination     string `json:"Destination"`
+						Date            int64  `json:"date"`
+						LedgerIndex     uint64 `json:"ledger_index"`
+						Memos           []struct {
+							Memo struct {
+								MemoData string `json:"MemoData"`
+							} `json:"Memo"`
+						} `json:"Memos"`
+					} `json:"tx"`
+					Meta struct {
+						TransactionResult string          `json:"TransactionResult"`
+						DeliveredAmount   json.RawMessage `json:"delivered_amount"`
+					} `json:"meta"`
+					Validated bool `json:"validated"`
+				} `json:"transactions"`
+				Marker json.RawMessage `json:"marker"`
+				Error  string          `json:"error"`
+			} `json:"result"`
+		}
+
+		if err := c.callRPC(ctx, req, &result); err != nil {
+			return nil, err
+		}
+		if result.Result.Error != "" {
+			return nil, fmt.Errorf("account_tx request failed: %s", result.Result.Error)
+		}
+
+		for _, t := range result.Result.Transactions {
+			if !t.Validated || t.Tx.TransactionType != "Payment" || t.Tx.Account != account {
+				continue
+			}
+			payment := XRPLPayment{
+				Hash:        t.Tx.Hash,
+				Account:     t.Tx.Account,
+				Destination: t.Tx.Destination,
+				Result:      t.Meta.TransactionResult,
+				LedgerIndex: t.Tx.LedgerIndex,
+				Time:        time.Unix(t.Tx.Date+rippleEpochOffset, 0).UTC(),
+			}
+			if len(t.Tx.Memos) > 0 {
+				payment.Reference = decodeMemoData(t.Tx.Memos[0].Memo.MemoData)
+			}
+			var drops string
+			if err := json.Unmarshal(t.Meta.DeliveredAmount, &drops); err == nil {
+				payment.DeliveredAmount, _ = new(big.Int).SetString(drops, 10)
+			}
+			payments = append(payments, payment)
+		}
+
+		if len(result.Result.Marker) == 0 || string(result.Result.Marker) == "null" {
+			return payments, nil
+		}
+		marker = result.Result.Marker
+	}
+}
+
+// rippleEpochOffset is the number of seconds between the Unix and Ripple epochs
+const rippleEpochOffset = 946684800
+
+// LedgerInfo identifies a closed XRPL ledger
+type LedgerInfo struct {
+	Index     uint64
+	CloseTime time.Time
+}
+
+// GetLedger fetches a ledger by index, or the latest validated ledger when
+// ledgerIndex is nil
+func (c *XRPLClient) GetLedger(ctx context.Context, ledgerIndex *uint64) (*LedgerInfo, error) {
+	var index interface{} = "validated"
+	if ledgerIndex != nil {
+		index = *ledgerIndex
+	}
+
+	req := map[string]interface{}{
+		"method": "ledger",
+		"params": []map[string]interface{}{
+			{
+				"ledger_index": index,
+			},
+		},
+	}
+
+	var result struct {
+		Result struct {
+			LedgerIndex uint64 `json:"ledger_index"`
+			Ledger      struct {
+				CloseTime int64 `json:"close_time"`
+			} `json:"ledger"`
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		} `json:"result"`
+	}
+
+	if err := c.callRPC(ctx, req, &result); err != nil {
+		return nil, err
+	}
+	if result.Result.Error != "" {
+		return nil, fmt.Errorf("ledger request failed: %s", result.Result.Error)
+	}
+
+	return &LedgerInfo{
+		Index:     result.Result.LedgerIndex,
+		CloseTime: time.Unix(result.Result.Ledger.CloseTime+rippleEpochOffset, 0),
+	}, nil
+}
+
 // callRPC makes an HTTP JSON-RPC call to XRPL
 func (c *XRPLClient) callRPC(ctx context.Context, reqBody map[string]interface{}, result interface{}) error {
 	jsonData, err := json.Marshal(reqBody)
@@ -266,4 +516,3 @@ func (c *XRPLClient) callRPC(ctx context.Context, reqBody map[string]interface{}
 
 	return nil
 }
-
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newFakeXRPLServer answers JSON-RPC requests with the canned result for
+// their method
+func newFakeXRPLServer(t *testing.T, results map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, ok := results[req.Method]
+		if !ok {
+			http.Error(w, "unexpected method "+req.Method, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"result":` + result + `}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestXRPLClientGetBalanceReturnsDrops(t *testing.T) {
+	server := newFakeXRPLServer(t, map[string]string{
+		"account_info": `{"account_data":{"Account":"rAgent","Balance":"123456789"},"validated":true}`,
+	})
+	client := &XRPLClient{rpcURL: server.URL, wallet: &XRPLWallet{Address: "rAgent"}}
+
+	balance, err := client.GetBalance(context.Background())
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if balance != "123456789" {
+		t.Fatalf("balance = %s drops, want 123456789", balance)
+	}
+}
+
+func TestXRPLClientGetBalanceRejectsNonInteger(t *testing.T) {
+	server := newFakeXRPLServer(t, map[string]string{
+		"account_info": `{"account_data":{"Account":"rAgent","Balance":"123.45"}}`,
+	})
+	client := &XRPLClient{rpcURL: server.URL, wallet: &XRPLWallet{Address: "rAgent"}}
+
+	if _, err := client.GetBalance(context.Background()); err == nil {
+		t.Fatal("GetBalance accepted a fractional drops balance")
+	}
+}
+
+func TestEncodeMemoDataMatchesBridge(t *testing.T) {
+	reference := generatePaymentReference(big.NewInt(42))
+	tests := []struct {
+		memo string
+		want string
+	}{
+		// A payment reference is sent as 32 raw bytes, so MemoData is the reference itself
+		{reference, strings.ToUpper(reference)},
+		{"0x" + reference, strings.ToUpper(reference)},
+		{"refund", "726566756E64"},
+	}
+	for _, tt := range tests {
+		if got := encodeMemoData(tt.memo); got != tt.want {
+			t.Errorf("encodeMemoData(%q) = %s, want %s", tt.memo, got, tt.want)
+		}
+	}
+}
+
+func TestXRPLClientAccountPaymentsDecodesReferences(t *testing.T) {
+	reference := generatePaymentReference(big.NewInt(42))
+	server := newFakeXRPLServer(t, map[string]string{
+		"account_tx": `{"transactions":[
+			{"tx":{"hash":"AA","TransactionType":"Payment","Account":"rAgent","Destination":"rUser","date":0,"ledger_index":100,
+				"Memos":[{"Memo":{"MemoData":"` + encodeMemoData(reference) + `"}}]},
+			 "meta":{"TransactionResult":"tesSUCCESS","delivered_amount":"1000000"},"validated":true},
+			{"tx":{"hash":"BB","TransactionType":"Payment","Account":"rAgent","Destination":"rUser","date":0,"ledger_index":101,
+				"Memos":[{"Memo":{"MemoData":"` + encodeMemoData("refund") + `"}}]},
+			 "meta":{"TransactionResult":"tesSUCCESS","delivered_amount":"5"},"validated":true},
+			{"tx":{"hash":"CC","TransactionType":"Payment","Account":"rAgent","Destination":"rUser","date":0,"ledger_index":102},
+			 "meta":{"TransactionResult":"tesSUCCESS","delivered_amount":"5"},"validated":false}
+		]}`,
+	})
+	client := &XRPLClient{rpcURL: server.URL, wallet: &XRPLWallet{Address: "rAgent"}}
+
+	payments, err := client.AccountPayments(context.Background(), "rAgent", 0)
+	if err != nil {
+		t.Fatalf("AccountPayments: %v", err)
+	}
+	if len(payments) != 2 {
+		t.Fatalf("payments = %d, want 2 validated", len(payments))
+	}
+	if payments[0].Reference != reference {
+		t.Fatalf("reference = %q, want %s", payments[0].Reference, reference)
+	}
+	if id := redemptionFromReference(payments[0].Reference); id == nil || *id != 42 {
+		t.Fatalf("redemption = %v, want 42", id)
+	}
+	if payments[0].DeliveredAmount.String() != "1000000" {
+		t.Fatalf("delivered = %s drops", payments[0].DeliveredAmount)
+	}
+	if payments[1].Reference != "refund" {
+		t.Fatalf("reference = %q, want refund", payments[1].Reference)
+	}
+}
@@ -4,4 +4,7 @@ go 1.21
 
 require (
     github.com/ethereum/go-ethereum v1.14.11
+    github.com/flip-protocol/scoring v0.0.0
 )
+
+replace github.com/flip-protocol/scoring => ../scoring
@@ -15,6 +15,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/flip-protocol/scoring"
 )
 
 // OracleNode is the main oracle service
@@ -128,7 +129,7 @@ func (on *OracleNode) handleRedemptionRequested(logEntry types.Log) {
 	}
 
 	// Use deterministic scoring (not ML)
-	scorer := NewDeterministicScorer()
+	scorer := scoring.NewDeterministicScorer()
 	
 	// Calculate score using deterministic scoring
 	scoreResult := scorer.CalculateScore(scoringParams)
@@ -14,6 +14,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/flip-protocol/scoring"
 	"strings"
 )
 
@@ -23,7 +24,7 @@ type OracleNode struct {
 	rpcURL        string
 	flipCore      common.Address
 	oracleRelay   common.Address
-	scorer        *DeterministicScorer
+	scorer        *scoring.DeterministicScorer
 	relay         *Relay
 	monitor       *Monitor
 	ctx           context.Context
@@ -41,7 +42,7 @@ func NewOracleNode(rpcURL string, flipCoreAddr string, oracleRelayAddr string) (
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	scorer := NewDeterministicScorer()
+	scorer := scoring.NewDeterministicScorer()
 
 	relay, err := NewRelay(client, common.HexToAddress(oracleRelayAddr))
 	if err != nil {
@@ -199,7 +200,7 @@ func (on *OracleNode) handleRedemptionRequested(logEntry types.Log) {
 }
 
 // extractScoringParams extracts on-chain data for deterministic scoring
-func (on *OracleNode) extractScoringParams(redemptionId *big.Int, asset common.Address, amount *big.Int) (ScoringParams, error) {
+func (on *OracleNode) extractScoringParams(redemptionId *big.Int, asset common.Address, amount *big.Int) (scoring.ScoringParams, error) {
 	// 1. Get price volatility from FTSO (query last 10 blocks)
 	priceVolatility, err := on.getPriceVolatility(asset)
 	if err != nil {
@@ -214,7 +215,7 @@ func (on *OracleNode) extractScoringParams(redemptionId *big.Int, asset common.A
 	// 3. Get current hour
 	hourOfDay := time.Now().Hour()
 
-	return ScoringParams{
+	return scoring.ScoringParams{
 		PriceVolatility: priceVolatility,
 		Amount:          amount,
 		AgentSuccessRate: agentSuccessRate,
@@ -231,7 +232,7 @@ func (on *OracleNode) getPriceVolatility(asset common.Address) (*big.Int, error)
 }
 
 // calculateSuggestedHaircut calculates suggested haircut from score result
-func calculateSuggestedHaircut(result ScoreResult) *big.Int {
+func calculateSuggestedHaircut(result scoring.ScoreResult) *big.Int {
 	// Higher confidence = lower haircut
 	// haircut = (1 - confidenceLower) * maxHaircut
 	maxHaircut := big.NewInt(50000) // 5% max (scaled)
@@ -0,0 +1,3 @@
+module github.com/flip-protocol/scoring
+
+go 1.21
@@ -1,4 +1,7 @@
-package main
+// Package scoring is the off-chain copy of DeterministicScoring.sol. The
+// oracle node and the agent both score requests with it, so they agree with
+// each other and with how FLIPCore will score them.
+package scoring
 
 import (
 	"math/big"
@@ -8,44 +11,44 @@ import (
 // DeterministicScorer calculates redemption scores without ML
 type DeterministicScorer struct {
 	// Configuration (can be updated via governance)
-	baseSuccessRate    *big.Int // 980000 = 98% (scaled: 1000000 = 100%)
-	maxVolatility      *big.Int // 50000 = 5%
-	smallAmountThreshold *big.Int
+	baseSuccessRate       *big.Int // 980000 = 98% (scaled: 1000000 = 100%)
+	maxVolatility         *big.Int // 50000 = 5%
+	smallAmountThreshold  *big.Int
 	mediumAmountThreshold *big.Int
 	largeAmountThreshold  *big.Int
-	minStake            *big.Int
-	provisionalThreshold *big.Int // 997000 = 99.7%
+	minStake              *big.Int
+	provisionalThreshold  *big.Int // 997000 = 99.7%
 }
 
 // NewDeterministicScorer creates a new scorer with default parameters
 func NewDeterministicScorer() *DeterministicScorer {
 	return &DeterministicScorer{
-		baseSuccessRate:      big.NewInt(980000),
-		maxVolatility:        big.NewInt(50000),
-		smallAmountThreshold: big.NewInt(1000).Mul(big.NewInt(1000), big.NewInt(1e18)),
+		baseSuccessRate:       big.NewInt(980000),
+		maxVolatility:         big.NewInt(50000),
+		smallAmountThreshold:  big.NewInt(1000).Mul(big.NewInt(1000), big.NewInt(1e18)),
 		mediumAmountThreshold: big.NewInt(10000).Mul(big.NewInt(10000), big.NewInt(1e18)),
 		largeAmountThreshold:  big.NewInt(100000).Mul(big.NewInt(100000), big.NewInt(1e18)),
-		minStake:             big.NewInt(100000).Mul(big.NewInt(100000), big.NewInt(1e18)),
-		provisionalThreshold: big.NewInt(997000),
+		minStake:              big.NewInt(100000).Mul(big.NewInt(100000), big.NewInt(1e18)),
+		provisionalThreshold:  big.NewInt(997000),
 	}
 }
 
 // ScoringParams contains all inputs for scoring
 type ScoringParams struct {
-	PriceVolatility *big.Int // Scaled: 1000000 = 100%
-	Amount          *big.Int
+	PriceVolatility  *big.Int // Scaled: 1000000 = 100%
+	Amount           *big.Int
 	AgentSuccessRate *big.Int // Scaled: 1000000 = 100%
-	AgentStake      *big.Int
-	HourOfDay       int
+	AgentStake       *big.Int
+	HourOfDay        int
 }
 
 // ScoreResult contains the calculated score and decision
 type ScoreResult struct {
-	Score              *big.Int // Final score (scaled: 1000000 = 100%)
-	ConfidenceLower    *big.Int
-	ConfidenceUpper    *big.Int
+	Score                *big.Int // Final score (scaled: 1000000 = 100%)
+	ConfidenceLower      *big.Int
+	ConfidenceUpper      *big.Int
 	CanProvisionalSettle bool
-	Decision           uint8 // 0=QueueFDC, 1=BufferEarmark, 2=ProvisionalSettle
+	Decision             uint8 // 0=QueueFDC, 1=BufferEarmark, 2=ProvisionalSettle
 }
 
 // CalculateScore computes deterministic score for a redemption
@@ -104,11 +107,11 @@ func (ds *DeterministicScorer) CalculateScore(params ScoringParams) ScoreResult
 	}
 
 	return ScoreResult{
-		Score:              score,
-		ConfidenceLower:    confidenceLower,
-		ConfidenceUpper:    confidenceUpper,
+		Score:                score,
+		ConfidenceLower:      confidenceLower,
+		ConfidenceUpper:      confidenceUpper,
 		CanProvisionalSettle: canProvisionalSettle,
-		Decision:           decision,
+		Decision:             decision,
 	}
 }
 
@@ -259,4 +262,3 @@ func GetAgentSuccessRate(completed, total int) *big.Int {
 func GetCurrentHour() int {
 	return time.Now().Hour()
 }
-